@@ -0,0 +1,203 @@
+// Package client is a Go client for the sales API. It mirrors the product
+// and health check handlers of sales-api, retrying requests that failed on
+// the server and turning error responses into Go errors.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Client calls the sales API. Make one with New and change its fields before
+// it is first used.
+type Client struct {
+	// BaseURL is where the API is served, like http://localhost:8000.
+	BaseURL string
+
+	// HTTPClient sends the requests.
+	HTTPClient *http.Client
+
+	// Token is sent as a bearer token when it is set.
+	Token string
+
+	// Actor names who makes the changes, as recorded in the audit log.
+	Actor string
+
+	// EventID is the event the product calls are about. Blank means the
+	// default event.
+	EventID string
+
+	// Retries is how many more times a request is tried after failing with
+	// a 5xx or 429 response or a network error.
+	Retries int
+
+	// Backoff is the wait before the first retry. It doubles after every
+	// retry unless the API asks for a wait with a Retry-After header.
+	Backoff time.Duration
+}
+
+// New makes a Client for the API served at baseURL.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Retries:    3,
+		Backoff:    200 * time.Millisecond,
+	}
+}
+
+// ForEvent gives a copy of the Client making product calls about an event.
+func (c *Client) ForEvent(eventID string) *Client {
+	cp := *c
+	cp.EventID = eventID
+	return &cp
+}
+
+// Health is the status reported by the health check.
+type Health struct {
+	Status string `json:"status"`
+}
+
+// Health checks the service is up and can reach its database.
+func (c *Client) Health(ctx context.Context) (*Health, error) {
+	var h Health
+	if err := c.do(ctx, request{method: http.MethodGet, path: "/v1/health"}, &h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// request describes a call to the API.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   interface{}
+
+	// idempotent marks POSTs the API processes once per Idempotency-Key, so
+	// they can safely be retried.
+	idempotent bool
+}
+
+// retryable tells whether the request can be sent again after a failure
+// without risking it being processed twice.
+func (r request) retryable() bool {
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.idempotent
+}
+
+// do sends a request, retrying it while it fails on the server, and decodes
+// the response into out. Error responses are returned as an *Error.
+func (c *Client) do(ctx context.Context, req request, out interface{}) error {
+	var body []byte
+	if req.body != nil {
+		var err error
+		if body, err = json.Marshal(req.body); err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+	}
+
+	u := c.BaseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	// The key is the same for every try so the API knows it is a retry.
+	var key string
+	if req.idempotent {
+		key = uuid.New().String()
+	}
+
+	wait := c.Backoff
+	for try := 0; ; try++ {
+		hr, err := http.NewRequestWithContext(ctx, req.method, u, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("creating request: %w", err)
+		}
+		hr.Header.Set("Accept", "application/json")
+		if body != nil {
+			hr.Header.Set("Content-Type", "application/json")
+		}
+		if c.Token != "" {
+			hr.Header.Set("Authorization", "Bearer "+c.Token)
+		}
+		if c.Actor != "" {
+			hr.Header.Set("X-Actor", c.Actor)
+		}
+		if key != "" {
+			hr.Header.Set("Idempotency-Key", key)
+		}
+
+		resp, err := c.HTTPClient.Do(hr)
+
+		retry := try < c.Retries && req.retryable()
+		switch {
+		case err != nil:
+			if !retry || ctx.Err() != nil {
+				return fmt.Errorf("%s %s: %w", req.method, req.path, err)
+			}
+		case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+			if !retry {
+				return decodeError(resp)
+			}
+			if after := retryAfter(resp); after > 0 {
+				wait = after
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return decodeResponse(resp, out)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		wait *= 2
+	}
+}
+
+// decodeResponse reads the body of a response into out.
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decodeError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// retryAfter gives the wait asked for by a Retry-After header in seconds.
+func retryAfter(resp *http.Response) time.Duration {
+	s, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || s < 0 {
+		return 0
+	}
+	return time.Duration(s) * time.Second
+}
@@ -0,0 +1,118 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/client"
+)
+
+// newClient makes a Client for a test server that does not wait between
+// retries.
+func newClient(t *testing.T, h http.HandlerFunc) *client.Client {
+	t.Helper()
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	c := client.New(srv.URL)
+	c.Backoff = time.Millisecond
+	return c
+}
+
+func TestRetries(t *testing.T) {
+	var tries int
+	var keys []string
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		tries++
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+
+		switch tries {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":"p1","name":"Lamp"}`))
+		}
+	})
+
+	p, err := c.CreateProduct(context.Background(), client.NewProduct{Name: "Lamp", Quantity: 1})
+	if err != nil {
+		t.Fatalf("creating: %v", err)
+	}
+	if p.ID != "p1" || tries != 3 {
+		t.Fatalf("expected p1 after 3 tries, got %q after %d", p.ID, tries)
+	}
+	if keys[0] == "" || keys[0] != keys[1] || keys[1] != keys[2] {
+		t.Fatalf("expected every try sent with the same idempotency key, got %q", keys)
+	}
+
+	// Reverting is not deduplicated by the API so it is never retried.
+	tries = 0
+	c = newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		tries++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	err = c.RevertProduct(context.Background(), "p1", 1)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected a 502 error, got %v", err)
+	}
+	if tries != 1 {
+		t.Fatalf("expected a single try, got %d", tries)
+	}
+
+	// Reads give up once the retries are used up.
+	tries = 0
+	c.Retries = 2
+	if _, err := c.ListProducts(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if tries != 3 {
+		t.Fatalf("expected 3 tries, got %d", tries)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("expected the bearer token, got %q", got)
+		}
+		if got := r.Header.Get("X-Actor"); got != "alice" {
+			t.Errorf("expected the actor, got %q", got)
+		}
+		if r.URL.Path != "/v1/events/e1/products" {
+			t.Errorf("expected the products of the event, got %s", r.URL.Path)
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"field validation error","fields":[{"field":"name","error":"name is a required field"}]}`))
+	})
+	c.Token = "secret"
+	c.Actor = "alice"
+	c = c.ForEvent("e1")
+
+	_, err := c.CreateProduct(context.Background(), client.NewProduct{})
+	if !errors.Is(err, client.ErrBadRequest) || errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("expected a bad request error, got %v", err)
+	}
+
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *Error, got %T", err)
+	}
+	if msg, ok := apiErr.Field("name"); !ok || msg != "name is a required field" {
+		t.Fatalf("expected the name field error, got %q", msg)
+	}
+	if apiErr.Message != "field validation error" {
+		t.Fatalf("expected the error message, got %q", apiErr.Message)
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Errors an *Error matches with errors.Is, by the status of the response.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+)
+
+// FieldError is a problem with a single field of a request.
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// Error is an error response of the API.
+type Error struct {
+	StatusCode int
+	Message    string
+	Fields     []FieldError
+}
+
+func (e *Error) Error() string {
+	if len(e.Fields) == 0 {
+		return fmt.Sprintf("sales api: %d: %s", e.StatusCode, e.Message)
+	}
+
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.Field + ": " + f.Error
+	}
+	return fmt.Sprintf("sales api: %d: %s (%s)", e.StatusCode, e.Message, strings.Join(fields, ", "))
+}
+
+// Is makes errors.Is match the error against the errors of this package.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	}
+	return false
+}
+
+// Field gives the error reported for a field of the request, if any.
+func (e *Error) Field(name string) (string, bool) {
+	for _, f := range e.Fields {
+		if f.Field == name {
+			return f.Error, true
+		}
+	}
+	return "", false
+}
+
+// decodeError turns an error response into an *Error. The health check
+// reports its failures in a status field rather than an error.
+func decodeError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	var er struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+		Status string       `json:"status"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	json.Unmarshal(b, &er)
+
+	e := Error{
+		StatusCode: resp.StatusCode,
+		Message:    er.Error,
+		Fields:     er.Fields,
+	}
+	if e.Message == "" {
+		e.Message = er.Status
+	}
+	if e.Message == "" {
+		e.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return &e
+}
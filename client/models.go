@@ -0,0 +1,329 @@
+package client
+
+import "time"
+
+// The types below mirror the JSON the API sends and receives. They are
+// declared here rather than shared with the server so programs outside this
+// module can use them and the server is free to change its own types as long
+// as the JSON stays the same.
+
+// Product is something sold at an event. Quantity is the number of units
+// ever stocked and OnHand what is left of them. Reserved units are on hand
+// but held for a customer and Available is what is left to sell.
+type Product struct {
+	ID          string    `json:"id"`
+	EventID     string    `json:"event_id"`
+	SellerID    *string   `json:"seller_id,omitempty"`
+	Name        string    `json:"name"`
+	Category    string    `json:"category"`
+	Cost        int       `json:"cost"`
+	Quantity    int       `json:"quantity"`
+	OnHand      int       `json:"on_hand"`
+	Reserved    int       `json:"reserved"`
+	Available   int       `json:"available"`
+	LowStock    int       `json:"low_stock_threshold"`
+	MinPrice    int       `json:"min_price"`
+	Archived    bool      `json:"archived"`
+	Sold        int       `json:"sold"`
+	Revenue     int       `json:"revenue"`
+	Images      []Image   `json:"images"`
+	Variants    []Variant `json:"variants"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+// Image is a photo of a product. URLs maps the name of every available size,
+// including "original", to the path it can be downloaded from.
+type Image struct {
+	ID          string            `json:"id"`
+	ContentType string            `json:"content_type"`
+	Width       int               `json:"width"`
+	Height      int               `json:"height"`
+	Size        int64             `json:"size"`
+	Position    int               `json:"position"`
+	Primary     bool              `json:"primary"`
+	URLs        map[string]string `json:"urls"`
+	DateCreated time.Time         `json:"date_created"`
+}
+
+// NewProduct is what is needed to add a Product.
+type NewProduct struct {
+	Name     string `json:"name"`
+	Category string `json:"category"`
+	Cost     int    `json:"cost"`
+	Quantity int    `json:"quantity"`
+	LowStock int    `json:"low_stock_threshold"`
+	MinPrice int    `json:"min_price"`
+	SellerID string `json:"seller_id,omitempty"`
+}
+
+// UpdateProduct holds the fields of a Product to change. Fields left nil are
+// not changed. A blank SellerID takes the product off consignment.
+type UpdateProduct struct {
+	Name     *string `json:"name,omitempty"`
+	Category *string `json:"category,omitempty"`
+	Cost     *int    `json:"cost,omitempty"`
+	Quantity *int    `json:"quantity,omitempty"`
+	LowStock *int    `json:"low_stock_threshold,omitempty"`
+	MinPrice *int    `json:"min_price,omitempty"`
+	SellerID *string `json:"seller_id,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+}
+
+// Version is the state of the fields of a Product as of one of its updates.
+// MinPrice and Archived are nil for versions recorded before they were kept.
+type Version struct {
+	ProductID string    `json:"product_id"`
+	Version   int       `json:"version"`
+	SellerID  *string   `json:"seller_id,omitempty"`
+	Name      string    `json:"name"`
+	Category  string    `json:"category"`
+	Cost      int       `json:"cost"`
+	LowStock  int       `json:"low_stock_threshold"`
+	MinPrice  *int      `json:"min_price,omitempty"`
+	Archived  *bool     `json:"archived,omitempty"`
+	ValidFrom time.Time `json:"valid_from"`
+}
+
+// Variant is a version of a Product, such as a size or color, with its own
+// SKU and stock. A nil Cost means the variant sells for the product cost.
+type Variant struct {
+	ID          string    `json:"id"`
+	ProductID   string    `json:"product_id"`
+	SKU         string    `json:"sku"`
+	Name        string    `json:"name"`
+	Cost        *int      `json:"cost,omitempty"`
+	Quantity    int       `json:"quantity"`
+	OnHand      int       `json:"on_hand"`
+	Reserved    int       `json:"reserved"`
+	Available   int       `json:"available"`
+	Sold        int       `json:"sold"`
+	Revenue     int       `json:"revenue"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+// NewVariant is what is needed to add a Variant to a Product.
+type NewVariant struct {
+	SKU      string `json:"sku"`
+	Name     string `json:"name"`
+	Cost     *int   `json:"cost,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+// UpdateVariant holds the fields of a Variant to change. Fields left nil are
+// not changed.
+type UpdateVariant struct {
+	SKU      *string `json:"sku,omitempty"`
+	Name     *string `json:"name,omitempty"`
+	Cost     *int    `json:"cost,omitempty"`
+	Quantity *int    `json:"quantity,omitempty"`
+}
+
+// Sale is some amount of a product sold. Net, Tax and Gross break down what
+// was paid for tax reporting; Paid equals Gross.
+type Sale struct {
+	ID           string    `json:"id"`
+	EventID      string    `json:"event_id"`
+	SellerID     *string   `json:"seller_id,omitempty"`
+	CustomerID   *string   `json:"customer_id,omitempty"`
+	ProductID    string    `json:"product_id"`
+	VariantID    *string   `json:"variant_id,omitempty"`
+	Quantity     int       `json:"quantity"`
+	Paid         int       `json:"paid"`
+	Discount     int       `json:"discount"`
+	CouponCode   string    `json:"coupon_code,omitempty"`
+	Jurisdiction string    `json:"jurisdiction,omitempty"`
+	TaxRate      int       `json:"tax_rate"`
+	Net          int       `json:"net"`
+	Tax          int       `json:"tax"`
+	Gross        int       `json:"gross"`
+	DateCreated  time.Time `json:"date_created"`
+}
+
+// NewSale is what is needed to record a Sale. The VariantID is required for
+// products that come in variants. A sale naming a ReservationID, OfferID or
+// AuctionID takes the units held by the reservation or sells at the price
+// agreed in the offer or won in the auction.
+type NewSale struct {
+	VariantID     string `json:"variant_id,omitempty"`
+	CustomerID    string `json:"customer_id,omitempty"`
+	ReservationID string `json:"reservation_id,omitempty"`
+	OfferID       string `json:"offer_id,omitempty"`
+	AuctionID     string `json:"auction_id,omitempty"`
+	Email         string `json:"email,omitempty"`
+	Quantity      int    `json:"quantity"`
+	Coupon        string `json:"coupon,omitempty"`
+	Jurisdiction  string `json:"jurisdiction,omitempty"`
+}
+
+// DayTotal adds up the sales of an event made on a single day, in UTC, and
+// the units refunded that day along with what was paid back for them.
+type DayTotal struct {
+	Day           time.Time `json:"day"`
+	Sales         int       `json:"sales"`
+	Units         int       `json:"units"`
+	Discount      int       `json:"discount"`
+	Net           int       `json:"net"`
+	Tax           int       `json:"tax"`
+	Gross         int       `json:"gross"`
+	RefundedUnits int       `json:"refunded_units"`
+	RefundedGross int       `json:"refunded_gross"`
+}
+
+// Movement is an entry of the inventory ledger. Quantity is positive for units
+// coming in and negative for units going out.
+type Movement struct {
+	ID          string    `json:"id"`
+	ProductID   string    `json:"product_id"`
+	VariantID   *string   `json:"variant_id,omitempty"`
+	SaleID      *string   `json:"sale_id,omitempty"`
+	Kind        string    `json:"kind"`
+	Quantity    int       `json:"quantity"`
+	Reason      string    `json:"reason"`
+	Actor       string    `json:"actor,omitempty"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// NewAdjustment is what is needed to change stock by hand. Kind is one of
+// received, damaged, lost, correction or refund. Quantity is positive except
+// for corrections, which take a signed change.
+type NewAdjustment struct {
+	VariantID string `json:"variant_id,omitempty"`
+	SaleID    string `json:"sale_id,omitempty"`
+	Kind      string `json:"kind"`
+	Quantity  int    `json:"quantity"`
+	Reason    string `json:"reason"`
+}
+
+// Reservation sets aside units of a Product, or one of its variants, for a
+// customer until ExpiresAt.
+type Reservation struct {
+	ID          string    `json:"id"`
+	ProductID   string    `json:"product_id"`
+	VariantID   *string   `json:"variant_id,omitempty"`
+	AuctionID   *string   `json:"auction_id,omitempty"`
+	CustomerID  *string   `json:"customer_id,omitempty"`
+	SaleID      *string   `json:"sale_id,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	Quantity    int       `json:"quantity"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	DateCreated time.Time `json:"date_created"`
+	DateUpdated time.Time `json:"date_updated"`
+}
+
+// NewReservation is what is needed to hold stock for a customer.
+type NewReservation struct {
+	VariantID  string    `json:"variant_id,omitempty"`
+	CustomerID string    `json:"customer_id,omitempty"`
+	Note       string    `json:"note,omitempty"`
+	Quantity   int       `json:"quantity"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Offer is a price per unit a buyer proposes for a Product. Once accepted,
+// Price is the agreed price per unit and holds until ExpiresAt.
+type Offer struct {
+	ID            string     `json:"id"`
+	ProductID     string     `json:"product_id"`
+	VariantID     *string    `json:"variant_id,omitempty"`
+	CustomerID    *string    `json:"customer_id,omitempty"`
+	SaleID        *string    `json:"sale_id,omitempty"`
+	Buyer         string     `json:"buyer,omitempty"`
+	Quantity      int        `json:"quantity"`
+	Amount        int        `json:"amount"`
+	CounterAmount *int       `json:"counter_amount,omitempty"`
+	Price         *int       `json:"price,omitempty"`
+	Status        string     `json:"status"`
+	Reason        string     `json:"reason,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	DateCreated   time.Time  `json:"date_created"`
+	DateUpdated   time.Time  `json:"date_updated"`
+}
+
+// NewOffer is what is needed to make an Offer. Amount is the price offered
+// per unit.
+type NewOffer struct {
+	VariantID  string `json:"variant_id,omitempty"`
+	CustomerID string `json:"customer_id,omitempty"`
+	Buyer      string `json:"buyer,omitempty"`
+	Quantity   int    `json:"quantity"`
+	Amount     int    `json:"amount"`
+}
+
+// OfferResponse is how staff answer an Offer. Counters take the Amount per
+// unit they would sell for and accepting locks the price until ExpiresAt.
+type OfferResponse struct {
+	Action    string     `json:"action"`
+	Amount    *int       `json:"amount,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// Actions of an OfferResponse.
+const (
+	OfferAccept  = "accept"
+	OfferReject  = "reject"
+	OfferCounter = "counter"
+)
+
+// Statuses of an Offer.
+const (
+	OfferPending   = "pending"
+	OfferCountered = "countered"
+	OfferAccepted  = "accepted"
+	OfferRejected  = "rejected"
+	OfferSold      = "sold"
+)
+
+// Auction sells a single unit of a Product, or one of its variants, to the
+// highest bidder between StartsAt and EndsAt.
+type Auction struct {
+	ID           string    `json:"id"`
+	ProductID    string    `json:"product_id"`
+	VariantID    *string   `json:"variant_id,omitempty"`
+	SaleID       *string   `json:"sale_id,omitempty"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	ReservePrice int       `json:"reserve_price"`
+	MinIncrement int       `json:"min_increment"`
+	HighBid      *int      `json:"high_bid,omitempty"`
+	Bids         int       `json:"bids"`
+	Status       string    `json:"status"`
+	SaleAttempts int       `json:"sale_attempts"`
+	LastError    string    `json:"last_error,omitempty"`
+	DateCreated  time.Time `json:"date_created"`
+	DateUpdated  time.Time `json:"date_updated"`
+}
+
+// NewAuction is what is needed to put a Product up for auction.
+type NewAuction struct {
+	VariantID    string    `json:"variant_id,omitempty"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at"`
+	ReservePrice int       `json:"reserve_price"`
+	MinIncrement int       `json:"min_increment"`
+}
+
+// Bid is an amount a bidder offers for the lot of an Auction.
+type Bid struct {
+	ID          string    `json:"id"`
+	AuctionID   string    `json:"auction_id"`
+	CustomerID  *string   `json:"customer_id,omitempty"`
+	Bidder      string    `json:"bidder,omitempty"`
+	Amount      int       `json:"amount"`
+	DateCreated time.Time `json:"date_created"`
+}
+
+// NewBid is what is needed to bid in an Auction.
+type NewBid struct {
+	CustomerID string `json:"customer_id,omitempty"`
+	Bidder     string `json:"bidder,omitempty"`
+	Amount     int    `json:"amount"`
+}
+
+// revert is the body of a request restoring a previous Version of a Product.
+type revert struct {
+	Version int `json:"version"`
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// prefix gives the path the product routes of the event of the Client are
+// served under.
+func (c *Client) prefix() string {
+	if c.EventID == "" {
+		return "/v1"
+	}
+	return "/v1/events/" + url.PathEscape(c.EventID)
+}
+
+// products gives the path of a product route.
+func (c *Client) products(productID string, rest ...string) string {
+	p := c.prefix() + "/products"
+	if productID != "" {
+		p += "/" + url.PathEscape(productID)
+	}
+	for _, r := range rest {
+		p += "/" + url.PathEscape(r)
+	}
+	return p
+}
+
+// ListProducts gives all products of the event.
+func (c *Client) ListProducts(ctx context.Context) ([]Product, error) {
+	var list []Product
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products("")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RetrieveProduct gives a single product.
+func (c *Client) RetrieveProduct(ctx context.Context, id string) (*Product, error) {
+	var p Product
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(id)}, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// RetrieveProductAsOf gives a product as it was at a point in time.
+func (c *Client) RetrieveProductAsOf(ctx context.Context, id string, asOf time.Time) (*Product, error) {
+	req := request{
+		method: http.MethodGet,
+		path:   c.products(id),
+		query:  url.Values{"as_of": {asOf.Format(time.RFC3339)}},
+	}
+
+	var p Product
+	if err := c.do(ctx, req, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// CreateProduct adds a product to the event.
+func (c *Client) CreateProduct(ctx context.Context, np NewProduct) (*Product, error) {
+	req := request{method: http.MethodPost, path: c.products(""), body: np, idempotent: true}
+
+	var p Product
+	if err := c.do(ctx, req, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// UpdateProduct changes the fields of a product that are set in update.
+func (c *Client) UpdateProduct(ctx context.Context, id string, update UpdateProduct) error {
+	return c.do(ctx, request{method: http.MethodPut, path: c.products(id), body: update}, nil)
+}
+
+// DeleteProduct removes a product.
+func (c *Client) DeleteProduct(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: c.products(id)}, nil)
+}
+
+// ProductHistory gives every version of a product, oldest first.
+func (c *Client) ProductHistory(ctx context.Context, id string) ([]Version, error) {
+	var list []Version
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(id, "history")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RevertProduct restores a product to a previous version.
+func (c *Client) RevertProduct(ctx context.Context, id string, version int) error {
+	req := request{method: http.MethodPost, path: c.products(id, "revert"), body: revert{Version: version}}
+	return c.do(ctx, req, nil)
+}
+
+// AddSale records a sale of a product.
+func (c *Client) AddSale(ctx context.Context, productID string, ns NewSale) (*Sale, error) {
+	req := request{method: http.MethodPost, path: c.products(productID, "sales"), body: ns, idempotent: true}
+
+	var s Sale
+	if err := c.do(ctx, req, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSales gives the sales of a product.
+func (c *Client) ListSales(ctx context.Context, productID string) ([]Sale, error) {
+	var list []Sale
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(productID, "sales")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// DailyTotals gives the sales and refunds of the event added up by day, the
+// most recent first.
+func (c *Client) DailyTotals(ctx context.Context) ([]DayTotal, error) {
+	var list []DayTotal
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.prefix() + "/reports/daily"}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Adjust corrects the stock of a product.
+func (c *Client) Adjust(ctx context.Context, productID string, na NewAdjustment) (*Movement, error) {
+	req := request{method: http.MethodPost, path: c.products(productID, "adjustments"), body: na, idempotent: true}
+
+	var m Movement
+	if err := c.do(ctx, req, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// ListMovements gives the stock movements of a product.
+func (c *Client) ListMovements(ctx context.Context, productID string) ([]Movement, error) {
+	var list []Movement
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(productID, "movements")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ListVariants gives the variants of a product.
+func (c *Client) ListVariants(ctx context.Context, productID string) ([]Variant, error) {
+	var list []Variant
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(productID, "variants")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// AddVariant adds a variant to a product.
+func (c *Client) AddVariant(ctx context.Context, productID string, nv NewVariant) (*Variant, error) {
+	req := request{method: http.MethodPost, path: c.products(productID, "variants"), body: nv}
+
+	var v Variant
+	if err := c.do(ctx, req, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// UpdateVariant changes the fields of a variant that are set in update.
+func (c *Client) UpdateVariant(ctx context.Context, productID, variantID string, update UpdateVariant) error {
+	req := request{method: http.MethodPut, path: c.products(productID, "variants", variantID), body: update}
+	return c.do(ctx, req, nil)
+}
+
+// DeleteVariant removes a variant of a product.
+func (c *Client) DeleteVariant(ctx context.Context, productID, variantID string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: c.products(productID, "variants", variantID)}, nil)
+}
+
+// Reserve holds units of a product for a buyer.
+func (c *Client) Reserve(ctx context.Context, productID string, nr NewReservation) (*Reservation, error) {
+	req := request{method: http.MethodPost, path: c.products(productID, "reservations"), body: nr, idempotent: true}
+
+	var r Reservation
+	if err := c.do(ctx, req, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ListReservations gives the reservations of a product.
+func (c *Client) ListReservations(ctx context.Context, productID string) ([]Reservation, error) {
+	var list []Reservation
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(productID, "reservations")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RetrieveReservation gives a single reservation.
+func (c *Client) RetrieveReservation(ctx context.Context, id string) (*Reservation, error) {
+	var r Reservation
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.prefix() + "/reservations/" + url.PathEscape(id)}, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ReleaseReservation gives the units held by a reservation back to stock.
+func (c *Client) ReleaseReservation(ctx context.Context, id string) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: c.prefix() + "/reservations/" + url.PathEscape(id)}, nil)
+}
+
+// MakeOffer proposes a price for a product.
+func (c *Client) MakeOffer(ctx context.Context, productID string, no NewOffer) (*Offer, error) {
+	req := request{method: http.MethodPost, path: c.products(productID, "offers"), body: no, idempotent: true}
+
+	var o Offer
+	if err := c.do(ctx, req, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// ListOffers gives the offers made for a product.
+func (c *Client) ListOffers(ctx context.Context, productID string) ([]Offer, error) {
+	var list []Offer
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(productID, "offers")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RetrieveOffer gives a single offer.
+func (c *Client) RetrieveOffer(ctx context.Context, id string) (*Offer, error) {
+	var o Offer
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.prefix() + "/offers/" + url.PathEscape(id)}, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// RespondToOffer accepts, rejects or counters an offer.
+func (c *Client) RespondToOffer(ctx context.Context, id string, resp OfferResponse) (*Offer, error) {
+	req := request{method: http.MethodPost, path: c.prefix() + "/offers/" + url.PathEscape(id) + "/response", body: resp}
+
+	var o Offer
+	if err := c.do(ctx, req, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// CreateAuction puts a unit of a product up for auction.
+func (c *Client) CreateAuction(ctx context.Context, productID string, na NewAuction) (*Auction, error) {
+	req := request{method: http.MethodPost, path: c.products(productID, "auctions"), body: na, idempotent: true}
+
+	var a Auction
+	if err := c.do(ctx, req, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ListAuctions gives the auctions of a product.
+func (c *Client) ListAuctions(ctx context.Context, productID string) ([]Auction, error) {
+	var list []Auction
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.products(productID, "auctions")}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// RetrieveAuction gives a single auction.
+func (c *Client) RetrieveAuction(ctx context.Context, id string) (*Auction, error) {
+	var a Auction
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.prefix() + "/auctions/" + url.PathEscape(id)}, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// PlaceBid bids in an open auction.
+func (c *Client) PlaceBid(ctx context.Context, auctionID string, nb NewBid) (*Bid, error) {
+	req := request{method: http.MethodPost, path: c.prefix() + "/auctions/" + url.PathEscape(auctionID) + "/bids", body: nb, idempotent: true}
+
+	var b Bid
+	if err := c.do(ctx, req, &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// ListBids gives the bids of an auction, the highest first.
+func (c *Client) ListBids(ctx context.Context, auctionID string) ([]Bid, error) {
+	var list []Bid
+	if err := c.do(ctx, request{method: http.MethodGet, path: c.prefix() + "/auctions/" + url.PathEscape(auctionID) + "/bids"}, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
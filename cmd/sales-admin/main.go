@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"text/tabwriter"
+	"time"
 
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/event"
 	"github.com/ivan-sabo/garagesale/internal/platform/database"
 	"github.com/ivan-sabo/garagesale/internal/schema"
+	"github.com/ivan-sabo/garagesale/internal/seller"
+	"github.com/jmoiron/sqlx"
 )
 
 func main() {
@@ -36,5 +46,119 @@ func main() {
 		}
 		log.Println("Seed data inserted")
 		return
+	case "audit":
+		if flag.Arg(1) != "export" {
+			log.Fatal("usage: sales-admin audit export [flags]")
+		}
+		if err := auditExport(db, flag.Args()[2:]); err != nil {
+			log.Fatal("exporting audit log: ", err)
+		}
+		return
+	case "payouts":
+		if err := payouts(db, flag.Args()[1:]); err != nil {
+			log.Fatal("settling payouts: ", err)
+		}
+		return
+	}
+}
+
+// auditExport writes the audit log as JSON lines to standard output or the
+// file given with -o.
+func auditExport(db *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("audit export", flag.ExitOnError)
+
+	var f audit.Filter
+	fs.StringVar(&f.Actor, "actor", "", "only changes made by this actor")
+	fs.StringVar(&f.Action, "action", "", "only create, update or delete actions")
+	fs.StringVar(&f.ResourceType, "resource-type", "", "only changes to this type of resource")
+	fs.StringVar(&f.ResourceID, "resource-id", "", "only changes to this resource")
+	since := fs.String("since", "", "only changes at or after this RFC 3339 time")
+	until := fs.String("until", "", "only changes before this RFC 3339 time")
+	out := fs.String("o", "", "write to this file instead of standard output")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *since != "" {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("invalid since: %w", err)
+		}
+		f.Since = &t
+	}
+	if *until != "" {
+		t, err := time.Parse(time.RFC3339, *until)
+		if err != nil {
+			return fmt.Errorf("invalid until: %w", err)
+		}
+		f.Until = &t
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	n, err := audit.Export(context.Background(), db, f, w)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Exported %d audit entries", n)
+	return nil
+}
+
+// payouts prints what the sellers of an event are owed. With -pay it records a
+// payout of everything owed, marking the settlements paid.
+func payouts(db *sqlx.DB, args []string) error {
+	fs := flag.NewFlagSet("payouts", flag.ExitOnError)
+
+	eventID := fs.String("event", event.DefaultID, "settle the sellers of this event")
+	sellerID := fs.String("seller", "", "only settle this seller")
+	pay := fs.Bool("pay", false, "mark the settlements paid")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	list, err := seller.Settlements(ctx, db, *eventID)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SELLER\tNET\tREFUNDS\tCOMMISSION\tPAID\tOWED")
+
+	var n int
+	for _, s := range list {
+		if *sellerID != "" && s.SellerID != *sellerID {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%d\n", s.Name, s.Net, s.Refunds, s.Commission, s.Paid, s.Owed)
+
+		if !*pay || s.Owed <= 0 {
+			continue
+		}
+		if _, err := seller.Pay(ctx, db, *eventID, s.SellerID, time.Now()); err != nil {
+			return fmt.Errorf("paying %s: %w", s.Name, err)
+		}
+		n++
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if *pay {
+		log.Printf("Paid %d sellers", n)
 	}
+	return nil
 }
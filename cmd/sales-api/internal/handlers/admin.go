@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/middleware"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/jmoiron/sqlx"
+)
+
+// adminFiles holds the templates and static assets of the admin UI.
+//
+//go:embed admin
+var adminFiles embed.FS
+
+// adminPages are the pages of the admin UI, each parsed together with the
+// layout they are rendered in.
+var adminPages = parseAdminPages("products.html", "product.html", "totals.html", "error.html")
+
+// adminNotices are the messages shown after a form was handled, keyed by the
+// notice query parameter of the page redirected to.
+var adminNotices = map[string]string{
+	"created": "Product created.",
+	"saved":   "Changes saved.",
+	"sold":    "Sale recorded.",
+}
+
+func parseAdminPages(names ...string) map[string]*template.Template {
+	funcs := template.FuncMap{
+		"day": func(t time.Time) string { return t.Format("Mon 2 Jan 2006") },
+	}
+
+	pages := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		pages[name] = template.Must(template.New(name).Funcs(funcs).ParseFS(adminFiles,
+			"admin/templates/layout.html", "admin/templates/"+name))
+	}
+	return pages
+}
+
+// Admin defines the handlers of the HTML admin UI for volunteers working from
+// a browser. Forms are validated like the JSON API, with the errors shown next
+// to the fields they are about.
+type Admin struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// adminView is what the admin pages are rendered with. Base is the prefix of
+// the admin routes for the event the page is about.
+type adminView struct {
+	Title  string
+	Base   string
+	Event  *event.Event
+	Events []event.Event
+	CSRF   string
+	Notice string
+	Error  string
+	Fields map[string]string
+
+	Query    string
+	Products []product.Product
+	Product  *product.Product
+	Form     productForm
+	Sales    []product.Sale
+	Sale     saleForm
+	Totals   []product.DayTotal
+}
+
+// productForm holds the product form as it was filled in so it can be shown
+// again with its errors.
+type productForm struct {
+	Name     string
+	Category string
+	Cost     string
+	Quantity string
+	LowStock string
+	MinPrice string
+	Archived bool
+}
+
+// saleForm holds the sale form as it was filled in.
+type saleForm struct {
+	VariantID    string
+	Quantity     string
+	Coupon       string
+	Jurisdiction string
+}
+
+// Index sends volunteers to the products of the default event.
+func (a *Admin) Index(w http.ResponseWriter, r *http.Request) error {
+	http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
+	return nil
+}
+
+// Switch moves to the products of the event picked in the event menu.
+func (a *Admin) Switch(w http.ResponseWriter, r *http.Request) error {
+	id := r.URL.Query().Get("event")
+	if _, err := event.Retrieve(r.Context(), a.DB, id); err != nil {
+		return a.fail(w, r, err)
+	}
+
+	http.Redirect(w, r, adminBase(id)+"/products", http.StatusSeeOther)
+	return nil
+}
+
+// Static sends the stylesheet and other assets of the admin UI.
+func (a *Admin) Static(w http.ResponseWriter, r *http.Request) error {
+	static, err := fs.Sub(adminFiles, "admin/static")
+	if err != nil {
+		return fmt.Errorf("opening admin assets: %w", err)
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.StripPrefix("/admin/static/", http.FileServer(http.FS(static))).ServeHTTP(w, r)
+	return nil
+}
+
+// Products lists the products of an event. The q query parameter searches
+// their names and categories.
+func (a *Admin) Products(w http.ResponseWriter, r *http.Request) error {
+	list, err := product.List(r.Context(), a.DB, eventID(r))
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if q != "" {
+		term := strings.ToLower(q)
+		found := []product.Product{}
+		for _, p := range list {
+			if strings.Contains(strings.ToLower(p.Name), term) || strings.Contains(strings.ToLower(p.Category), term) {
+				found = append(found, p)
+			}
+		}
+		list = found
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+
+	return a.render(w, r, http.StatusOK, "products.html", adminView{Title: "Products", Query: q, Products: list})
+}
+
+// NewProduct shows the form for adding a product.
+func (a *Admin) NewProduct(w http.ResponseWriter, r *http.Request) error {
+	v := adminView{
+		Title: "New product",
+		Form:  productForm{Quantity: "1", Cost: "0", LowStock: "0", MinPrice: "0"},
+	}
+
+	return a.render(w, r, http.StatusOK, "product.html", v)
+}
+
+// CreateProduct adds the product filled in on the new product form.
+func (a *Admin) CreateProduct(w http.ResponseWriter, r *http.Request) error {
+	f := readProductForm(r)
+	fields := map[string]string{}
+
+	np := product.NewProduct{Name: f.Name, Category: f.Category}
+	formInt(r, "cost", &np.Cost, fields)
+	formInt(r, "quantity", &np.Quantity, fields)
+	formInt(r, "low_stock_threshold", &np.LowStock, fields)
+	formInt(r, "min_price", &np.MinPrice, fields)
+	if err := validateForm(np, fields); err != nil {
+		return a.fail(w, r, err)
+	}
+
+	if len(fields) > 0 {
+		v := adminView{Title: "New product", Form: f, Fields: fields}
+		return a.render(w, r, http.StatusBadRequest, "product.html", v)
+	}
+
+	p, err := product.Create(r.Context(), a.DB, eventID(r), np, time.Now())
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+
+	http.Redirect(w, r, adminBase(eventParam(r))+"/products/"+p.ID+"?notice=created", http.StatusSeeOther)
+	return nil
+}
+
+// EditProduct shows a product with the forms for changing it and recording a
+// sale, along with its sales so far.
+func (a *Admin) EditProduct(w http.ResponseWriter, r *http.Request) error {
+	p, err := product.Retrieve(r.Context(), a.DB, eventID(r), chi.URLParam(r, "id"))
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+
+	v := adminView{
+		Notice: adminNotices[r.URL.Query().Get("notice")],
+		Form:   productFormOf(p),
+		Sale:   saleForm{Quantity: "1"},
+	}
+
+	return a.renderProduct(w, r, http.StatusOK, p, v)
+}
+
+// UpdateProduct saves the changes made on the edit form of a product.
+func (a *Admin) UpdateProduct(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	p, err := product.Retrieve(r.Context(), a.DB, eventID(r), id)
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+
+	f := readProductForm(r)
+	fields := map[string]string{}
+
+	var cost, lowStock, minPrice int
+	u := product.UpdateProduct{
+		Name:     &f.Name,
+		Category: &f.Category,
+		Cost:     &cost,
+		LowStock: &lowStock,
+		MinPrice: &minPrice,
+		Archived: &f.Archived,
+	}
+	formInt(r, "cost", u.Cost, fields)
+	formInt(r, "low_stock_threshold", u.LowStock, fields)
+	formInt(r, "min_price", u.MinPrice, fields)
+
+	// Products with variants have their stock changed through the variants
+	// so their form has no quantity.
+	if _, ok := r.PostForm["quantity"]; ok {
+		var quantity int
+		u.Quantity = &quantity
+		formInt(r, "quantity", u.Quantity, fields)
+	}
+
+	if f.Name == "" {
+		fields["name"] = "name is a required field"
+	}
+	if err := validateForm(u, fields); err != nil {
+		return a.fail(w, r, err)
+	}
+
+	if len(fields) == 0 {
+		err := product.Update(r.Context(), a.DB, eventID(r), id, u, time.Now())
+		switch err {
+		case nil:
+		case product.ErrVariantRequired:
+			fields["quantity"] = err.Error()
+		default:
+			return a.fail(w, r, err)
+		}
+	}
+
+	if len(fields) > 0 {
+		v := adminView{Form: f, Fields: fields, Sale: saleForm{Quantity: "1"}}
+		return a.renderProduct(w, r, http.StatusBadRequest, p, v)
+	}
+
+	http.Redirect(w, r, adminBase(eventParam(r))+"/products/"+id+"?notice=saved", http.StatusSeeOther)
+	return nil
+}
+
+// RecordSale records the sale filled in on the page of a product.
+func (a *Admin) RecordSale(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	p, err := product.Retrieve(r.Context(), a.DB, eventID(r), id)
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+
+	sf := saleForm{
+		VariantID:    r.PostFormValue("variant_id"),
+		Quantity:     r.PostFormValue("quantity"),
+		Coupon:       strings.TrimSpace(r.PostFormValue("coupon")),
+		Jurisdiction: strings.TrimSpace(r.PostFormValue("jurisdiction")),
+	}
+	fields := map[string]string{}
+
+	ns := product.NewSale{VariantID: sf.VariantID, Coupon: sf.Coupon, Jurisdiction: sf.Jurisdiction}
+	formInt(r, "quantity", &ns.Quantity, fields)
+	if err := validateForm(ns, fields); err != nil {
+		return a.fail(w, r, err)
+	}
+
+	v := adminView{Form: productFormOf(p), Sale: sf, Fields: fields}
+	if len(fields) > 0 {
+		v.Error = "The sale was not recorded."
+		return a.renderProduct(w, r, http.StatusBadRequest, p, v)
+	}
+
+	_, err = product.AddSale(r.Context(), a.DB, eventID(r), ns, id, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrVariantRequired, product.ErrVariantNotFound,
+			pricing.ErrCouponNotFound, pricing.ErrCouponExpired, pricing.ErrCouponExhausted:
+			v.Error = "The sale was not recorded: " + err.Error() + "."
+			return a.renderProduct(w, r, http.StatusBadRequest, p, v)
+		case product.ErrInsufficientStock:
+			v.Error = "The sale was not recorded: " + err.Error() + "."
+			return a.renderProduct(w, r, http.StatusConflict, p, v)
+		default:
+			return a.fail(w, r, err)
+		}
+	}
+
+	http.Redirect(w, r, adminBase(eventParam(r))+"/products/"+id+"?notice=sold", http.StatusSeeOther)
+	return nil
+}
+
+// Totals shows the sales of an event added up by day.
+func (a *Admin) Totals(w http.ResponseWriter, r *http.Request) error {
+	totals, err := product.DailyTotals(r.Context(), a.DB, eventID(r))
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+
+	return a.render(w, r, http.StatusOK, "totals.html", adminView{Title: "Daily totals", Totals: totals})
+}
+
+// renderProduct shows the page of a product along with its sales.
+func (a *Admin) renderProduct(w http.ResponseWriter, r *http.Request, status int, p *product.Product, v adminView) error {
+	sales, err := product.ListSales(r.Context(), a.DB, eventID(r), p.ID)
+	if err != nil {
+		return a.fail(w, r, err)
+	}
+	sort.Slice(sales, func(i, j int) bool { return sales[i].DateCreated.After(sales[j].DateCreated) })
+
+	v.Title = p.Name
+	v.Product = p
+	v.Sales = sales
+
+	return a.render(w, r, status, "product.html", v)
+}
+
+// render fills in what every page shows and sends the page.
+func (a *Admin) render(w http.ResponseWriter, r *http.Request, status int, page string, v adminView) error {
+	v.Base = adminBase(eventParam(r))
+	v.CSRF = middleware.CSRFToken(r)
+
+	events, err := event.List(r.Context(), a.DB)
+	if err != nil {
+		return fmt.Errorf("getting events list: %w", err)
+	}
+	v.Events = events
+	for i := range events {
+		if events[i].ID == eventID(r) {
+			v.Event = &events[i]
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := adminPages[page].ExecuteTemplate(w, "layout", v); err != nil {
+		return fmt.Errorf("rendering %s: %w", page, err)
+	}
+
+	return nil
+}
+
+// fail shows an error page. Errors that are not the fault of the request are
+// logged and shown without their details.
+func (a *Admin) fail(w http.ResponseWriter, r *http.Request, err error) error {
+	status := http.StatusInternalServerError
+	msg := "Something went wrong. Please try again."
+
+	switch err {
+	case product.ErrNotFound, product.ErrEventNotFound, event.ErrNotFound:
+		status, msg = http.StatusNotFound, "That page does not exist."
+	case product.ErrInvalidID, event.ErrInvalidID:
+		status, msg = http.StatusBadRequest, "That page does not exist."
+	default:
+		a.Log.Printf("admin : %s %s : %v", r.Method, r.URL.Path, err)
+	}
+
+	return a.render(w, r, status, "error.html", adminView{Title: "Error", Error: msg})
+}
+
+// adminBase gives the prefix of the admin routes of an event. The default
+// event lives directly under /admin.
+func adminBase(eventID string) string {
+	if eventID == "" || eventID == event.DefaultID {
+		return "/admin"
+	}
+	return "/admin/events/" + eventID
+}
+
+// eventParam gives the event named in the route, blank for the default event.
+func eventParam(r *http.Request) string {
+	return chi.URLParam(r, "eventID")
+}
+
+// readProductForm reads the product form as it was filled in.
+func readProductForm(r *http.Request) productForm {
+	return productForm{
+		Name:     strings.TrimSpace(r.PostFormValue("name")),
+		Category: strings.TrimSpace(r.PostFormValue("category")),
+		Cost:     r.PostFormValue("cost"),
+		Quantity: r.PostFormValue("quantity"),
+		LowStock: r.PostFormValue("low_stock_threshold"),
+		MinPrice: r.PostFormValue("min_price"),
+		Archived: r.PostFormValue("archived") == "on",
+	}
+}
+
+// productFormOf fills in the product form with a product as it is.
+func productFormOf(p *product.Product) productForm {
+	return productForm{
+		Name:     p.Name,
+		Category: p.Category,
+		Cost:     strconv.Itoa(p.Cost),
+		Quantity: strconv.Itoa(p.Quantity),
+		LowStock: strconv.Itoa(p.LowStock),
+		MinPrice: strconv.Itoa(p.MinPrice),
+		Archived: p.Archived,
+	}
+}
+
+// formInt reads a whole number from a form field, noting a field error when
+// it is not one.
+func formInt(r *http.Request, field string, dst *int, fields map[string]string) {
+	n, err := strconv.Atoi(strings.TrimSpace(r.PostFormValue(field)))
+	if err != nil {
+		fields[field] = field + " must be a whole number"
+		return
+	}
+	*dst = n
+}
+
+// validateForm checks a value read from a form with the same rules as the
+// JSON API, adding its field errors to those already noted. Any other error
+// is returned.
+func validateForm(val interface{}, fields map[string]string) error {
+	err := web.Validate(val)
+	if err == nil {
+		return nil
+	}
+
+	var webErr *web.Error
+	if !errors.As(err, &webErr) || len(webErr.Fields) == 0 {
+		return err
+	}
+
+	for _, fe := range webErr.Fields {
+		if _, ok := fields[fe.Field]; !ok {
+			fields[fe.Field] = fe.Error
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxAuditEntries is the most audit entries returned by a single request.
+const maxAuditEntries = 1000
+
+// Audit defines the handlers for reading the audit log.
+type Audit struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// List gives the audit log, oldest entries first. The actor, action,
+// resource_type and resource_id query parameters filter the entries, since and
+// until restrict them to a time range. Pages of at most limit entries are
+// walked by passing the seq of the last entry seen as after.
+func (a *Audit) List(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+
+	f := audit.Filter{
+		Actor:        q.Get("actor"),
+		Action:       q.Get("action"),
+		ResourceType: q.Get("resource_type"),
+		ResourceID:   q.Get("resource_id"),
+		Limit:        100,
+	}
+
+	if v := q.Get("since"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return web.NewRequestError(fmt.Errorf("invalid since: %w", err), http.StatusBadRequest)
+		}
+		f.Since = &t
+	}
+	if v := q.Get("until"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			return web.NewRequestError(fmt.Errorf("invalid until: %w", err), http.StatusBadRequest)
+		}
+		f.Until = &t
+	}
+	if v := q.Get("after"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return web.NewRequestError(fmt.Errorf("after must be a number"), http.StatusBadRequest)
+		}
+		f.AfterSeq = seq
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditEntries {
+			return web.NewRequestError(fmt.Errorf("limit must be between 1 and %d", maxAuditEntries), http.StatusBadRequest)
+		}
+		f.Limit = n
+	}
+
+	list, err := audit.List(r.Context(), a.DB, f)
+	if err != nil {
+		return fmt.Errorf("getting audit log: %w", err)
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
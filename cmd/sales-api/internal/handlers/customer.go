@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/customer"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// Customer defines the handlers for customer records.
+type Customer struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// List gets all customers. The q query parameter searches their names, emails
+// and phone numbers.
+func (c *Customer) List(w http.ResponseWriter, r *http.Request) error {
+	list, err := customer.List(r.Context(), c.DB, r.URL.Query().Get("q"))
+	if err != nil {
+		return fmt.Errorf("getting customers list: %w", err)
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Retrieve gives a single customer
+func (c *Customer) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	cu, err := customer.Retrieve(r.Context(), c.DB, id)
+	if err != nil {
+		switch err {
+		case customer.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case customer.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for customer %q; %w", id, err)
+		}
+	}
+
+	return web.Respond(w, cu, http.StatusOK)
+}
+
+// Create decodes a JSON document from a POST request and records a new
+// customer
+func (c *Customer) Create(w http.ResponseWriter, r *http.Request) error {
+	var nc customer.NewCustomer
+	if err := web.Decode(r, &nc); err != nil {
+		return fmt.Errorf("decoding new customer: %w", err)
+	}
+
+	cu, err := customer.Create(r.Context(), c.DB, nc, time.Now())
+	if err != nil {
+		return fmt.Errorf("creating customer: %w", err)
+	}
+
+	return web.Respond(w, cu, http.StatusCreated)
+}
+
+// Update decodes the body of a request to update an existing customer
+func (c *Customer) Update(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	var update customer.UpdateCustomer
+	if err := web.Decode(r, &update); err != nil {
+		return fmt.Errorf("decoding customer update: %w", err)
+	}
+
+	if err := customer.Update(r.Context(), c.DB, id, update, time.Now()); err != nil {
+		switch err {
+		case customer.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case customer.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("updating customer (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Purchases gets every sale made to a customer
+func (c *Customer) Purchases(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := customer.Purchases(r.Context(), c.DB, id)
+	if err != nil {
+		switch err {
+		case customer.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case customer.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting purchases of customer %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Merge folds the duplicate named in the request body into the customer and
+// responds with the merged customer.
+func (c *Customer) Merge(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	var m customer.Merge
+	if err := web.Decode(r, &m); err != nil {
+		return fmt.Errorf("decoding merge: %w", err)
+	}
+
+	cu, err := customer.MergeInto(r.Context(), c.DB, id, m.DuplicateID, time.Now())
+	if err != nil {
+		switch err {
+		case customer.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case customer.ErrInvalidID, customer.ErrMergeSelf:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("merging customer (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, cu, http.StatusOK)
+}
+
+// Anonymize removes the personal details of a customer.
+func (c *Customer) Anonymize(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	if err := customer.Anonymize(r.Context(), c.DB, id, time.Now()); err != nil {
+		switch err {
+		case customer.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case customer.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("anonymizing customer (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// Event defines the handlers for the garage sale events products and sales
+// are scoped to.
+type Event struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// List gets all events
+func (e *Event) List(w http.ResponseWriter, r *http.Request) error {
+	list, err := event.List(r.Context(), e.DB)
+	if err != nil {
+		return fmt.Errorf("getting events list: %w", err)
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// defaultRadius is how far, in kilometres, nearby searches look when the
+// request does not say.
+const defaultRadius = 10
+
+// Nearby finds the events around the point given by the lat and lng query
+// parameters, within radius kilometres. With open=true only events open right
+// now are listed.
+func (e *Event) Nearby(w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+
+	lat, err := strconv.ParseFloat(q.Get("lat"), 64)
+	if err != nil {
+		return web.NewRequestError(fmt.Errorf("lat must be a number"), http.StatusBadRequest)
+	}
+	lng, err := strconv.ParseFloat(q.Get("lng"), 64)
+	if err != nil {
+		return web.NewRequestError(fmt.Errorf("lng must be a number"), http.StatusBadRequest)
+	}
+
+	n := event.Nearby{Latitude: lat, Longitude: lng, Radius: defaultRadius}
+	if v := q.Get("radius"); v != "" {
+		radius, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return web.NewRequestError(fmt.Errorf("radius must be a number"), http.StatusBadRequest)
+		}
+		n.Radius = radius
+	}
+	if v := q.Get("open"); v != "" {
+		open, err := strconv.ParseBool(v)
+		if err != nil {
+			return web.NewRequestError(fmt.Errorf("open must be true or false"), http.StatusBadRequest)
+		}
+		n.OpenNow = open
+	}
+
+	list, err := event.FindNearby(r.Context(), e.DB, n, time.Now())
+	if err != nil {
+		switch err {
+		case event.ErrInvalidSearch:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("finding nearby events: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Retrieve gives a single event
+func (e *Event) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "eventID")
+
+	ev, err := event.Retrieve(r.Context(), e.DB, id)
+	if err != nil {
+		switch err {
+		case event.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case event.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for event %q; %w", id, err)
+		}
+	}
+
+	return web.Respond(w, ev, http.StatusOK)
+}
+
+// Create decodes a JSON document from a POST request and creates a new event
+func (e *Event) Create(w http.ResponseWriter, r *http.Request) error {
+	var ne event.NewEvent
+	if err := web.Decode(r, &ne); err != nil {
+		return fmt.Errorf("decoding new event: %w", err)
+	}
+
+	ev, err := event.Create(r.Context(), e.DB, ne, time.Now())
+	if err != nil {
+		switch err {
+		case event.ErrInvalidDates, event.ErrInvalidPlace, event.ErrInvalidZone, event.ErrInvalidHours:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("creating event: %w", err)
+		}
+	}
+
+	return web.Respond(w, ev, http.StatusCreated)
+}
+
+// Update decodes the body of a request to update an existing event
+func (e *Event) Update(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "eventID")
+
+	var update event.UpdateEvent
+	if err := web.Decode(r, &update); err != nil {
+		return fmt.Errorf("decoding event update: %w", err)
+	}
+
+	if err := event.Update(r.Context(), e.DB, id, update, time.Now()); err != nil {
+		switch err {
+		case event.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case event.ErrInvalidID, event.ErrInvalidDates, event.ErrInvalidPlace, event.ErrInvalidZone, event.ErrInvalidHours:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("updating event (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Delete removes an event that has no products left.
+func (e *Event) Delete(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "eventID")
+
+	if err := event.Delete(r.Context(), e.DB, id); err != nil {
+		switch err {
+		case event.ErrInvalidID, event.ErrDeleteDefault:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case event.ErrInUse:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("deleting event (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// eventID gives the event a request is scoped to. Routes outside of
+// /v1/events/{eventID} act on the default event.
+func eventID(r *http.Request) string {
+	if id := chi.URLParam(r, "eventID"); id != "" {
+		return id
+	}
+	return event.DefaultID
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/stream"
+)
+
+// heartbeat is how often an idle stream sends a comment to keep proxies from
+// closing the connection.
+const heartbeat = 15 * time.Second
+
+// Events streams live inventory changes to clients using Server-Sent Events.
+type Events struct {
+	Broker *stream.Broker
+}
+
+// Stream pushes product and sale events of the event as they happen. Clients
+// can narrow the stream with product_id and category query parameters and
+// resume after a disconnect with the Last-Event-ID header.
+func (e *Events) Stream(w http.ResponseWriter, r *http.Request) error {
+	var lastSeq int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return web.NewRequestError(errors.New("Last-Event-ID must be a number"), http.StatusBadRequest)
+		}
+		lastSeq = seq
+	}
+
+	f := stream.Filter{
+		EventID:    eventID(r),
+		ProductIDs: splitQuery(r, "product_id"),
+		Categories: splitQuery(r, "category"),
+	}
+
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	backlog, sub := e.Broker.Subscribe(f, lastSeq)
+	defer e.Broker.Unsubscribe(sub)
+
+	// write sends one chunk to the client, pushing the write deadline of the
+	// server out first since a stream outlives any request timeout.
+	write := func(format string, args ...interface{}) error {
+		rc.SetWriteDeadline(time.Now().Add(heartbeat * 2))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	for _, ev := range backlog {
+		if err := write("id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, ev.Data); err != nil {
+			return nil
+		}
+	}
+	if err := write(": connected\n\n"); err != nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	// Write errors mean the client went away, which is how every stream ends,
+	// so they are not reported.
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-ticker.C:
+			if err := write(": ping\n\n"); err != nil {
+				return nil
+			}
+		case ev, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := write("id: %d\nevent: %s\ndata: %s\n\n", ev.Seq, ev.Type, ev.Data); err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// splitQuery gives all values of a query parameter, accepting both repeated
+// parameters and comma separated lists.
+func splitQuery(r *http.Request, key string) []string {
+	var values []string
+	for _, v := range r.URL.Query()[key] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/images"
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/jmoiron/sqlx"
+)
+
+// Images defines the handlers for product images.
+type Images struct {
+	DB      *sqlx.DB
+	Log     *log.Logger
+	Storage storage.Storage
+	MaxSize int64
+}
+
+// Upload reads an image from the "image" field of a multipart form and adds
+// it to the product identified in the request URL.
+func (h *Images) Upload(w http.ResponseWriter, r *http.Request) error {
+	productID := chi.URLParam(r, "id")
+
+	if err := h.checkProduct(r); err != nil {
+		return err
+	}
+
+	// Leave some room for the multipart framing around the file itself.
+	r.Body = http.MaxBytesReader(w, r.Body, h.MaxSize+64<<10)
+
+	f, header, err := r.FormFile("image")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return web.NewRequestError(fmt.Errorf("image must be at most %d bytes", h.MaxSize), http.StatusRequestEntityTooLarge)
+		}
+		return web.NewRequestError(fmt.Errorf("reading image field: %w", err), http.StatusBadRequest)
+	}
+	defer f.Close()
+
+	if header.Size > h.MaxSize {
+		return web.NewRequestError(fmt.Errorf("image must be at most %d bytes", h.MaxSize), http.StatusRequestEntityTooLarge)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, h.MaxSize))
+	if err != nil {
+		return fmt.Errorf("reading image: %w", err)
+	}
+
+	img, err := images.Add(r.Context(), h.DB, h.Storage, productID, data, time.Now())
+	if err != nil {
+		switch err {
+		case images.ErrUnsupportedType:
+			return web.NewRequestError(err, http.StatusUnsupportedMediaType)
+		case images.ErrInvalidImage, images.ErrTooManyPixels:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("adding image: %w", err)
+		}
+	}
+
+	return web.Respond(w, img, http.StatusCreated)
+}
+
+// List gets all images of a product
+func (h *Images) List(w http.ResponseWriter, r *http.Request) error {
+	if err := h.checkProduct(r); err != nil {
+		return err
+	}
+
+	productID := chi.URLParam(r, "id")
+
+	list, err := images.List(r.Context(), h.DB, productID)
+	if err != nil {
+		switch err {
+		case images.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting images: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Download sends one size of an image, either "original" or a thumbnail.
+func (h *Images) Download(w http.ResponseWriter, r *http.Request) error {
+	if err := h.checkProduct(r); err != nil {
+		return err
+	}
+
+	productID := chi.URLParam(r, "id")
+	imageID := chi.URLParam(r, "imageID")
+	size := chi.URLParam(r, "size")
+
+	rc, contentType, err := images.Open(r.Context(), h.DB, h.Storage, productID, imageID, size)
+	if err != nil {
+		switch err {
+		case images.ErrNotFound, images.ErrUnknownSize:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case images.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("opening image: %w", err)
+		}
+	}
+	defer rc.Close()
+
+	// Images never change once uploaded so clients may cache them for good.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, rc); err != nil {
+		return fmt.Errorf("writing to client: %w", err)
+	}
+
+	return nil
+}
+
+// Update changes the position of an image or makes it the primary one.
+func (h *Images) Update(w http.ResponseWriter, r *http.Request) error {
+	if err := h.checkProduct(r); err != nil {
+		return err
+	}
+
+	productID := chi.URLParam(r, "id")
+	imageID := chi.URLParam(r, "imageID")
+
+	var update images.UpdateImage
+	if err := web.Decode(r, &update); err != nil {
+		return fmt.Errorf("decoding image update: %w", err)
+	}
+
+	if err := images.Update(r.Context(), h.DB, productID, imageID, update); err != nil {
+		switch err {
+		case images.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case images.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("updating image (id: %q): %w", imageID, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Delete removes a single image of a product.
+func (h *Images) Delete(w http.ResponseWriter, r *http.Request) error {
+	if err := h.checkProduct(r); err != nil {
+		return err
+	}
+
+	productID := chi.URLParam(r, "id")
+	imageID := chi.URLParam(r, "imageID")
+
+	if err := images.Delete(r.Context(), h.DB, h.Storage, productID, imageID); err != nil {
+		switch err {
+		case images.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("deleting image (id: %s): %w", imageID, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// checkProduct makes sure the product in the request URL belongs to the event
+// the request is scoped to so images never leak between events.
+func (h *Images) checkProduct(r *http.Request) error {
+	productID := chi.URLParam(r, "id")
+
+	if _, err := product.Retrieve(r.Context(), h.DB, eventID(r), productID); err != nil {
+		switch err {
+		case product.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for product %q; %w", productID, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/label"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxSheetLabels caps how many labels a single sheet request may print.
+const maxSheetLabels = 1000
+
+// Label defines the handlers for printing price labels and resolving the
+// codes printed on them.
+type Label struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// SheetRequest lists the products to print on a sheet of labels.
+type SheetRequest struct {
+	Symbology string      `json:"symbology" validate:"omitempty,oneof=code128 qr"`
+	Items     []SheetItem `json:"items" validate:"required,min=1,dive"`
+}
+
+// SheetItem is one product, or variant of it, to print Copies labels for.
+type SheetItem struct {
+	ProductID string `json:"product_id" validate:"required"`
+	VariantID string `json:"variant_id"`
+	Copies    int    `json:"copies" validate:"omitempty,gte=1,lte=1000"`
+}
+
+// LookupResult is what a scanned code resolves to.
+type LookupResult struct {
+	Product *product.Product `json:"product"`
+	Variant *product.Variant `json:"variant,omitempty"`
+}
+
+// Label renders the label of a product as a PNG or PDF. The format and
+// symbology query parameters pick the output, a variant parameter prints the
+// label of a variant with its SKU.
+func (h *Label) Label(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+	q := r.URL.Query()
+
+	symbology := q.Get("symbology")
+	if symbology == "" {
+		symbology = label.Code128
+	}
+
+	l, err := h.label(r, id, q.Get("variant"))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch q.Get("format") {
+	case "", "png":
+		contentType = "image/png"
+		err = label.PNG(&buf, *l, symbology)
+	case "pdf":
+		contentType = "application/pdf"
+		err = label.PDF(&buf, *l, symbology)
+	default:
+		return web.NewRequestError(fmt.Errorf("format must be png or pdf"), http.StatusBadRequest)
+	}
+	if err != nil {
+		if err == label.ErrUnknownSymbology {
+			return web.NewRequestError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("rendering label: %w", err)
+	}
+
+	return respondFile(w, contentType, buf.Bytes())
+}
+
+// Sheet renders an A4 PDF with labels for every requested product.
+func (h *Label) Sheet(w http.ResponseWriter, r *http.Request) error {
+	var req SheetRequest
+	if err := web.Decode(r, &req); err != nil {
+		return err
+	}
+	if req.Symbology == "" {
+		req.Symbology = label.Code128
+	}
+
+	var labels []label.Label
+	for _, item := range req.Items {
+		l, err := h.label(r, item.ProductID, item.VariantID)
+		if err != nil {
+			return err
+		}
+
+		copies := item.Copies
+		if copies == 0 {
+			copies = 1
+		}
+		if len(labels)+copies > maxSheetLabels {
+			return web.NewRequestError(fmt.Errorf("at most %d labels can be printed at once", maxSheetLabels), http.StatusBadRequest)
+		}
+		for i := 0; i < copies; i++ {
+			labels = append(labels, *l)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := label.Sheet(&buf, labels, req.Symbology); err != nil {
+		return fmt.Errorf("rendering label sheet: %w", err)
+	}
+
+	return respondFile(w, "application/pdf", buf.Bytes())
+}
+
+// Lookup resolves a scanned code, either a product ID or a SKU, to a product.
+func (h *Label) Lookup(w http.ResponseWriter, r *http.Request) error {
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		return web.NewRequestError(fmt.Errorf("code is required"), http.StatusBadRequest)
+	}
+
+	p, v, err := product.Lookup(r.Context(), h.DB, eventID(r), code)
+	if err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("looking up code %q: %w", code, err)
+		}
+	}
+
+	return web.Respond(w, LookupResult{Product: p, Variant: v}, http.StatusOK)
+}
+
+// label builds the label of a product or one of its variants.
+func (h *Label) label(r *http.Request, productID, variantID string) (*label.Label, error) {
+	p, err := product.Retrieve(r.Context(), h.DB, eventID(r), productID)
+	if err != nil {
+		switch err {
+		case product.ErrNotFound:
+			return nil, web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return nil, web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return nil, fmt.Errorf("looking for product %q; %w", productID, err)
+		}
+	}
+
+	l := label.Label{Name: p.Name, Price: p.Cost, Code: p.ID}
+	if variantID == "" {
+		return &l, nil
+	}
+
+	for _, v := range p.Variants {
+		if v.ID == variantID {
+			l.Name = p.Name + " - " + v.Name
+			l.Code = v.SKU
+			if v.Cost != nil {
+				l.Price = *v.Cost
+			}
+			return &l, nil
+		}
+	}
+
+	return nil, web.NewRequestError(product.ErrVariantNotFound, http.StatusNotFound)
+}
+
+// respondFile sends a rendered document to the client.
+func respondFile(w http.ResponseWriter, contentType string, data []byte) error {
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("writing to client: %w", err)
+	}
+
+	return nil
+}
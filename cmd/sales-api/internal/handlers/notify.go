@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/ivan-sabo/garagesale/internal/notify"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/receipt"
+)
+
+// notifySale queues the emails triggered by a sale: the receipt for the
+// customer and a low stock alert for staff when the sale takes the product
+// down to its threshold. The sale has already been recorded so failures are
+// only logged.
+func (p *Product) notifySale(ctx context.Context, email string, s *product.Sale) {
+	prod, err := product.Retrieve(ctx, p.DB, s.EventID, s.ProductID)
+	if err != nil {
+		p.Log.Printf("notify : looking for product %q: %v", s.ProductID, err)
+		return
+	}
+
+	if email != "" {
+		rc := receipt.Build(p.Receipt, *s, *prod)
+
+		var buf bytes.Buffer
+		if err := receipt.Text(&buf, rc, p.Receipt.Width); err != nil {
+			p.Log.Printf("notify : rendering receipt %q: %v", s.ID, err)
+		} else {
+			data := struct {
+				Shop    string
+				Receipt string
+			}{
+				Shop:    "us",
+				Receipt: buf.String(),
+			}
+			if len(rc.Header) > 0 {
+				data.Shop = rc.Header[0]
+			}
+			p.enqueue(notify.TemplateReceipt, []string{email}, data)
+		}
+	}
+
+	// Alert only on the sale that crosses the threshold so staff are not
+	// emailed again for every sale after it.
+	remaining := s.Remaining
+	if len(p.StaffEmails) > 0 && prod.LowStock > 0 &&
+		remaining <= prod.LowStock && remaining+s.Quantity > prod.LowStock {
+		data := struct {
+			ID        string
+			Name      string
+			Remaining int
+			Threshold int
+			Sold      int
+		}{
+			ID:        prod.ID,
+			Name:      prod.Name,
+			Remaining: remaining,
+			Threshold: prod.LowStock,
+			Sold:      prod.Sold,
+		}
+		p.enqueue(notify.TemplateLowStock, p.StaffEmails, data)
+	}
+}
+
+// enqueue renders a message template and queues it for delivery.
+func (p *Product) enqueue(name string, to []string, data interface{}) {
+	m, err := notify.Render(name, to, data)
+	if err != nil {
+		p.Log.Printf("notify : %v", err)
+		return
+	}
+	p.Notify.Enqueue(m)
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/customer"
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/images"
+	"github.com/ivan-sabo/garagesale/internal/platform/openapi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/seller"
+	"github.com/ivan-sabo/garagesale/internal/tax"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
+)
+
+// OpenAPI serves the OpenAPI 3 document describing the API.
+type OpenAPI struct {
+	Doc *openapi.Document
+}
+
+// Spec responds with the OpenAPI document.
+func (o *OpenAPI) Spec(w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(w, o.Doc, http.StatusOK)
+}
+
+// apiRoute is a route of the API as described in the OpenAPI document.
+type apiRoute struct {
+	method string
+	path   string
+	openapi.Route
+}
+
+// eventRoutes are the routes of products, and everything hanging off them,
+// served under both /v1 and /v1/events/{eventID}.
+var eventRoutes = []apiRoute{
+	{http.MethodGet, "/products", openapi.Route{Summary: "List products", Tag: "products", Response: []product.Product{}}},
+	{http.MethodPost, "/products", openapi.Route{Summary: "Create a product", Tag: "products", Request: product.NewProduct{}, Response: product.Product{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/products/{id}", openapi.Route{Summary: "Retrieve a product, as it was at as_of when given", Tag: "products", Query: []string{"as_of"}, Response: product.Product{}}},
+	{http.MethodPut, "/products/{id}", openapi.Route{Summary: "Update a product", Tag: "products", Request: product.UpdateProduct{}, Status: http.StatusNoContent}},
+	{http.MethodDelete, "/products/{id}", openapi.Route{Summary: "Delete a product", Tag: "products", Status: http.StatusNoContent}},
+	{http.MethodGet, "/products/{id}/history", openapi.Route{Summary: "List the versions of a product", Tag: "products", Response: []product.Version{}}},
+	{http.MethodPost, "/products/{id}/revert", openapi.Route{Summary: "Revert a product to a previous version", Tag: "products", Request: product.Revert{}, Status: http.StatusNoContent}},
+
+	{http.MethodPost, "/products/{id}/sales", openapi.Route{Summary: "Record a sale", Tag: "sales", Request: product.NewSale{}, Response: product.Sale{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/products/{id}/sales", openapi.Route{Summary: "List the sales of a product", Tag: "sales", Response: []product.Sale{}}},
+	{http.MethodGet, "/reports/daily", openapi.Route{Summary: "Add up the sales and refunds by day", Tag: "sales", Response: []product.DayTotal{}}},
+	{http.MethodGet, "/sales/{id}/receipt", openapi.Route{Summary: "Render the receipt of a sale as text, HTML or PDF", Tag: "sales", Query: []string{"format", "width"}, ContentType: "text/plain"}},
+
+	{http.MethodPost, "/products/{id}/adjustments", openapi.Route{Summary: "Adjust the stock of a product", Tag: "inventory", Request: product.NewAdjustment{}, Response: product.Movement{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/products/{id}/movements", openapi.Route{Summary: "List the stock movements of a product", Tag: "inventory", Response: []product.Movement{}}},
+
+	{http.MethodPost, "/products/{id}/reservations", openapi.Route{Summary: "Reserve units of a product", Tag: "reservations", Request: product.NewReservation{}, Response: product.Reservation{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/products/{id}/reservations", openapi.Route{Summary: "List the reservations of a product", Tag: "reservations", Response: []product.Reservation{}}},
+	{http.MethodGet, "/reservations/{reservationID}", openapi.Route{Summary: "Retrieve a reservation", Tag: "reservations", Response: product.Reservation{}}},
+	{http.MethodDelete, "/reservations/{reservationID}", openapi.Route{Summary: "Release a reservation", Tag: "reservations", Status: http.StatusNoContent}},
+
+	{http.MethodPost, "/products/{id}/offers", openapi.Route{Summary: "Make an offer", Tag: "offers", Request: product.NewOffer{}, Response: product.Offer{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/products/{id}/offers", openapi.Route{Summary: "List the offers made for a product", Tag: "offers", Response: []product.Offer{}}},
+	{http.MethodGet, "/offers/{offerID}", openapi.Route{Summary: "Retrieve an offer", Tag: "offers", Response: product.Offer{}}},
+	{http.MethodPost, "/offers/{offerID}/response", openapi.Route{Summary: "Accept, reject or counter an offer", Tag: "offers", Request: product.OfferResponse{}, Response: product.Offer{}}},
+
+	{http.MethodPost, "/products/{id}/auctions", openapi.Route{Summary: "Put a product up for auction", Tag: "auctions", Request: product.NewAuction{}, Response: product.Auction{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/products/{id}/auctions", openapi.Route{Summary: "List the auctions of a product", Tag: "auctions", Response: []product.Auction{}}},
+	{http.MethodGet, "/auctions/{auctionID}", openapi.Route{Summary: "Retrieve an auction", Tag: "auctions", Response: product.Auction{}}},
+	{http.MethodPost, "/auctions/{auctionID}/bids", openapi.Route{Summary: "Bid in an auction", Tag: "auctions", Request: product.NewBid{}, Response: product.Bid{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/auctions/{auctionID}/bids", openapi.Route{Summary: "List the bids of an auction, the highest first", Tag: "auctions", Response: []product.Bid{}}},
+
+	{http.MethodGet, "/products/{id}/variants", openapi.Route{Summary: "List the variants of a product", Tag: "variants", Response: []product.Variant{}}},
+	{http.MethodPost, "/products/{id}/variants", openapi.Route{Summary: "Add a variant", Tag: "variants", Request: product.NewVariant{}, Response: product.Variant{}, Status: http.StatusCreated}},
+	{http.MethodPut, "/products/{id}/variants/{variantID}", openapi.Route{Summary: "Update a variant", Tag: "variants", Request: product.UpdateVariant{}, Status: http.StatusNoContent}},
+	{http.MethodDelete, "/products/{id}/variants/{variantID}", openapi.Route{Summary: "Delete a variant", Tag: "variants", Status: http.StatusNoContent}},
+
+	{http.MethodGet, "/products/{id}/label", openapi.Route{Summary: "Render the label of a product as PNG or PDF", Tag: "labels", Query: []string{"format", "symbology", "variant"}, ContentType: "image/png"}},
+	{http.MethodPost, "/labels", openapi.Route{Summary: "Render a sheet of labels as PDF", Tag: "labels", Request: SheetRequest{}, ContentType: "application/pdf"}},
+	{http.MethodGet, "/lookup", openapi.Route{Summary: "Find the product of a scanned code", Tag: "labels", Query: []string{"code"}, Response: LookupResult{}}},
+
+	{http.MethodGet, "/sellers", openapi.Route{Summary: "List sellers", Tag: "sellers", Response: []seller.Seller{}}},
+	{http.MethodPost, "/sellers", openapi.Route{Summary: "Create a seller", Tag: "sellers", Request: seller.NewSeller{}, Response: seller.Seller{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/sellers/{sellerID}", openapi.Route{Summary: "Retrieve a seller", Tag: "sellers", Response: seller.Seller{}}},
+	{http.MethodPut, "/sellers/{sellerID}", openapi.Route{Summary: "Update a seller", Tag: "sellers", Request: seller.UpdateSeller{}, Status: http.StatusNoContent}},
+	{http.MethodDelete, "/sellers/{sellerID}", openapi.Route{Summary: "Delete a seller", Tag: "sellers", Status: http.StatusNoContent}},
+	{http.MethodGet, "/settlements", openapi.Route{Summary: "List what sellers are owed", Tag: "sellers", Response: []seller.Settlement{}}},
+
+	{http.MethodGet, "/coupons", openapi.Route{Summary: "List coupons", Tag: "pricing", Response: []pricing.Coupon{}}},
+	{http.MethodPost, "/coupons", openapi.Route{Summary: "Create a coupon", Tag: "pricing", Request: pricing.NewCoupon{}, Response: pricing.Coupon{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/markdowns", openapi.Route{Summary: "List markdowns", Tag: "pricing", Response: []pricing.Markdown{}}},
+	{http.MethodPost, "/markdowns", openapi.Route{Summary: "Create a markdown", Tag: "pricing", Request: pricing.NewMarkdown{}, Response: pricing.Markdown{}, Status: http.StatusCreated}},
+	{http.MethodDelete, "/markdowns/{id}", openapi.Route{Summary: "Delete a markdown", Tag: "pricing", Status: http.StatusNoContent}},
+	{http.MethodGet, "/stream", openapi.Route{Summary: "Stream product and sale changes as Server-Sent Events", Tag: "stream", Query: []string{"product_id", "category"}, ContentType: "text/event-stream"}},
+	{http.MethodGet, "/tax/report", openapi.Route{Summary: "Report the tax collected between from and to", Tag: "tax", Query: []string{"from", "to"}, Response: tax.Report{}}},
+
+	{http.MethodGet, "/products/{id}/images", openapi.Route{Summary: "List the images of a product", Tag: "images", Response: []images.Image{}}},
+	{http.MethodPost, "/products/{id}/images", openapi.Route{Summary: "Upload an image", Tag: "images", Files: []string{"image"}, Response: images.Image{}, Status: http.StatusCreated}},
+	{http.MethodPut, "/products/{id}/images/{imageID}", openapi.Route{Summary: "Update an image", Tag: "images", Request: images.UpdateImage{}, Status: http.StatusNoContent}},
+	{http.MethodDelete, "/products/{id}/images/{imageID}", openapi.Route{Summary: "Delete an image", Tag: "images", Status: http.StatusNoContent}},
+	{http.MethodGet, "/products/{id}/images/{imageID}/{size}", openapi.Route{Summary: "Download an image in a size", Tag: "images", ContentType: "image/jpeg"}},
+}
+
+// publicRoutes are the routes of the storefront, served under both
+// /public/v1 and /public/v1/events/{eventID}.
+var publicRoutes = []apiRoute{
+	{http.MethodGet, "/products", openapi.Route{Summary: "List the products on show", Tag: "storefront", Response: []product.PublicProduct{}}},
+	{http.MethodGet, "/products/{id}", openapi.Route{Summary: "Retrieve a product on show", Tag: "storefront", Response: product.PublicProduct{}}},
+	{http.MethodGet, "/products/{id}/images/{imageID}/{size}", openapi.Route{Summary: "Download an image of a product on show", Tag: "storefront", ContentType: "image/jpeg"}},
+}
+
+// otherRoutes are the routes not tied to an event.
+var otherRoutes = []apiRoute{
+	{http.MethodGet, "/v1/health", openapi.Route{Summary: "Check the service is ready for traffic", Tag: "health", Response: struct {
+		Status string `json:"status"`
+	}{}}},
+	{http.MethodGet, "/v1/openapi.json", openapi.Route{Summary: "This document", Tag: "health", ContentType: "application/json"}},
+	{http.MethodGet, "/v1/audit", openapi.Route{Summary: "List the audit log", Tag: "audit", Query: []string{"actor", "action", "resource_type", "resource_id", "since", "until", "after", "limit"}, Response: []audit.Entry{}}},
+
+	{http.MethodGet, "/v1/events", openapi.Route{Summary: "List events", Tag: "events", Response: []event.Event{}}},
+	{http.MethodPost, "/v1/events", openapi.Route{Summary: "Create an event", Tag: "events", Request: event.NewEvent{}, Response: event.Event{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/v1/events/nearby", openapi.Route{Summary: "Find events near a place", Tag: "events", Query: []string{"lat", "lng", "radius", "open"}, Response: []event.Event{}}},
+	{http.MethodGet, "/v1/events/{eventID}", openapi.Route{Summary: "Retrieve an event", Tag: "events", Response: event.Event{}}},
+	{http.MethodPut, "/v1/events/{eventID}", openapi.Route{Summary: "Update an event", Tag: "events", Request: event.UpdateEvent{}, Status: http.StatusNoContent}},
+	{http.MethodDelete, "/v1/events/{eventID}", openapi.Route{Summary: "Delete an event", Tag: "events", Status: http.StatusNoContent}},
+
+	{http.MethodGet, "/v1/customers", openapi.Route{Summary: "List customers, searching them with q", Tag: "customers", Query: []string{"q"}, Response: []customer.Customer{}}},
+	{http.MethodPost, "/v1/customers", openapi.Route{Summary: "Create a customer", Tag: "customers", Request: customer.NewCustomer{}, Response: customer.Customer{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/v1/customers/{id}", openapi.Route{Summary: "Retrieve a customer", Tag: "customers", Response: customer.Customer{}}},
+	{http.MethodPut, "/v1/customers/{id}", openapi.Route{Summary: "Update a customer", Tag: "customers", Request: customer.UpdateCustomer{}, Status: http.StatusNoContent}},
+	{http.MethodGet, "/v1/customers/{id}/purchases", openapi.Route{Summary: "List the purchases of a customer", Tag: "customers", Response: []product.Sale{}}},
+	{http.MethodPost, "/v1/customers/{id}/merge", openapi.Route{Summary: "Merge another customer into this one", Tag: "customers", Request: customer.Merge{}, Response: customer.Customer{}}},
+	{http.MethodPost, "/v1/customers/{id}/anonymize", openapi.Route{Summary: "Remove the personal details of a customer", Tag: "customers", Status: http.StatusNoContent}},
+
+	{http.MethodGet, "/v1/tax/rates", openapi.Route{Summary: "List tax rates", Tag: "tax", Response: []tax.Rate{}}},
+	{http.MethodPut, "/v1/tax/rates", openapi.Route{Summary: "Set a tax rate", Tag: "tax", Request: tax.NewRate{}, Response: tax.Rate{}}},
+	{http.MethodDelete, "/v1/tax/rates/{id}", openapi.Route{Summary: "Delete a tax rate", Tag: "tax", Status: http.StatusNoContent}},
+
+	{http.MethodGet, "/v1/webhooks", openapi.Route{Summary: "List webhook subscriptions", Tag: "webhooks", Response: []webhook.Subscription{}}},
+	{http.MethodPost, "/v1/webhooks", openapi.Route{Summary: "Subscribe to webhooks", Tag: "webhooks", Request: webhook.NewSubscription{}, Response: webhook.CreatedSubscription{}, Status: http.StatusCreated}},
+	{http.MethodGet, "/v1/webhooks/{id}", openapi.Route{Summary: "Retrieve a webhook subscription", Tag: "webhooks", Response: webhook.Subscription{}}},
+	{http.MethodPut, "/v1/webhooks/{id}", openapi.Route{Summary: "Update a webhook subscription", Tag: "webhooks", Request: webhook.UpdateSubscription{}, Status: http.StatusNoContent}},
+	{http.MethodDelete, "/v1/webhooks/{id}", openapi.Route{Summary: "Delete a webhook subscription", Tag: "webhooks", Status: http.StatusNoContent}},
+	{http.MethodGet, "/v1/webhooks/{id}/deliveries", openapi.Route{Summary: "List the deliveries of a subscription", Tag: "webhooks", Response: []webhook.Delivery{}}},
+	{http.MethodGet, "/v1/webhooks/{id}/deliveries/{deliveryID}/attempts", openapi.Route{Summary: "List the attempts of a delivery", Tag: "webhooks", Response: []webhook.Attempt{}}},
+}
+
+// openAPIDoc builds the OpenAPI document of the API. The HTML pages of the
+// admin UI are not part of it.
+func openAPIDoc() *openapi.Document {
+	doc := openapi.New("Garage Sale API", "1", web.ErrorResponse{})
+
+	for _, prefix := range []string{"/v1", "/v1/events/{eventID}"} {
+		for _, r := range eventRoutes {
+			doc.Add(r.method, prefix+r.path, r.Route)
+		}
+	}
+	for _, prefix := range []string{"/public/v1", "/public/v1/events/{eventID}"} {
+		for _, r := range publicRoutes {
+			doc.Add(r.method, prefix+r.path, r.Route)
+		}
+	}
+	for _, r := range otherRoutes {
+		doc.Add(r.method, r.path, r.Route)
+	}
+
+	return doc
+}
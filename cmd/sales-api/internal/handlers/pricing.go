@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
+	"github.com/jmoiron/sqlx"
+)
+
+// Pricing defines the handlers for managing coupons and markdowns.
+type Pricing struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// ListCoupons gets all coupons of the event
+func (p *Pricing) ListCoupons(w http.ResponseWriter, r *http.Request) error {
+	list, err := pricing.ListCoupons(r.Context(), p.DB, eventID(r))
+	if err != nil {
+		if err == pricing.ErrInvalidID {
+			return web.NewRequestError(err, http.StatusBadRequest)
+		}
+		return err
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// CreateCoupon decodes a JSON document from a POST request and creates a new
+// Coupon at the event
+func (p *Pricing) CreateCoupon(w http.ResponseWriter, r *http.Request) error {
+	var nc pricing.NewCoupon
+	if err := web.Decode(r, &nc); err != nil {
+		return err
+	}
+
+	c, err := pricing.CreateCoupon(r.Context(), p.DB, eventID(r), nc, time.Now())
+	if err != nil {
+		switch err {
+		case pricing.ErrInvalidID, pricing.ErrInvalidDiscount:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case pricing.ErrEventNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case pricing.ErrCouponCodeExists:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("creating coupon: %w", err)
+		}
+	}
+
+	return web.Respond(w, c, http.StatusCreated)
+}
+
+// ListMarkdowns gets all scheduled markdowns of the event
+func (p *Pricing) ListMarkdowns(w http.ResponseWriter, r *http.Request) error {
+	list, err := pricing.ListMarkdowns(r.Context(), p.DB, eventID(r))
+	if err != nil {
+		if err == pricing.ErrInvalidID {
+			return web.NewRequestError(err, http.StatusBadRequest)
+		}
+		return err
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// CreateMarkdown decodes a JSON document from a POST request and schedules a
+// new Markdown at the event
+func (p *Pricing) CreateMarkdown(w http.ResponseWriter, r *http.Request) error {
+	var nm pricing.NewMarkdown
+	if err := web.Decode(r, &nm); err != nil {
+		return err
+	}
+
+	m, err := pricing.CreateMarkdown(r.Context(), p.DB, eventID(r), nm, time.Now())
+	if err != nil {
+		switch err {
+		case pricing.ErrInvalidID, pricing.ErrInvalidDiscount, pricing.ErrMarkdownTimes:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case pricing.ErrEventNotFound, pricing.ErrProductNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		default:
+			return fmt.Errorf("creating markdown: %w", err)
+		}
+	}
+
+	return web.Respond(w, m, http.StatusCreated)
+}
+
+// DeleteMarkdown removes a single markdown identified by an ID in the request
+// URL.
+func (p *Pricing) DeleteMarkdown(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	if err := pricing.DeleteMarkdown(r.Context(), p.DB, eventID(r), id); err != nil {
+		switch err {
+		case pricing.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("deleting markdown (id: %s): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
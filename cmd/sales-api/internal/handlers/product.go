@@ -7,21 +7,30 @@ import (
 	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/images"
+	"github.com/ivan-sabo/garagesale/internal/notify"
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
 	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
 	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/receipt"
 	"github.com/jmoiron/sqlx"
 )
 
 // Product defines all of the handlers related to products. It holds the
 // application state needed by the handler methods
 type Product struct {
-	DB  *sqlx.DB
-	Log *log.Logger
+	DB          *sqlx.DB
+	Log         *log.Logger
+	Storage     storage.Storage
+	Notify      *notify.Queue
+	StaffEmails []string
+	Receipt     receipt.Config
 }
 
 // List gets all products from the service layer
 func (p *Product) List(w http.ResponseWriter, r *http.Request) error {
-	list, err := product.List(r.Context(), p.DB)
+	list, err := product.List(r.Context(), p.DB, eventID(r))
 	if err != nil {
 		return err
 	}
@@ -29,11 +38,22 @@ func (p *Product) List(w http.ResponseWriter, r *http.Request) error {
 	return web.Respond(w, list, http.StatusOK)
 }
 
-// Retrieve gives a signle Product
+// Retrieve gives a signle Product. With an as_of query parameter, holding a
+// date or an RFC 3339 timestamp, it gives the product as it was at that time.
 func (p *Product) Retrieve(w http.ResponseWriter, r *http.Request) error {
 	id := chi.URLParam(r, "id")
 
-	prod, err := product.Retrieve(r.Context(), p.DB, id)
+	var prod *product.Product
+	var err error
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		asOf, perr := parseTime(v)
+		if perr != nil {
+			return web.NewRequestError(fmt.Errorf("invalid as_of: %w", perr), http.StatusBadRequest)
+		}
+		prod, err = product.RetrieveAsOf(r.Context(), p.DB, eventID(r), id, asOf)
+	} else {
+		prod, err = product.Retrieve(r.Context(), p.DB, eventID(r), id)
+	}
 	if err != nil {
 		switch err {
 		case product.ErrNotFound:
@@ -56,9 +76,16 @@ func (p *Product) Create(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	prod, err := product.Create(r.Context(), p.DB, np, time.Now())
+	prod, err := product.Create(r.Context(), p.DB, eventID(r), np, time.Now())
 	if err != nil {
-		return err
+		switch err {
+		case product.ErrEventNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrSellerNotFound:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return err
+		}
 	}
 
 	return web.Respond(w, prod, http.StatusCreated)
@@ -74,12 +101,14 @@ func (p *Product) Update(w http.ResponseWriter, r *http.Request) error {
 		return fmt.Errorf("decoding product update: %w", err)
 	}
 
-	if err := product.Update(r.Context(), p.DB, id, update, time.Now()); err != nil {
+	if err := product.Update(r.Context(), p.DB, eventID(r), id, update, time.Now()); err != nil {
 		switch err {
 		case product.ErrNotFound:
 			return web.NewRequestError(err, http.StatusNotFound)
-		case product.ErrInvalidID:
+		case product.ErrInvalidID, product.ErrVariantRequired, product.ErrSellerNotFound:
 			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrInsufficientStock:
+			return web.NewRequestError(err, http.StatusConflict)
 		default:
 			return fmt.Errorf("updating product (id: %q): %w", id, err)
 		}
@@ -88,11 +117,56 @@ func (p *Product) Update(w http.ResponseWriter, r *http.Request) error {
 	return web.Respond(w, nil, http.StatusNoContent)
 }
 
+// History gives every version of a product, oldest first.
+func (p *Product) History(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := product.ListVersions(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting history of product %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Revert restores a product to a previous version given in the request body.
+func (p *Product) Revert(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	var rv product.Revert
+	if err := web.Decode(r, &rv); err != nil {
+		return fmt.Errorf("decoding revert: %w", err)
+	}
+
+	if err := product.RevertTo(r.Context(), p.DB, eventID(r), id, rv.Version, time.Now()); err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVersionNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrSellerNotFound:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("reverting product (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
 // Delete removes a single product identified by an ID in the request URL.
 func (p *Product) Delete(w http.ResponseWriter, r *http.Request) error {
 	id := chi.URLParam(r, "id")
 
-	if err := product.Delete(r.Context(), p.DB, id); err != nil {
+	imgs, err := product.Delete(r.Context(), p.DB, eventID(r), id, time.Now())
+	if err != nil {
 		switch err {
 		case product.ErrInvalidID:
 			return web.NewRequestError(err, http.StatusBadRequest)
@@ -101,6 +175,12 @@ func (p *Product) Delete(w http.ResponseWriter, r *http.Request) error {
 		}
 	}
 
+	// Image rows went away with the product but their files have to be
+	// removed from storage explicitly.
+	if p.Storage != nil {
+		images.RemoveFiles(r.Context(), p.Storage, imgs)
+	}
+
 	return web.Respond(w, nil, http.StatusNoContent)
 }
 
@@ -114,9 +194,22 @@ func (p *Product) AddSale(w http.ResponseWriter, r *http.Request) error {
 
 	productID := chi.URLParam(r, "id")
 
-	sale, err := product.AddSale(r.Context(), p.DB, ns, productID, time.Now())
+	sale, err := product.AddSale(r.Context(), p.DB, eventID(r), ns, productID, time.Now())
 	if err != nil {
-		return fmt.Errorf("adding new sale: %w", err)
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound, product.ErrCustomerNotFound, product.ErrReservationNotFound, product.ErrOfferNotFound, product.ErrAuctionNotFound, pricing.ErrCouponNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrVariantRequired, product.ErrReservationMismatch, product.ErrOfferMismatch, product.ErrAuctionMismatch, pricing.ErrCouponExpired, pricing.ErrCouponExhausted:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrReservationClosed, product.ErrInsufficientStock, product.ErrOfferNotAccepted, product.ErrOfferExpired, product.ErrAuctionNotWon:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("adding new sale: %w", err)
+		}
+	}
+
+	if p.Notify != nil {
+		p.notifySale(r.Context(), ns.Email, sale)
 	}
 
 	return web.Respond(w, sale, http.StatusCreated)
@@ -126,10 +219,441 @@ func (p *Product) AddSale(w http.ResponseWriter, r *http.Request) error {
 func (p *Product) ListSales(w http.ResponseWriter, r *http.Request) error {
 	id := chi.URLParam(r, "id")
 
-	list, err := product.ListSales(r.Context(), p.DB, id)
+	list, err := product.ListSales(r.Context(), p.DB, eventID(r), id)
 	if err != nil {
+		if err == product.ErrInvalidID {
+			return web.NewRequestError(err, http.StatusBadRequest)
+		}
 		return fmt.Errorf("getting sales list: %w", err)
 	}
 
 	return web.Respond(w, list, http.StatusOK)
 }
+
+// DailyTotals gives the sales and refunds of the event added up by day, the
+// most recent first.
+func (p *Product) DailyTotals(w http.ResponseWriter, r *http.Request) error {
+	list, err := product.DailyTotals(r.Context(), p.DB, eventID(r))
+	if err != nil {
+		if err == product.ErrInvalidID {
+			return web.NewRequestError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("getting daily totals: %w", err)
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Adjust records a manual change of the stock of a product. It looks for a
+// JSON object in the request body and records the X-Actor header as who made
+// the change.
+func (p *Product) Adjust(w http.ResponseWriter, r *http.Request) error {
+	var na product.NewAdjustment
+	if err := web.Decode(r, &na); err != nil {
+		return fmt.Errorf("decoding adjustment: %w", err)
+	}
+
+	id := chi.URLParam(r, "id")
+
+	m, err := product.Adjust(r.Context(), p.DB, eventID(r), id, na, r.Header.Get("X-Actor"), time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound, product.ErrSaleNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrVariantRequired, product.ErrInvalidAdjustment, product.ErrRefundOnly:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrInsufficientStock, product.ErrRefundExceedsSale:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("adjusting stock of product %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, m, http.StatusCreated)
+}
+
+// ListMovements gets the inventory ledger of a product
+func (p *Product) ListMovements(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := product.ListMovements(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting movements list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// ListVariants gets all variants of a product
+func (p *Product) ListVariants(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := product.ListVariants(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting variants list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// AddVariant decodes a JSON document and adds a new variant to a product
+func (p *Product) AddVariant(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	var nv product.NewVariant
+	if err := web.Decode(r, &nv); err != nil {
+		return fmt.Errorf("decoding new variant: %w", err)
+	}
+
+	v, err := product.AddVariant(r.Context(), p.DB, eventID(r), id, nv, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrSKUExists:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("adding variant: %w", err)
+		}
+	}
+
+	return web.Respond(w, v, http.StatusCreated)
+}
+
+// UpdateVariant decodes the body of a request to update a variant of a
+// product.
+func (p *Product) UpdateVariant(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+	variantID := chi.URLParam(r, "variantID")
+
+	var update product.UpdateVariant
+	if err := web.Decode(r, &update); err != nil {
+		return fmt.Errorf("decoding variant update: %w", err)
+	}
+
+	if err := product.EditVariant(r.Context(), p.DB, eventID(r), id, variantID, update, time.Now()); err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrSKUExists, product.ErrInsufficientStock:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("updating variant (id: %q): %w", variantID, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// DeleteVariant removes a single variant of a product.
+func (p *Product) DeleteVariant(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+	variantID := chi.URLParam(r, "variantID")
+
+	if err := product.DeleteVariant(r.Context(), p.DB, eventID(r), id, variantID, time.Now()); err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrVariantInUse:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("deleting variant (id: %s): %w", variantID, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Reserve holds units of a product for a customer. It looks for a JSON object
+// in the request body.
+func (p *Product) Reserve(w http.ResponseWriter, r *http.Request) error {
+	var nr product.NewReservation
+	if err := web.Decode(r, &nr); err != nil {
+		return fmt.Errorf("decoding reservation: %w", err)
+	}
+
+	id := chi.URLParam(r, "id")
+
+	res, err := product.Reserve(r.Context(), p.DB, eventID(r), id, nr, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound, product.ErrCustomerNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrVariantRequired, product.ErrInvalidExpiry:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrInsufficientStock:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("reserving product %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, res, http.StatusCreated)
+}
+
+// ListReservations gets the reservations of a product
+func (p *Product) ListReservations(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := product.ListReservations(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting reservations list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// RetrieveReservation gives a single reservation
+func (p *Product) RetrieveReservation(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "reservationID")
+
+	res, err := product.RetrieveReservation(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrReservationNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for reservation %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, res, http.StatusOK)
+}
+
+// ReleaseReservation ends a reservation before it expires.
+func (p *Product) ReleaseReservation(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "reservationID")
+
+	if err := product.ReleaseReservation(r.Context(), p.DB, eventID(r), id, time.Now()); err != nil {
+		switch err {
+		case product.ErrReservationNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrReservationClosed, product.ErrInsufficientStock:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("releasing reservation %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// MakeOffer records an offer on a product. It looks for a JSON object in the
+// request body.
+func (p *Product) MakeOffer(w http.ResponseWriter, r *http.Request) error {
+	var no product.NewOffer
+	if err := web.Decode(r, &no); err != nil {
+		return fmt.Errorf("decoding offer: %w", err)
+	}
+
+	id := chi.URLParam(r, "id")
+
+	o, err := product.MakeOffer(r.Context(), p.DB, eventID(r), id, no, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound, product.ErrCustomerNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrVariantRequired:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("making offer on product %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, o, http.StatusCreated)
+}
+
+// ListOffers gets the offers made on a product
+func (p *Product) ListOffers(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := product.ListOffers(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting offers list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// RetrieveOffer gives a single offer
+func (p *Product) RetrieveOffer(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "offerID")
+
+	o, err := product.RetrieveOffer(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrOfferNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for offer %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, o, http.StatusOK)
+}
+
+// RespondToOffer accepts, rejects or counters an offer. It looks for a JSON
+// object in the request body.
+func (p *Product) RespondToOffer(w http.ResponseWriter, r *http.Request) error {
+	var resp product.OfferResponse
+	if err := web.Decode(r, &resp); err != nil {
+		return fmt.Errorf("decoding offer response: %w", err)
+	}
+
+	id := chi.URLParam(r, "offerID")
+
+	o, err := product.RespondToOffer(r.Context(), p.DB, eventID(r), id, resp, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrOfferNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrCounterAmount, product.ErrInvalidExpiry:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrOfferClosed:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("responding to offer %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, o, http.StatusOK)
+}
+
+// CreateAuction puts a product up for auction. It looks for a JSON object in
+// the request body.
+func (p *Product) CreateAuction(w http.ResponseWriter, r *http.Request) error {
+	var na product.NewAuction
+	if err := web.Decode(r, &na); err != nil {
+		return fmt.Errorf("decoding auction: %w", err)
+	}
+
+	id := chi.URLParam(r, "id")
+
+	a, err := product.CreateAuction(r.Context(), p.DB, eventID(r), id, na, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrNotFound, product.ErrVariantNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID, product.ErrVariantRequired, product.ErrAuctionTimes:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrInsufficientStock:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("auctioning product %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, a, http.StatusCreated)
+}
+
+// ListAuctions gets the auctions of a product
+func (p *Product) ListAuctions(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := product.ListAuctions(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting auctions list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// RetrieveAuction gives a single auction
+func (p *Product) RetrieveAuction(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "auctionID")
+
+	a, err := product.RetrieveAuction(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrAuctionNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for auction %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, a, http.StatusOK)
+}
+
+// PlaceBid bids in an auction. It looks for a JSON object in the request
+// body.
+func (p *Product) PlaceBid(w http.ResponseWriter, r *http.Request) error {
+	var nb product.NewBid
+	if err := web.Decode(r, &nb); err != nil {
+		return fmt.Errorf("decoding bid: %w", err)
+	}
+
+	id := chi.URLParam(r, "auctionID")
+
+	b, err := product.PlaceBid(r.Context(), p.DB, eventID(r), id, nb, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrAuctionNotFound, product.ErrCustomerNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case product.ErrAuctionClosed, product.ErrBidTooLow:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("bidding in auction %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, b, http.StatusCreated)
+}
+
+// ListBids gets the bids of an auction, the highest first.
+func (p *Product) ListBids(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "auctionID")
+
+	list, err := product.ListBids(r.Context(), p.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrAuctionNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting bids list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
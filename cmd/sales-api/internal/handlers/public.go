@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/jmoiron/sqlx"
+)
+
+// Public defines the read-only handlers of the public storefront. They only
+// ever show products as product.PublicProduct.
+type Public struct {
+	DB     *sqlx.DB
+	Log    *log.Logger
+	Images *Images
+}
+
+// List gets the products on show in the storefront
+func (p *Public) List(w http.ResponseWriter, r *http.Request) error {
+	list, err := product.ListPublic(r.Context(), p.DB, eventID(r), time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting public products list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Retrieve gives a single product on show in the storefront
+func (p *Public) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	pp, err := product.RetrievePublic(r.Context(), p.DB, eventID(r), id, time.Now())
+	if err != nil {
+		switch err {
+		case product.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for public product %q: %w", id, err)
+		}
+	}
+
+	return web.Respond(w, pp, http.StatusOK)
+}
+
+// Download sends an image of a product on show in the storefront.
+func (p *Public) Download(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	if _, err := product.RetrievePublic(r.Context(), p.DB, eventID(r), id, time.Now()); err != nil {
+		switch err {
+		case product.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for public product %q: %w", id, err)
+		}
+	}
+
+	return p.Images.Download(w, r)
+}
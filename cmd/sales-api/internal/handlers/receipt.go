@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/receipt"
+	"github.com/jmoiron/sqlx"
+)
+
+// Receipt defines the handlers for sales receipts.
+type Receipt struct {
+	DB     *sqlx.DB
+	Log    *log.Logger
+	Config receipt.Config
+}
+
+// Retrieve renders the receipt of a sale. The format query parameter selects
+// text, html or pdf output and width overrides the line width of text
+// receipts.
+func (h *Receipt) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	s, err := product.RetrieveSale(r.Context(), h.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case product.ErrSaleNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case product.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for sale %q; %w", id, err)
+		}
+	}
+
+	p, err := product.Retrieve(r.Context(), h.DB, eventID(r), s.ProductID)
+	if err != nil {
+		return fmt.Errorf("looking for product of sale %q; %w", id, err)
+	}
+
+	rc := receipt.Build(h.Config, *s, *p)
+
+	width := h.Config.Width
+	if v := r.URL.Query().Get("width"); v != "" {
+		if width, err = strconv.Atoi(v); err != nil || width < receipt.MinWidth || width > receipt.MaxWidth {
+			return web.NewRequestError(fmt.Errorf("width must be a number from %d to %d", receipt.MinWidth, receipt.MaxWidth), http.StatusBadRequest)
+		}
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format := r.URL.Query().Get("format"); format {
+	case "", receipt.FormatText:
+		contentType = "text/plain; charset=utf-8"
+		err = receipt.Text(&buf, rc, width)
+	case receipt.FormatHTML:
+		contentType = "text/html; charset=utf-8"
+		err = receipt.HTML(&buf, rc)
+	case receipt.FormatPDF:
+		contentType = "application/pdf"
+		err = receipt.PDF(&buf, rc)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%s.pdf"`, s.ID))
+	default:
+		return web.NewRequestError(receipt.ErrUnknownFormat, http.StatusBadRequest)
+	}
+	if err != nil {
+		return fmt.Errorf("rendering receipt: %w", err)
+	}
+
+	return respondFile(w, contentType, buf.Bytes())
+}
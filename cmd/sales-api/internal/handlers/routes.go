@@ -3,28 +3,247 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/ivan-sabo/garagesale/internal/middleware"
+	"github.com/ivan-sabo/garagesale/internal/notify"
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
 	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/receipt"
+	"github.com/ivan-sabo/garagesale/internal/stream"
 	"github.com/jmoiron/sqlx"
 )
 
-func API(l *log.Logger, db *sqlx.DB) http.Handler {
-	app := web.NewApp(l, middleware.Errors(l), middleware.Metrics())
+// Config holds the settings of the API that are not tied to a single
+// dependency.
+type Config struct {
+	// IdempotencyTTL is how long idempotency keys are remembered.
+	IdempotencyTTL time.Duration
+
+	// Events feeds the /v1/stream of live inventory changes. The stream is
+	// not registered when it is nil.
+	Events *stream.Broker
+
+	// Storage holds product images. The image routes are not registered when
+	// it is nil.
+	Storage storage.Storage
+
+	// MaxImageSize is the largest image upload accepted, in bytes.
+	MaxImageSize int64
+
+	// Receipt holds the shop details printed on every receipt.
+	Receipt receipt.Config
+
+	// PublicMaxAge is how long responses of the public storefront may be
+	// cached.
+	PublicMaxAge time.Duration
+
+	// Notify sends receipts to customers and low stock alerts to
+	// StaffEmails. No emails are sent when it is nil.
+	Notify      *notify.Queue
+	StaffEmails []string
+}
+
+// API constructs an http.Handler with all application routes defined.
+func API(l *log.Logger, db *sqlx.DB, cfg Config) http.Handler {
+	app := web.NewApp(l, middleware.RequestID(), middleware.Audit(), middleware.Errors(l), middleware.Metrics())
+
+	if cfg.IdempotencyTTL == 0 {
+		cfg.IdempotencyTTL = 24 * time.Hour
+	}
+	idem := middleware.Idempotency(db, l, cfg.IdempotencyTTL)
 
 	c := Check{db: db}
 	app.Handle(http.MethodGet, "/v1/health", c.Health)
 
-	p := Product{DB: db, Log: l}
+	o := OpenAPI{Doc: openAPIDoc()}
+	app.Handle(http.MethodGet, "/v1/openapi.json", o.Spec)
+
+	if cfg.PublicMaxAge == 0 {
+		cfg.PublicMaxAge = 5 * time.Minute
+	}
+
+	if cfg.Receipt.Width == 0 {
+		cfg.Receipt.Width = 42
+	}
+
+	p := Product{
+		DB:          db,
+		Log:         l,
+		Storage:     cfg.Storage,
+		Notify:      cfg.Notify,
+		StaffEmails: cfg.StaffEmails,
+		Receipt:     cfg.Receipt,
+	}
+
+	au := Audit{DB: db, Log: l}
+
+	app.Handle(http.MethodGet, "/v1/audit", au.List)
+
+	lb := Label{DB: db, Log: l}
+	sl := Seller{DB: db, Log: l}
+	rc := Receipt{DB: db, Log: l, Config: cfg.Receipt}
+	pr := Pricing{DB: db, Log: l}
+	t := Tax{DB: db, Log: l}
+
+	var es *Events
+	if cfg.Events != nil {
+		es = &Events{Broker: cfg.Events}
+	}
+
+	var i *Images
+	if cfg.Storage != nil {
+		if cfg.MaxImageSize == 0 {
+			cfg.MaxImageSize = 10 << 20
+		}
+		i = &Images{DB: db, Log: l, Storage: cfg.Storage, MaxSize: cfg.MaxImageSize}
+	}
+
+	// Products, and everything hanging off them, belong to an event. The
+	// routes are served under /v1/events/{eventID} and, for the default
+	// event, directly under /v1.
+	for _, prefix := range []string{"/v1", "/v1/events/{eventID}"} {
+		app.Handle(http.MethodGet, prefix+"/products", p.List)
+		app.Handle(http.MethodPost, prefix+"/products", p.Create, idem)
+		app.Handle(http.MethodGet, prefix+"/products/{id}", p.Retrieve)
+		app.Handle(http.MethodPut, prefix+"/products/{id}", p.Update)
+		app.Handle(http.MethodDelete, prefix+"/products/{id}", p.Delete)
+		app.Handle(http.MethodGet, prefix+"/products/{id}/history", p.History)
+		app.Handle(http.MethodPost, prefix+"/products/{id}/revert", p.Revert)
+
+		app.Handle(http.MethodPost, prefix+"/products/{id}/sales", p.AddSale, idem)
+		app.Handle(http.MethodGet, prefix+"/products/{id}/sales", p.ListSales)
+		app.Handle(http.MethodGet, prefix+"/reports/daily", p.DailyTotals)
+
+		app.Handle(http.MethodPost, prefix+"/products/{id}/adjustments", p.Adjust, idem)
+		app.Handle(http.MethodGet, prefix+"/products/{id}/movements", p.ListMovements)
+
+		app.Handle(http.MethodPost, prefix+"/products/{id}/reservations", p.Reserve, idem)
+		app.Handle(http.MethodGet, prefix+"/products/{id}/reservations", p.ListReservations)
+		app.Handle(http.MethodGet, prefix+"/reservations/{reservationID}", p.RetrieveReservation)
+		app.Handle(http.MethodDelete, prefix+"/reservations/{reservationID}", p.ReleaseReservation)
+		app.Handle(http.MethodPost, prefix+"/products/{id}/offers", p.MakeOffer, idem)
+		app.Handle(http.MethodGet, prefix+"/products/{id}/offers", p.ListOffers)
+		app.Handle(http.MethodGet, prefix+"/offers/{offerID}", p.RetrieveOffer)
+		app.Handle(http.MethodPost, prefix+"/offers/{offerID}/response", p.RespondToOffer)
+		app.Handle(http.MethodPost, prefix+"/products/{id}/auctions", p.CreateAuction, idem)
+		app.Handle(http.MethodGet, prefix+"/products/{id}/auctions", p.ListAuctions)
+		app.Handle(http.MethodGet, prefix+"/auctions/{auctionID}", p.RetrieveAuction)
+		app.Handle(http.MethodPost, prefix+"/auctions/{auctionID}/bids", p.PlaceBid, idem)
+		app.Handle(http.MethodGet, prefix+"/auctions/{auctionID}/bids", p.ListBids)
+
+		app.Handle(http.MethodGet, prefix+"/products/{id}/variants", p.ListVariants)
+		app.Handle(http.MethodPost, prefix+"/products/{id}/variants", p.AddVariant)
+		app.Handle(http.MethodPut, prefix+"/products/{id}/variants/{variantID}", p.UpdateVariant)
+		app.Handle(http.MethodDelete, prefix+"/products/{id}/variants/{variantID}", p.DeleteVariant)
+
+		app.Handle(http.MethodGet, prefix+"/products/{id}/label", lb.Label)
+		app.Handle(http.MethodPost, prefix+"/labels", lb.Sheet)
+		app.Handle(http.MethodGet, prefix+"/lookup", lb.Lookup)
+
+		app.Handle(http.MethodGet, prefix+"/sales/{id}/receipt", rc.Retrieve)
+
+		app.Handle(http.MethodGet, prefix+"/coupons", pr.ListCoupons)
+		app.Handle(http.MethodPost, prefix+"/coupons", pr.CreateCoupon)
+
+		app.Handle(http.MethodGet, prefix+"/markdowns", pr.ListMarkdowns)
+		app.Handle(http.MethodPost, prefix+"/markdowns", pr.CreateMarkdown)
+		app.Handle(http.MethodDelete, prefix+"/markdowns/{id}", pr.DeleteMarkdown)
+
+		app.Handle(http.MethodGet, prefix+"/tax/report", t.Report)
+
+		app.Handle(http.MethodGet, prefix+"/sellers", sl.List)
+		app.Handle(http.MethodPost, prefix+"/sellers", sl.Create)
+		app.Handle(http.MethodGet, prefix+"/sellers/{sellerID}", sl.Retrieve)
+		app.Handle(http.MethodPut, prefix+"/sellers/{sellerID}", sl.Update)
+		app.Handle(http.MethodDelete, prefix+"/sellers/{sellerID}", sl.Delete)
+		app.Handle(http.MethodGet, prefix+"/settlements", sl.Settlements)
+
+		// Live changes of an event are streamed from a path of their own as
+		// GET /v1/events lists the events themselves.
+		if es != nil {
+			app.Handle(http.MethodGet, prefix+"/stream", es.Stream)
+		}
+
+		if i != nil {
+			app.Handle(http.MethodGet, prefix+"/products/{id}/images", i.List)
+			app.Handle(http.MethodPost, prefix+"/products/{id}/images", i.Upload)
+			app.Handle(http.MethodPut, prefix+"/products/{id}/images/{imageID}", i.Update)
+			app.Handle(http.MethodDelete, prefix+"/products/{id}/images/{imageID}", i.Delete)
+			app.Handle(http.MethodGet, prefix+"/products/{id}/images/{imageID}/{size}", i.Download)
+		}
+	}
+
+	// The public storefront is a separate, read-only route group. It never
+	// reaches the handlers above so cost, sales and the admin routes stay
+	// private.
+	pub := Public{DB: db, Log: l, Images: i}
+	cache := middleware.Cache(cfg.PublicMaxAge)
+
+	for _, prefix := range []string{"/public/v1", "/public/v1/events/{eventID}"} {
+		app.Handle(http.MethodGet, prefix+"/products", pub.List, cache)
+		app.Handle(http.MethodGet, prefix+"/products/{id}", pub.Retrieve, cache)
+
+		if i != nil {
+			app.Handle(http.MethodGet, prefix+"/products/{id}/images/{imageID}/{size}", pub.Download)
+		}
+	}
+
+	ev := Event{DB: db, Log: l}
+
+	app.Handle(http.MethodGet, "/v1/events", ev.List)
+	app.Handle(http.MethodPost, "/v1/events", ev.Create)
+	app.Handle(http.MethodGet, "/v1/events/nearby", ev.Nearby)
+	app.Handle(http.MethodGet, "/v1/events/{eventID}", ev.Retrieve)
+	app.Handle(http.MethodPut, "/v1/events/{eventID}", ev.Update)
+	app.Handle(http.MethodDelete, "/v1/events/{eventID}", ev.Delete)
+
+	// Customers are left out of the audit log so anonymizing one leaves no
+	// personal details behind.
+	cu := Customer{DB: db, Log: l}
+
+	app.Handle(http.MethodGet, "/v1/customers", cu.List)
+	app.Handle(http.MethodPost, "/v1/customers", cu.Create)
+	app.Handle(http.MethodGet, "/v1/customers/{id}", cu.Retrieve)
+	app.Handle(http.MethodPut, "/v1/customers/{id}", cu.Update)
+	app.Handle(http.MethodGet, "/v1/customers/{id}/purchases", cu.Purchases)
+	app.Handle(http.MethodPost, "/v1/customers/{id}/merge", cu.Merge)
+	app.Handle(http.MethodPost, "/v1/customers/{id}/anonymize", cu.Anonymize)
+
+	// Tax rates are set by jurisdiction and apply to every event.
+	app.Handle(http.MethodGet, "/v1/tax/rates", t.ListRates)
+	app.Handle(http.MethodPut, "/v1/tax/rates", t.SetRate)
+	app.Handle(http.MethodDelete, "/v1/tax/rates/{id}", t.DeleteRate)
+
+	wh := Webhook{DB: db, Log: l}
+
+	app.Handle(http.MethodGet, "/v1/webhooks", wh.List)
+	app.Handle(http.MethodPost, "/v1/webhooks", wh.Create)
+	app.Handle(http.MethodGet, "/v1/webhooks/{id}", wh.Retrieve)
+	app.Handle(http.MethodPut, "/v1/webhooks/{id}", wh.Update)
+	app.Handle(http.MethodDelete, "/v1/webhooks/{id}", wh.Delete)
+	app.Handle(http.MethodGet, "/v1/webhooks/{id}/deliveries", wh.ListDeliveries)
+	app.Handle(http.MethodGet, "/v1/webhooks/{id}/deliveries/{deliveryID}/attempts", wh.ListAttempts)
+
+	// The admin UI is served as HTML pages for volunteers using a browser.
+	// Its forms are posted with the cookies of the browser so every route
+	// checks the CSRF token.
+	ad := Admin{DB: db, Log: l}
+	csrf := middleware.CSRF("/admin")
 
-	app.Handle(http.MethodGet, "/v1/products", p.List)
-	app.Handle(http.MethodPost, "/v1/products", p.Create)
-	app.Handle(http.MethodGet, "/v1/products/{id}", p.Retrieve)
-	app.Handle(http.MethodPut, "/v1/products/{id}", p.Update)
-	app.Handle(http.MethodDelete, "/v1/products/{id}", p.Delete)
+	app.Handle(http.MethodGet, "/admin", ad.Index, csrf)
+	app.Handle(http.MethodGet, "/admin/switch", ad.Switch, csrf)
+	app.Handle(http.MethodGet, "/admin/static/*", ad.Static)
 
-	app.Handle(http.MethodPost, "/v1/products/{id}/sales", p.AddSale)
-	app.Handle(http.MethodGet, "/v1/products/{id}/sales", p.ListSales)
+	for _, prefix := range []string{"/admin", "/admin/events/{eventID}"} {
+		app.Handle(http.MethodGet, prefix+"/products", ad.Products, csrf)
+		app.Handle(http.MethodGet, prefix+"/products/new", ad.NewProduct, csrf)
+		app.Handle(http.MethodPost, prefix+"/products", ad.CreateProduct, csrf)
+		app.Handle(http.MethodGet, prefix+"/products/{id}", ad.EditProduct, csrf)
+		app.Handle(http.MethodPost, prefix+"/products/{id}", ad.UpdateProduct, csrf)
+		app.Handle(http.MethodPost, prefix+"/products/{id}/sales", ad.RecordSale, csrf)
+		app.Handle(http.MethodGet, prefix+"/totals", ad.Totals, csrf)
+	}
 
 	return app
 }
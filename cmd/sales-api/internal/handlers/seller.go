@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/seller"
+	"github.com/jmoiron/sqlx"
+)
+
+// Seller defines the handlers for consignment sellers and their settlements.
+type Seller struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// List gets all sellers of an event
+func (s *Seller) List(w http.ResponseWriter, r *http.Request) error {
+	list, err := seller.List(r.Context(), s.DB, eventID(r))
+	if err != nil {
+		switch err {
+		case seller.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting sellers list: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Retrieve gives a single seller
+func (s *Seller) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "sellerID")
+
+	sl, err := seller.Retrieve(r.Context(), s.DB, eventID(r), id)
+	if err != nil {
+		switch err {
+		case seller.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case seller.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for seller %q; %w", id, err)
+		}
+	}
+
+	return web.Respond(w, sl, http.StatusOK)
+}
+
+// Create decodes a JSON document from a POST request and adds a new seller to
+// the event
+func (s *Seller) Create(w http.ResponseWriter, r *http.Request) error {
+	var ns seller.NewSeller
+	if err := web.Decode(r, &ns); err != nil {
+		return fmt.Errorf("decoding new seller: %w", err)
+	}
+
+	sl, err := seller.Create(r.Context(), s.DB, eventID(r), ns, time.Now())
+	if err != nil {
+		switch err {
+		case seller.ErrEventNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case seller.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("creating seller: %w", err)
+		}
+	}
+
+	return web.Respond(w, sl, http.StatusCreated)
+}
+
+// Update decodes the body of a request to update an existing seller
+func (s *Seller) Update(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "sellerID")
+
+	var update seller.UpdateSeller
+	if err := web.Decode(r, &update); err != nil {
+		return fmt.Errorf("decoding seller update: %w", err)
+	}
+
+	if err := seller.Update(r.Context(), s.DB, eventID(r), id, update, time.Now()); err != nil {
+		switch err {
+		case seller.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case seller.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("updating seller (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Delete removes a seller that has no products or payouts.
+func (s *Seller) Delete(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "sellerID")
+
+	if err := seller.Delete(r.Context(), s.DB, eventID(r), id, time.Now()); err != nil {
+		switch err {
+		case seller.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		case seller.ErrInUse:
+			return web.NewRequestError(err, http.StatusConflict)
+		default:
+			return fmt.Errorf("deleting seller (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Settlements reports what every seller of the event is owed.
+func (s *Seller) Settlements(w http.ResponseWriter, r *http.Request) error {
+	list, err := seller.Settlements(r.Context(), s.DB, eventID(r))
+	if err != nil {
+		switch err {
+		case seller.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting settlements: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
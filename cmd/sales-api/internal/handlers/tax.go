@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/tax"
+	"github.com/jmoiron/sqlx"
+)
+
+// Tax defines the handlers for tax rates and tax reporting.
+type Tax struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// ListRates gets all configured tax rates
+func (t *Tax) ListRates(w http.ResponseWriter, r *http.Request) error {
+	list, err := tax.ListRates(r.Context(), t.DB)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// SetRate decodes a JSON document and creates or replaces the tax rate for a
+// jurisdiction and category.
+func (t *Tax) SetRate(w http.ResponseWriter, r *http.Request) error {
+	var nr tax.NewRate
+	if err := web.Decode(r, &nr); err != nil {
+		return err
+	}
+
+	rate, err := tax.SetRate(r.Context(), t.DB, nr, time.Now())
+	if err != nil {
+		return fmt.Errorf("setting tax rate: %w", err)
+	}
+
+	return web.Respond(w, rate, http.StatusOK)
+}
+
+// DeleteRate removes a single tax rate identified by an ID in the request URL.
+func (t *Tax) DeleteRate(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	if err := tax.DeleteRate(r.Context(), t.DB, id); err != nil {
+		switch err {
+		case tax.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("deleting tax rate (id: %s): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Report summarizes the tax collected on sales of the event between the from
+// and to query parameters. Both accept either a date (2006-01-02) or an RFC 3339 timestamp.
+func (t *Tax) Report(w http.ResponseWriter, r *http.Request) error {
+	from, err := parseTime(r.URL.Query().Get("from"))
+	if err != nil {
+		return web.NewRequestError(fmt.Errorf("invalid from: %w", err), http.StatusBadRequest)
+	}
+	to, err := parseTime(r.URL.Query().Get("to"))
+	if err != nil {
+		return web.NewRequestError(fmt.Errorf("invalid to: %w", err), http.StatusBadRequest)
+	}
+
+	report, err := tax.Summarize(r.Context(), t.DB, eventID(r), from, to)
+	if err != nil {
+		switch err {
+		case tax.ErrInvalidID, tax.ErrInvalidRange:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("building tax report: %w", err)
+		}
+	}
+
+	return web.Respond(w, report, http.StatusOK)
+}
+
+// parseTime reads a query parameter holding either a date or a timestamp.
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, errors.New("value is required")
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
+	"github.com/jmoiron/sqlx"
+)
+
+// Webhook defines the handlers for managing webhook subscriptions.
+type Webhook struct {
+	DB  *sqlx.DB
+	Log *log.Logger
+}
+
+// List gets all webhook subscriptions
+func (h *Webhook) List(w http.ResponseWriter, r *http.Request) error {
+	list, err := webhook.List(r.Context(), h.DB)
+	if err != nil {
+		return err
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// Retrieve gives a single subscription
+func (h *Webhook) Retrieve(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	s, err := webhook.Retrieve(r.Context(), h.DB, id)
+	if err != nil {
+		switch err {
+		case webhook.ErrNotFound:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case webhook.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("looking for subscription %q; %w", id, err)
+		}
+	}
+
+	return web.Respond(w, s, http.StatusOK)
+}
+
+// Create decodes a JSON document from a POST request and subscribes a new
+// endpoint to events
+func (h *Webhook) Create(w http.ResponseWriter, r *http.Request) error {
+	var ns webhook.NewSubscription
+	if err := web.Decode(r, &ns); err != nil {
+		return err
+	}
+
+	s, err := webhook.Create(r.Context(), h.DB, ns, time.Now())
+	if err != nil {
+		if errors.Is(err, webhook.ErrUnknownEvent) {
+			return web.NewRequestError(err, http.StatusBadRequest)
+		}
+		return fmt.Errorf("creating subscription: %w", err)
+	}
+
+	return web.Respond(w, webhook.CreatedSubscription{Subscription: *s, Secret: s.Secret}, http.StatusCreated)
+}
+
+// Update decodes the body of a request to update an existing subscription.
+func (h *Webhook) Update(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	var update webhook.UpdateSubscription
+	if err := web.Decode(r, &update); err != nil {
+		return fmt.Errorf("decoding subscription update: %w", err)
+	}
+
+	if err := webhook.Update(r.Context(), h.DB, id, update, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrNotFound):
+			return web.NewRequestError(err, http.StatusNotFound)
+		case errors.Is(err, webhook.ErrInvalidID), errors.Is(err, webhook.ErrUnknownEvent):
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("updating subscription (id: %q): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// Delete removes a single subscription identified by an ID in the request URL.
+func (h *Webhook) Delete(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	if err := webhook.Delete(r.Context(), h.DB, id); err != nil {
+		switch err {
+		case webhook.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("deleting subscription (id: %s): %w", id, err)
+		}
+	}
+
+	return web.Respond(w, nil, http.StatusNoContent)
+}
+
+// ListDeliveries gets the most recent deliveries of a subscription
+func (h *Webhook) ListDeliveries(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+
+	list, err := webhook.ListDeliveries(r.Context(), h.DB, id)
+	if err != nil {
+		switch err {
+		case webhook.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting deliveries: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
+
+// ListAttempts gets every attempt made at sending a delivery of the
+// subscription in the request URL
+func (h *Webhook) ListAttempts(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "id")
+	deliveryID := chi.URLParam(r, "deliveryID")
+
+	list, err := webhook.ListAttempts(r.Context(), h.DB, id, deliveryID)
+	if err != nil {
+		switch err {
+		case webhook.ErrNoDelivery:
+			return web.NewRequestError(err, http.StatusNotFound)
+		case webhook.ErrInvalidID:
+			return web.NewRequestError(err, http.StatusBadRequest)
+		default:
+			return fmt.Errorf("getting attempts: %w", err)
+		}
+	}
+
+	return web.Respond(w, list, http.StatusOK)
+}
@@ -14,7 +14,13 @@ import (
 
 	"github.com/caarlos0/env/v6"
 	"github.com/ivan-sabo/garagesale/cmd/sales-api/internal/handlers"
+	"github.com/ivan-sabo/garagesale/internal/notify"
 	"github.com/ivan-sabo/garagesale/internal/platform/database"
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/receipt"
+	"github.com/ivan-sabo/garagesale/internal/stream"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
 )
 
 func main() {
@@ -34,6 +40,8 @@ func run() error {
 			ReadTimeout     time.Duration `env:"READ_TIMEOUT" envDefault:"5s"`
 			WriteTimeout    time.Duration `env:"WRITE_TIMEOUT" envDefault:"5s"`
 			ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"5s"`
+			IdempotencyTTL  time.Duration `env:"IDEMPOTENCY_TTL" envDefault:"24h"`
+			PublicMaxAge    time.Duration `env:"PUBLIC_MAX_AGE" envDefault:"5m"`
 		}
 		DB struct {
 			User       string `env:"USER" envDefault:"postgres"`
@@ -42,6 +50,47 @@ func run() error {
 			Name       string `env:"NAME" envDefault:"postgres"`
 			DisableTLS bool   `env:"DISABLE_TLS" envDefault:"true"`
 		}
+		Webhook struct {
+			Interval    time.Duration `env:"WEBHOOK_INTERVAL" envDefault:"1s"`
+			Timeout     time.Duration `env:"WEBHOOK_TIMEOUT" envDefault:"10s"`
+			MaxAttempts int           `env:"WEBHOOK_MAX_ATTEMPTS" envDefault:"8"`
+			Backoff     time.Duration `env:"WEBHOOK_BACKOFF" envDefault:"10s"`
+			MaxBackoff  time.Duration `env:"WEBHOOK_MAX_BACKOFF" envDefault:"1h"`
+			Retention   time.Duration `env:"WEBHOOK_RETENTION" envDefault:"720h"`
+		}
+		Storage struct {
+			Backend      string `env:"STORAGE_BACKEND" envDefault:"local"`
+			Dir          string `env:"STORAGE_DIR" envDefault:"./data"`
+			Endpoint     string `env:"STORAGE_ENDPOINT"`
+			Region       string `env:"STORAGE_REGION" envDefault:"us-east-1"`
+			Bucket       string `env:"STORAGE_BUCKET"`
+			AccessKey    string `env:"STORAGE_ACCESS_KEY"`
+			SecretKey    string `env:"STORAGE_SECRET_KEY"`
+			MaxImageSize int64  `env:"STORAGE_MAX_IMAGE_SIZE" envDefault:"10485760"`
+		}
+		Receipt struct {
+			Header string `env:"RECEIPT_HEADER" envDefault:"Garage Sale"`
+			Footer string `env:"RECEIPT_FOOTER" envDefault:"Thank you!"`
+			Width  int    `env:"RECEIPT_WIDTH" envDefault:"42"`
+		}
+		SMTP struct {
+			Addr        string   `env:"SMTP_ADDR"`
+			From        string   `env:"SMTP_FROM" envDefault:"garagesale@localhost"`
+			Username    string   `env:"SMTP_USERNAME"`
+			Password    string   `env:"SMTP_PASSWORD"`
+			StaffEmails []string `env:"SMTP_STAFF_EMAILS" envSeparator:","`
+			QueueSize   int      `env:"SMTP_QUEUE_SIZE" envDefault:"100"`
+		}
+		Events struct {
+			Interval time.Duration `env:"EVENTS_INTERVAL" envDefault:"500ms"`
+			Replay   int           `env:"EVENTS_REPLAY" envDefault:"1000"`
+		}
+		Reservations struct {
+			Interval time.Duration `env:"RESERVATIONS_INTERVAL" envDefault:"30s"`
+		}
+		Auctions struct {
+			Interval time.Duration `env:"AUCTIONS_INTERVAL" envDefault:"10s"`
+		}
 	}
 
 	log.Printf("Main : started")
@@ -65,6 +114,23 @@ func run() error {
 	}
 	defer db.Close()
 
+	var st storage.Storage
+	switch cfg.Storage.Backend {
+	case "local":
+		st = &storage.Local{Dir: cfg.Storage.Dir}
+	case "s3":
+		st = &storage.S3{
+			Endpoint:  cfg.Storage.Endpoint,
+			Region:    cfg.Storage.Region,
+			Bucket:    cfg.Storage.Bucket,
+			AccessKey: cfg.Storage.AccessKey,
+			SecretKey: cfg.Storage.SecretKey,
+			Client:    &http.Client{Timeout: 30 * time.Second},
+		}
+	default:
+		return fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+
 	// Start Debug service
 	go func() {
 		log.Printf("main : Debug service listening on %s", cfg.Web.Debug)
@@ -72,10 +138,68 @@ func run() error {
 		log.Printf("main: Debug service ended %v", err)
 	}()
 
+	// Start background workers
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	dispatcher := webhook.Dispatcher{
+		DB:          db,
+		Log:         log,
+		Client:      &http.Client{Timeout: cfg.Webhook.Timeout},
+		Interval:    cfg.Webhook.Interval,
+		MaxAttempts: cfg.Webhook.MaxAttempts,
+		Backoff:     cfg.Webhook.Backoff,
+		MaxBackoff:  cfg.Webhook.MaxBackoff,
+		Retention:   cfg.Webhook.Retention,
+	}
+	go dispatcher.Run(workerCtx)
+
+	broker := stream.NewBroker(db, log, cfg.Events.Interval, cfg.Events.Replay)
+	go broker.Run(workerCtx)
+
+	expirer := product.Expirer{
+		DB:       db,
+		Log:      log,
+		Interval: cfg.Reservations.Interval,
+	}
+	go expirer.Run(workerCtx)
+
+	auctioneer := product.Auctioneer{
+		DB:       db,
+		Log:      log,
+		Interval: cfg.Auctions.Interval,
+	}
+	go auctioneer.Run(workerCtx)
+
+	// Emails are only sent when an SMTP server is configured.
+	var notifier *notify.Queue
+	if cfg.SMTP.Addr != "" {
+		notifier = notify.NewQueue(&notify.SMTP{
+			Addr:     cfg.SMTP.Addr,
+			From:     cfg.SMTP.From,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+		}, log, cfg.SMTP.QueueSize)
+		go notifier.Run(workerCtx)
+	}
+
 	// Start API service
 	api := http.Server{
-		Addr:         cfg.Web.Address,
-		Handler:      handlers.API(log, db),
+		Addr: cfg.Web.Address,
+		Handler: handlers.API(log, db, handlers.Config{
+			IdempotencyTTL: cfg.Web.IdempotencyTTL,
+			Events:         broker,
+			Storage:        st,
+			MaxImageSize:   cfg.Storage.MaxImageSize,
+			Receipt: receipt.Config{
+				Header: cfg.Receipt.Header,
+				Footer: cfg.Receipt.Footer,
+				Width:  cfg.Receipt.Width,
+			},
+			Notify:       notifier,
+			StaffEmails:  cfg.SMTP.StaffEmails,
+			PublicMaxAge: cfg.Web.PublicMaxAge,
+		}),
 		ReadTimeout:  cfg.Web.ReadTimeout,
 		WriteTimeout: cfg.Web.WriteTimeout,
 	}
@@ -97,6 +221,10 @@ func run() error {
 	case <-shutdown:
 		log.Println("main : Start shutdown")
 
+		// Stop background workers first so open event streams end and do not
+		// hold up the server shutdown.
+		stopWorkers()
+
 		const timeout = 5 * time.Second
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ivan-sabo/garagesale/client"
+	"github.com/ivan-sabo/garagesale/cmd/sales-api/internal/handlers"
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/schema"
+)
+
+// TestClient runs the client package against the real API.
+func TestClient(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	if err := schema.Seed(db); err != nil {
+		t.Fatal(err)
+	}
+
+	log := log.New(os.Stderr, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+
+	srv := httptest.NewServer(handlers.API(log, db, handlers.Config{}))
+	defer srv.Close()
+
+	c := client.New(srv.URL)
+	c.Actor = "client-test"
+	ctx := context.Background()
+
+	h, err := c.Health(ctx)
+	if err != nil || h.Status != "OK" {
+		t.Fatalf("checking health: %v, %+v", err, h)
+	}
+
+	list, err := c.ListProducts(ctx)
+	if err != nil {
+		t.Fatalf("listing: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected the 2 seeded products, got %d", len(list))
+	}
+
+	_, err = c.CreateProduct(ctx, client.NewProduct{Cost: 5, Quantity: 1})
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, client.ErrBadRequest) {
+		t.Fatalf("creating without a name: expected a bad request, got %v", err)
+	}
+	if _, ok := apiErr.Field("name"); !ok {
+		t.Fatalf("expected a field error for name, got %v", apiErr.Fields)
+	}
+
+	p, err := c.CreateProduct(ctx, client.NewProduct{Name: "Lamp", Cost: 12, Quantity: 3})
+	if err != nil {
+		t.Fatalf("creating: %v", err)
+	}
+
+	name := "Desk lamp"
+	if err := c.UpdateProduct(ctx, p.ID, client.UpdateProduct{Name: &name}); err != nil {
+		t.Fatalf("updating: %v", err)
+	}
+
+	s, err := c.AddSale(ctx, p.ID, client.NewSale{Quantity: 2})
+	if err != nil {
+		t.Fatalf("selling: %v", err)
+	}
+	if s.Quantity != 2 || s.Paid != 24 {
+		t.Fatalf("expected 2 sold for 24, got %+v", s)
+	}
+
+	got, err := c.RetrieveProduct(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("retrieving: %v", err)
+	}
+	if got.Name != name || got.Sold != 2 || got.Available != 1 {
+		t.Fatalf("expected %q with 2 sold and 1 left, got %+v", name, got)
+	}
+
+	sales, err := c.ListSales(ctx, p.ID)
+	if err != nil || len(sales) != 1 || sales[0].ID != s.ID {
+		t.Fatalf("listing sales: expected the sale, got %v, %v", sales, err)
+	}
+
+	totals, err := c.DailyTotals(ctx)
+	if err != nil || len(totals) == 0 {
+		t.Fatalf("getting daily totals: %v, %v", totals, err)
+	}
+	if totals[0].Units != 2 || totals[0].Gross != 24 {
+		t.Fatalf("expected today's totals first with the sale, got %+v", totals[0])
+	}
+
+	versions, err := c.ProductHistory(ctx, p.ID)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("getting history: expected 2 versions, got %v, %v", versions, err)
+	}
+
+	if err := c.DeleteProduct(ctx, p.ID); err != nil {
+		t.Fatalf("deleting: %v", err)
+	}
+	if _, err := c.RetrieveProduct(ctx, p.ID); !errors.Is(err, client.ErrNotFound) {
+		t.Fatalf("retrieving deleted: expected not found, got %v", err)
+	}
+}
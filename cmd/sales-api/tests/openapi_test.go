@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/cmd/sales-api/internal/handlers"
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/ivan-sabo/garagesale/internal/stream"
+)
+
+// TestOpenAPI checks every route of the API is described in the OpenAPI
+// document it serves. The HTML pages of the admin UI are left out of it.
+func TestOpenAPI(t *testing.T) {
+	log := log.New(os.Stderr, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+
+	// Storage and Events are set so the image and stream routes are
+	// registered too.
+	cfg := handlers.Config{
+		Storage: &storage.Local{Dir: t.TempDir()},
+		Events:  stream.NewBroker(nil, log, time.Second, 1),
+	}
+	app := handlers.API(log, nil, cfg)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil)
+	resp := httptest.NewRecorder()
+	app.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("getting the document: expected status code %v, got %v", http.StatusOK, resp.Code)
+	}
+
+	var doc struct {
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]struct {
+					Minimum *float64 `json:"minimum"`
+				} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decoding the document: %s", err)
+	}
+
+	err := app.(*web.App).Walk(func(method, pattern string) error {
+		if strings.HasPrefix(pattern, "/admin") {
+			return nil
+		}
+		if _, ok := doc.Paths[pattern][strings.ToLower(method)]; !ok {
+			t.Errorf("%s %s is not described", method, pattern)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking the routes: %s", err)
+	}
+
+	for _, name := range []string{"Product", "NewProduct", "UpdateProduct", "Sale", "NewSale", "ErrorResponse"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("expected a schema for %s", name)
+		}
+	}
+
+	ns := doc.Components.Schemas["NewSale"]
+	if min := ns.Properties["quantity"].Minimum; min == nil || *min != 1 {
+		t.Errorf("expected the quantity of a sale to be at least 1, got %v", min)
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path"
 	"strings"
 	"testing"
 
@@ -26,10 +28,14 @@ func TestProducts(t *testing.T) {
 
 	log := log.New(os.Stderr, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
 
-	tests := ProductTests{app: handlers.API(log, db)}
+	tests := ProductTests{app: handlers.API(log, db, handlers.Config{})}
 
 	t.Run("List", tests.List)
+	t.Run("Storefront", tests.Storefront)
+	t.Run("Admin", tests.Admin)
 	t.Run("ProductCRUD", tests.ProductCRUD)
+	t.Run("SaleIdempotency", tests.SaleIdempotency)
+	t.Run("Audit", tests.Audit)
 }
 
 type ProductTests struct {
@@ -53,20 +59,40 @@ func (p *ProductTests) List(t *testing.T) {
 
 	want := []map[string]interface{}{
 		{
-			"id":           "fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01",
-			"name":         "Comic Books",
-			"cost":         float64(50),
-			"quantity":     float64(42),
-			"date_created": "1999-01-08T04:05:06Z",
-			"date_updated": "1999-01-08T04:05:06Z",
+			"id":                  "fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01",
+			"event_id":            "00000000-0000-0000-0000-000000000001",
+			"name":                "Comic Books",
+			"category":            "",
+			"cost":                float64(50),
+			"quantity":            float64(42),
+			"on_hand":             float64(36),
+			"min_price":           float64(0),
+			"archived":            false,
+			"reserved":            float64(0),
+			"available":           float64(36),
+			"images":              []interface{}{},
+			"variants":            []interface{}{},
+			"low_stock_threshold": float64(0),
+			"date_created":        "1999-01-08T04:05:06Z",
+			"date_updated":        "1999-01-08T04:05:06Z",
 		},
 		{
-			"id":           "67621e3c-b845-4379-9ec8-875c8b2702c6",
-			"name":         "McDonalds Toys",
-			"cost":         float64(75),
-			"quantity":     float64(120),
-			"date_created": "2020-04-04T04:05:06Z",
-			"date_updated": "2020-04-04T04:05:06Z",
+			"id":                  "67621e3c-b845-4379-9ec8-875c8b2702c6",
+			"event_id":            "00000000-0000-0000-0000-000000000001",
+			"name":                "McDonalds Toys",
+			"category":            "",
+			"cost":                float64(75),
+			"quantity":            float64(120),
+			"on_hand":             float64(120),
+			"min_price":           float64(0),
+			"archived":            false,
+			"reserved":            float64(0),
+			"available":           float64(120),
+			"images":              []interface{}{},
+			"variants":            []interface{}{},
+			"low_stock_threshold": float64(0),
+			"date_created":        "2020-04-04T04:05:06Z",
+			"date_updated":        "2020-04-04T04:05:06Z",
 		},
 	}
 
@@ -75,6 +101,185 @@ func (p *ProductTests) List(t *testing.T) {
 	}
 }
 
+func (p *ProductTests) Storefront(t *testing.T) {
+	get := func(etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/public/v1/products", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp := httptest.NewRecorder()
+
+		p.app.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	resp := get("")
+	if resp.Code != http.StatusOK {
+		t.Fatalf("getting: expected status code %v, got %v", http.StatusOK, resp.Code)
+	}
+	if cc := resp.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public, max-age=") {
+		t.Fatalf("expected a public Cache-Control header, got %q", cc)
+	}
+
+	var list []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatalf("decoding: %s", err)
+	}
+
+	want := []map[string]interface{}{
+		{
+			"id":        "fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01",
+			"event_id":  "00000000-0000-0000-0000-000000000001",
+			"name":      "Comic Books",
+			"category":  "",
+			"price":     float64(50),
+			"available": float64(36),
+			"images":    []interface{}{},
+			"variants":  []interface{}{},
+		},
+		{
+			"id":        "67621e3c-b845-4379-9ec8-875c8b2702c6",
+			"event_id":  "00000000-0000-0000-0000-000000000001",
+			"name":      "McDonalds Toys",
+			"category":  "",
+			"price":     float64(75),
+			"available": float64(120),
+			"images":    []interface{}{},
+			"variants":  []interface{}{},
+		},
+	}
+
+	if diff := cmp.Diff(want, list); diff != "" {
+		t.Fatalf("Response did not match expected. Diff:\n%s", diff)
+	}
+
+	etag := resp.Header().Get("ETag")
+	if got := get(etag); got.Code != http.StatusNotModified {
+		t.Fatalf("revalidating: expected status code %v, got %v", http.StatusNotModified, got.Code)
+	}
+
+	req := httptest.NewRequest("PUT", "/v1/products/67621e3c-b845-4379-9ec8-875c8b2702c6", strings.NewReader(`{"archived":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	p.app.ServeHTTP(httptest.NewRecorder(), req)
+
+	resp = get(etag)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("getting after archiving: expected status code %v, got %v", http.StatusOK, resp.Code)
+	}
+	var shown []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&shown); err != nil {
+		t.Fatalf("decoding: %s", err)
+	}
+	if len(shown) != 1 || shown[0]["name"] != "Comic Books" {
+		t.Fatalf("expected the archived product hidden, got %v", shown)
+	}
+
+	for _, url := range []string{
+		"/public/v1/products/67621e3c-b845-4379-9ec8-875c8b2702c6",
+		"/public/v1/products/fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01/sales",
+	} {
+		resp := httptest.NewRecorder()
+		p.app.ServeHTTP(resp, httptest.NewRequest("GET", url, nil))
+		if resp.Code != http.StatusNotFound {
+			t.Fatalf("getting %s: expected status code %v, got %v", url, http.StatusNotFound, resp.Code)
+		}
+	}
+}
+
+func (p *ProductTests) Admin(t *testing.T) {
+	req := httptest.NewRequest("GET", "/admin/products?q=comic", nil)
+	resp := httptest.NewRecorder()
+
+	p.app.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Fatalf("getting: expected status code %v, got %v", http.StatusOK, resp.Code)
+	}
+	if ct := resp.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected an HTML page, got %q", ct)
+	}
+	if body := resp.Body.String(); !strings.Contains(body, "Comic Books") || strings.Contains(body, "McDonalds Toys") {
+		t.Fatalf("expected only the products matching the search, got %s", body)
+	}
+
+	var csrf *http.Cookie
+	for _, c := range resp.Result().Cookies() {
+		if c.Name == "csrf_token" {
+			csrf = c
+		}
+	}
+	if csrf == nil {
+		t.Fatal("expected a csrf_token cookie")
+	}
+
+	post := func(path string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(csrf)
+		resp := httptest.NewRecorder()
+
+		p.app.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	form := url.Values{"name": {"Lamp"}, "cost": {"12"}, "quantity": {"2"}, "low_stock_threshold": {"0"}, "min_price": {"0"}}
+
+	if resp := post("/admin/products", form); resp.Code != http.StatusForbidden {
+		t.Fatalf("posting without a token: expected status code %v, got %v", http.StatusForbidden, resp.Code)
+	}
+
+	form.Set("csrf_token", csrf.Value)
+	form.Set("name", "")
+	form.Set("cost", "a lot")
+
+	resp = post("/admin/products", form)
+	if resp.Code != http.StatusBadRequest {
+		t.Fatalf("posting an invalid form: expected status code %v, got %v", http.StatusBadRequest, resp.Code)
+	}
+	for _, msg := range []string{"name is a required field", "cost must be a whole number"} {
+		if !strings.Contains(resp.Body.String(), msg) {
+			t.Fatalf("expected the form to show %q, got %s", msg, resp.Body.String())
+		}
+	}
+
+	form.Set("name", "Lamp")
+	form.Set("cost", "12")
+
+	resp = post("/admin/products", form)
+	if resp.Code != http.StatusSeeOther {
+		t.Fatalf("posting: expected status code %v, got %v", http.StatusSeeOther, resp.Code)
+	}
+	page := strings.TrimSuffix(resp.Header().Get("Location"), "?notice=created")
+
+	resp = httptest.NewRecorder()
+	p.app.ServeHTTP(resp, httptest.NewRequest("GET", "/v1/audit?resource_id="+path.Base(page), nil))
+	var entries []struct {
+		Action string `json:"action"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		t.Fatalf("decoding audit log: %s", err)
+	}
+	if len(entries) != 1 || entries[0].Action != "create" {
+		t.Fatalf("expected the product created through the admin UI audited once, got %+v", entries)
+	}
+
+	resp = post(page+"/sales", url.Values{"csrf_token": {csrf.Value}, "quantity": {"1"}})
+	if resp.Code != http.StatusSeeOther {
+		t.Fatalf("recording a sale: expected status code %v, got %v: %s", http.StatusSeeOther, resp.Code, resp.Body)
+	}
+
+	resp = httptest.NewRecorder()
+	p.app.ServeHTTP(resp, httptest.NewRequest("GET", "/admin/totals", nil))
+	if resp.Code != http.StatusOK {
+		t.Fatalf("getting totals: expected status code %v, got %v", http.StatusOK, resp.Code)
+	}
+	if strings.Contains(resp.Body.String(), "Nothing has been sold") {
+		t.Fatalf("expected the sale in the daily totals, got %s", resp.Body)
+	}
+}
+
 func (p *ProductTests) ProductCRUD(t *testing.T) {
 	var created map[string]interface{}
 
@@ -106,12 +311,22 @@ func (p *ProductTests) ProductCRUD(t *testing.T) {
 		}
 
 		want := map[string]interface{}{
-			"id":           created["id"],
-			"date_created": created["date_created"],
-			"date_updated": created["date_updated"],
-			"name":         "product0",
-			"cost":         float64(55),
-			"quantity":     float64(6),
+			"id":                  created["id"],
+			"event_id":            "00000000-0000-0000-0000-000000000001",
+			"date_created":        created["date_created"],
+			"date_updated":        created["date_updated"],
+			"name":                "product0",
+			"category":            "",
+			"cost":                float64(55),
+			"quantity":            float64(6),
+			"on_hand":             float64(6),
+			"min_price":           float64(0),
+			"archived":            false,
+			"reserved":            float64(0),
+			"available":           float64(6),
+			"images":              []interface{}{},
+			"variants":            []interface{}{},
+			"low_stock_threshold": float64(0),
 		}
 
 		if diff := cmp.Diff(want, created); diff != "" {
@@ -141,3 +356,93 @@ func (p *ProductTests) ProductCRUD(t *testing.T) {
 		}
 	}
 }
+
+func (p *ProductTests) SaleIdempotency(t *testing.T) {
+	const url = "/v1/products/67621e3c-b845-4379-9ec8-875c8b2702c6/sales"
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", url, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "sale-idempotency-test")
+		resp := httptest.NewRecorder()
+
+		p.app.ServeHTTP(resp, req)
+
+		return resp
+	}
+
+	first := post(`{"quantity":2}`)
+	if http.StatusCreated != first.Code {
+		t.Fatalf("posting: expected status code %v, got %v", http.StatusCreated, first.Code)
+	}
+
+	retry := post(`{"quantity":2}`)
+	if http.StatusCreated != retry.Code {
+		t.Fatalf("retrying: expected status code %v, got %v", http.StatusCreated, retry.Code)
+	}
+	if diff := cmp.Diff(first.Body.String(), retry.Body.String()); diff != "" {
+		t.Fatalf("Retry should replay the original response. Diff:\n%s", diff)
+	}
+
+	reused := post(`{"quantity":3}`)
+	if http.StatusUnprocessableEntity != reused.Code {
+		t.Fatalf("reusing key: expected status code %v, got %v", http.StatusUnprocessableEntity, reused.Code)
+	}
+
+	req := httptest.NewRequest("GET", url, nil)
+	resp := httptest.NewRecorder()
+	p.app.ServeHTTP(resp, req)
+
+	var sales []map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&sales); err != nil {
+		t.Fatalf("decoding: %s", err)
+	}
+	if exp, got := 1, len(sales); exp != got {
+		t.Fatalf("expected %v sale recorded, got %v", exp, got)
+	}
+}
+
+func (p *ProductTests) Audit(t *testing.T) {
+	const id = "fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01"
+
+	req := httptest.NewRequest("PUT", "/v1/products/"+id, strings.NewReader(`{"cost":60}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Actor", "alice")
+	req.Header.Set("X-Request-ID", "audit-test")
+	resp := httptest.NewRecorder()
+
+	p.app.ServeHTTP(resp, req)
+
+	if http.StatusNoContent != resp.Code {
+		t.Fatalf("updating: expected status code %v, got %v", http.StatusNoContent, resp.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/audit?actor=alice&resource_id="+id, nil)
+	resp = httptest.NewRecorder()
+	p.app.ServeHTTP(resp, req)
+
+	if http.StatusOK != resp.Code {
+		t.Fatalf("listing audit log: expected status code %v, got %v", http.StatusOK, resp.Code)
+	}
+
+	var entries []struct {
+		Action       string                            `json:"action"`
+		ResourceType string                            `json:"resource_type"`
+		RequestID    string                            `json:"request_id"`
+		Diff         map[string]map[string]interface{} `json:"diff"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		t.Fatalf("decoding: %s", err)
+	}
+	if exp, got := 1, len(entries); exp != got {
+		t.Fatalf("expected %v audit entry, got %v", exp, got)
+	}
+
+	e := entries[0]
+	if e.Action != "update" || e.ResourceType != "product" || e.RequestID != "audit-test" {
+		t.Fatalf("unexpected audit entry %+v", e)
+	}
+	if diff := cmp.Diff(map[string]interface{}{"before": float64(50), "after": float64(60)}, e.Diff["cost"]); diff != "" {
+		t.Fatalf("Audit entry should record the cost change. Diff:\n%s", diff)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/client"
+)
+
+// commands runs the commands that call the API.
+type commands struct {
+	ctx context.Context
+	c   *client.Client
+	out *printer
+}
+
+var productHeader = []string{"id", "name", "category", "cost", "available", "sold", "revenue", "archived"}
+
+func productRow(p client.Product) []string {
+	return []string{
+		p.ID, p.Name, p.Category,
+		strconv.Itoa(p.Cost), strconv.Itoa(p.Available), strconv.Itoa(p.Sold), strconv.Itoa(p.Revenue),
+		strconv.FormatBool(p.Archived),
+	}
+}
+
+var saleHeader = []string{"id", "date", "quantity", "paid", "discount", "tax", "gross"}
+
+func saleRow(s client.Sale) []string {
+	return []string{
+		s.ID, s.DateCreated.Local().Format("2006-01-02 15:04"),
+		strconv.Itoa(s.Quantity), strconv.Itoa(s.Paid), strconv.Itoa(s.Discount), strconv.Itoa(s.Tax), strconv.Itoa(s.Gross),
+	}
+}
+
+func (cmd commands) listProducts(args []string) error {
+	if _, err := parse(flag.NewFlagSet("products ls", flag.ExitOnError), args, 0); err != nil {
+		return err
+	}
+
+	list, err := cmd.c.ListProducts(cmd.ctx)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(list))
+	for i, p := range list {
+		rows[i] = productRow(p)
+	}
+	return cmd.out.print(list, productHeader, rows)
+}
+
+func (cmd commands) getProduct(args []string) error {
+	pos, err := parse(flag.NewFlagSet("products get", flag.ExitOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	p, err := cmd.c.RetrieveProduct(cmd.ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	return cmd.out.print(p, productHeader, [][]string{productRow(*p)})
+}
+
+func (cmd commands) addProduct(args []string) error {
+	fs := flag.NewFlagSet("products add", flag.ExitOnError)
+
+	var np client.NewProduct
+	fs.StringVar(&np.Name, "name", "", "name of the product")
+	fs.StringVar(&np.Category, "category", "", "category of the product")
+	fs.IntVar(&np.Cost, "cost", 0, "price of a unit")
+	fs.IntVar(&np.Quantity, "qty", 1, "units in stock")
+	fs.IntVar(&np.LowStock, "low-stock", 0, "stock at which staff are alerted")
+	fs.IntVar(&np.MinPrice, "min-price", 0, "lowest offer accepted for a unit")
+	fs.StringVar(&np.SellerID, "seller", "", "seller the product is consigned by")
+
+	if _, err := parse(fs, args, 0); err != nil {
+		return err
+	}
+
+	p, err := cmd.c.CreateProduct(cmd.ctx, np)
+	if err != nil {
+		return err
+	}
+
+	return cmd.out.print(p, productHeader, [][]string{productRow(*p)})
+}
+
+func (cmd commands) editProduct(args []string) error {
+	fs := flag.NewFlagSet("products edit", flag.ExitOnError)
+
+	name := fs.String("name", "", "name of the product")
+	category := fs.String("category", "", "category of the product")
+	cost := fs.Int("cost", 0, "price of a unit")
+	qty := fs.Int("qty", 0, "units in stock")
+	lowStock := fs.Int("low-stock", 0, "stock at which staff are alerted")
+	minPrice := fs.Int("min-price", 0, "lowest offer accepted for a unit")
+	archived := fs.Bool("archived", false, "hide the product from the storefront")
+
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	// Only the flags that were given are changed.
+	var u client.UpdateProduct
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "name":
+			u.Name = name
+		case "category":
+			u.Category = category
+		case "cost":
+			u.Cost = cost
+		case "qty":
+			u.Quantity = qty
+		case "low-stock":
+			u.LowStock = lowStock
+		case "min-price":
+			u.MinPrice = minPrice
+		case "archived":
+			u.Archived = archived
+		}
+	})
+
+	if err := cmd.c.UpdateProduct(cmd.ctx, pos[0], u); err != nil {
+		return err
+	}
+
+	p, err := cmd.c.RetrieveProduct(cmd.ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	return cmd.out.print(p, productHeader, [][]string{productRow(*p)})
+}
+
+func (cmd commands) removeProduct(args []string) error {
+	pos, err := parse(flag.NewFlagSet("products rm", flag.ExitOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.c.DeleteProduct(cmd.ctx, pos[0]); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Removed", pos[0])
+	return nil
+}
+
+// sell records a sale. A price haggled at the table is given with -paid, the
+// total before tax, and is agreed through an offer the sale is then made on.
+func (cmd commands) sell(args []string) error {
+	fs := flag.NewFlagSet("sell", flag.ExitOnError)
+
+	var ns client.NewSale
+	fs.IntVar(&ns.Quantity, "qty", 1, "units sold")
+	fs.StringVar(&ns.VariantID, "variant", "", "variant sold, required for products with variants")
+	fs.StringVar(&ns.Coupon, "coupon", "", "coupon code to redeem")
+	fs.StringVar(&ns.Email, "email", "", "send the receipt to this address")
+	fs.StringVar(&ns.Jurisdiction, "jurisdiction", "", "tax jurisdiction of the sale")
+	paid := fs.Int("paid", -1, "total agreed for the units, before tax")
+
+	pos, err := parse(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id := pos[0]
+
+	if *paid >= 0 {
+		if ns.Coupon != "" {
+			return fmt.Errorf("-paid cannot be combined with -coupon")
+		}
+		if ns.Quantity < 1 || *paid%ns.Quantity != 0 {
+			return fmt.Errorf("-paid of %d does not split evenly over %d units", *paid, ns.Quantity)
+		}
+
+		no := client.NewOffer{VariantID: ns.VariantID, Quantity: ns.Quantity, Amount: *paid / ns.Quantity, Buyer: "sales-cli"}
+		o, err := cmd.c.MakeOffer(cmd.ctx, id, no)
+		if err != nil {
+			return err
+		}
+		if o.Status == client.OfferRejected {
+			return fmt.Errorf("price of %d turned down: %s", *paid, o.Reason)
+		}
+
+		if _, err := cmd.c.RespondToOffer(cmd.ctx, o.ID, client.OfferResponse{Action: client.OfferAccept}); err != nil {
+			return err
+		}
+		ns.OfferID = o.ID
+	}
+
+	s, err := cmd.c.AddSale(cmd.ctx, id, ns)
+	if err != nil {
+		return err
+	}
+
+	return cmd.out.print(s, saleHeader, [][]string{saleRow(*s)})
+}
+
+func (cmd commands) listSales(args []string) error {
+	pos, err := parse(flag.NewFlagSet("sales ls", flag.ExitOnError), args, 1)
+	if err != nil {
+		return err
+	}
+
+	list, err := cmd.c.ListSales(cmd.ctx, pos[0])
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(list))
+	for i, s := range list {
+		rows[i] = saleRow(s)
+	}
+	return cmd.out.print(list, saleHeader, rows)
+}
+
+// report prints the sales of the event added up by day, or only those of
+// today, along with the refunds made that day. Days are in UTC, as the API
+// adds them up.
+func (cmd commands) report(args []string, today bool) error {
+	if _, err := parse(flag.NewFlagSet("report", flag.ExitOnError), args, 0); err != nil {
+		return err
+	}
+
+	list, err := cmd.c.DailyTotals(cmd.ctx)
+	if err != nil {
+		return err
+	}
+
+	if today {
+		day := time.Now().UTC().Truncate(24 * time.Hour)
+		t := client.DayTotal{Day: day}
+		for _, d := range list {
+			if d.Day.Equal(day) {
+				t = d
+			}
+		}
+		list = []client.DayTotal{t}
+	}
+
+	header := []string{"day", "sales", "units", "discount", "net", "tax", "gross", "refunds", "refunded"}
+	rows := make([][]string, len(list))
+	for i, d := range list {
+		rows[i] = []string{
+			d.Day.Format("2006-01-02"),
+			strconv.Itoa(d.Sales), strconv.Itoa(d.Units), strconv.Itoa(d.Discount),
+			strconv.Itoa(d.Net), strconv.Itoa(d.Tax), strconv.Itoa(d.Gross),
+			strconv.Itoa(d.RefundedUnits), strconv.Itoa(d.RefundedGross),
+		}
+	}
+
+	if today {
+		return cmd.out.print(list[0], header, rows)
+	}
+	return cmd.out.print(list, header, rows)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// config is what sales-cli keeps in its config file.
+type config struct {
+	URL     string `json:"url"`
+	Token   string `json:"token,omitempty"`
+	EventID string `json:"event_id,omitempty"`
+	Actor   string `json:"actor,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+// defaultConfigPath gives where the config file is kept when -config is not
+// given.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "sales-cli.json"
+	}
+	return filepath.Join(dir, "garagesale", "sales-cli.json")
+}
+
+// loadConfig reads the config file. A missing file is an empty config.
+func loadConfig(path string) (config, error) {
+	var cfg config
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("reading config: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("reading config %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// saveConfig writes the config file. It holds the token so only the user may
+// read it.
+func saveConfig(path string, cfg config) error {
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o600); err != nil {
+		return fmt.Errorf("saving config: %w", err)
+	}
+
+	return nil
+}
+
+// configCmd changes or shows the config file.
+func configCmd(path string, cfg config, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "show":
+		if cfg.Token != "" {
+			cfg.Token = strings.Repeat("*", 8)
+		}
+		fmt.Printf("file:   %s\nurl:    %s\ntoken:  %s\nevent:  %s\nactor:  %s\noutput: %s\n",
+			path, cfg.URL, cfg.Token, cfg.EventID, cfg.Actor, cfg.Output)
+		return nil
+
+	case "set":
+		fs := flag.NewFlagSet("config set", flag.ExitOnError)
+		fs.StringVar(&cfg.URL, "url", cfg.URL, "base URL of the sales API")
+		fs.StringVar(&cfg.Token, "token", cfg.Token, "API token")
+		fs.StringVar(&cfg.EventID, "event", cfg.EventID, "event to work on, blank for the default event")
+		fs.StringVar(&cfg.Actor, "actor", cfg.Actor, "name recorded in the audit log")
+		fs.StringVar(&cfg.Output, "output", cfg.Output, "default output: table, json or csv")
+
+		if _, err := parse(fs, args[1:], 0); err != nil {
+			return err
+		}
+		if _, err := newPrinter(nil, cfg.Output); err != nil {
+			return err
+		}
+		cfg.URL = strings.TrimRight(cfg.URL, "/")
+
+		if err := saveConfig(path, cfg); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Saved", path)
+		return nil
+	}
+
+	return errUsage
+}
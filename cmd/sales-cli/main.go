@@ -0,0 +1,146 @@
+// Command sales-cli is what clerks use from a laptop at the sale. It talks to
+// sales-api, never to the database, using the base URL and token saved in its
+// config file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/client"
+)
+
+const usage = `usage: sales-cli [-config file] [-o table|json|csv] <command> [args]
+
+commands:
+  config set [-url url] [-token token] [-event id] [-actor name] [-output format]
+  config show
+  products ls
+  products get <id>
+  products add -name name [-category c] [-cost n] [-qty n] [-low-stock n] [-min-price n]
+  products edit <id> [-name name] [-category c] [-cost n] [-qty n] [-low-stock n] [-min-price n] [-archived]
+  products rm <id>
+  sell <id> [-qty n] [-paid total] [-variant id] [-coupon code] [-email address] [-jurisdiction j]
+  sales ls <id>
+  report today
+  report daily
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "sales-cli:", err)
+		os.Exit(1)
+	}
+}
+
+// errUsage is returned for command lines that make no sense.
+var errUsage = errors.New("see sales-cli -h for usage")
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("sales-cli", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+
+	path := fs.String("config", defaultConfigPath(), "config file")
+	output := fs.String("o", "", "output as table, json or csv")
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*path)
+	if err != nil {
+		return err
+	}
+
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	if args[0] == "config" {
+		return configCmd(*path, cfg, args[1:])
+	}
+
+	if *output != "" {
+		cfg.Output = *output
+	}
+
+	out, err := newPrinter(os.Stdout, cfg.Output)
+	if err != nil {
+		return err
+	}
+
+	if cfg.URL == "" {
+		return fmt.Errorf("no API url configured, run: sales-cli config set -url http://host:port")
+	}
+	c := client.New(cfg.URL)
+	c.Token = cfg.Token
+	c.EventID = cfg.EventID
+	c.Actor = cfg.Actor
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cmd := commands{ctx: ctx, c: c, out: out}
+
+	switch sub(args) {
+	case "products ls":
+		return cmd.listProducts(args[2:])
+	case "products get":
+		return cmd.getProduct(args[2:])
+	case "products add":
+		return cmd.addProduct(args[2:])
+	case "products edit":
+		return cmd.editProduct(args[2:])
+	case "products rm":
+		return cmd.removeProduct(args[2:])
+	case "sales ls":
+		return cmd.listSales(args[2:])
+	case "report today":
+		return cmd.report(args[2:], true)
+	case "report daily":
+		return cmd.report(args[2:], false)
+	}
+
+	if args[0] == "sell" {
+		return cmd.sell(args[1:])
+	}
+
+	fs.Usage()
+	return errUsage
+}
+
+// sub gives the command and subcommand named by the arguments.
+func sub(args []string) string {
+	if len(args) < 2 {
+		return args[0]
+	}
+	return args[0] + " " + args[1]
+}
+
+// parse parses the flags of a command, which may come before or after its
+// positional arguments, and checks there are exactly want positional ones.
+func parse(fs *flag.FlagSet, args []string, want int) ([]string, error) {
+	var pos []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		pos = append(pos, args[0])
+		args = args[1:]
+	}
+
+	if len(pos) != want {
+		return nil, errUsage
+	}
+	return pos, nil
+}
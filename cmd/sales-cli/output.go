@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// printer writes the results of commands as a table, JSON or CSV.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "":
+		format = "table"
+	case "table", "json", "csv":
+	default:
+		return nil, fmt.Errorf("unknown output %q, use table, json or csv", format)
+	}
+
+	return &printer{w: w, format: format}, nil
+}
+
+// print writes v as JSON, or the rows under the header as a table or CSV.
+func (p *printer) print(v interface{}, header []string, rows [][]string) error {
+	switch p.format {
+	case "json":
+		enc := json.NewEncoder(p.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+
+	case "csv":
+		cw := csv.NewWriter(p.w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		return cw.WriteAll(rows)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, r := range rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	return tw.Flush()
+}
@@ -1,10 +1,13 @@
 module github.com/ivan-sabo/garagesale
 
-go 1.18
+go 1.20
 
 require (
 	github.com/GuiaBolso/darwin v0.0.0-20191218124601-fd6d2aa3d244
+	github.com/boombuler/barcode v1.0.2
+	github.com/caarlos0/env/v6 v6.9.2
 	github.com/go-chi/chi v1.5.4
+	github.com/go-pdf/fpdf v0.9.0
 	github.com/go-playground/locales v0.14.0
 	github.com/go-playground/universal-translator v0.18.0
 	github.com/google/go-cmp v0.5.8
@@ -12,12 +15,12 @@ require (
 	github.com/jmoiron/sqlx v1.3.5
 	github.com/lib/pq v1.10.5
 	github.com/pkg/errors v0.9.1
+	golang.org/x/image v0.14.0
 	gopkg.in/go-playground/validator.v9 v9.31.0
 )
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.5.0 // indirect
-	github.com/caarlos0/env/v6 v6.9.2 // indirect
 	github.com/cznic/ql v1.2.0 // indirect
 	github.com/leodido/go-urn v1.2.1 // indirect
 	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
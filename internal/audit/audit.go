@@ -0,0 +1,200 @@
+// Package audit keeps an append-only record of who changed what.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// null is the JSON stored for a resource that does not exist.
+var null = json.RawMessage("null")
+
+// originKey is the context key an Origin is stored under.
+type originKey struct{}
+
+// NewContext gives a context carrying the origin of the changes made with it.
+func NewContext(ctx context.Context, o Origin) context.Context {
+	return context.WithValue(ctx, originKey{}, o)
+}
+
+// FromContext gives the origin carried by ctx, if any.
+func FromContext(ctx context.Context) (Origin, bool) {
+	o, ok := ctx.Value(originKey{}).(Origin)
+	return o, ok
+}
+
+// Log records a change made on behalf of the origin carried by ctx. It is
+// meant to be called inside the transaction that makes the change so the
+// change and its entry are committed, or rolled back, together. Before and
+// after are the resource as it was and as it is left, nil when it did not or
+// no longer exists. Changes made without an origin, like seeding the
+// database, are not recorded.
+func Log(ctx context.Context, tx sqlx.ExecerContext, action, resourceType, id string, before, after interface{}, now time.Time) error {
+	o, ok := FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	ne := NewEntry{
+		Actor:        o.Actor,
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   id,
+		RequestID:    o.RequestID,
+	}
+
+	var err error
+	if before != nil {
+		if ne.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("marshaling audited %s: %w", resourceType, err)
+		}
+	}
+	if after != nil {
+		if ne.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("marshaling audited %s: %w", resourceType, err)
+		}
+	}
+
+	_, err = Record(ctx, tx, ne, now)
+	return err
+}
+
+// Record adds a change to the audit log.
+func Record(ctx context.Context, db sqlx.ExecerContext, ne NewEntry, now time.Time) (*Entry, error) {
+	e := Entry{
+		ID:           uuid.New().String(),
+		Actor:        ne.Actor,
+		Action:       ne.Action,
+		ResourceType: ne.ResourceType,
+		ResourceID:   ne.ResourceID,
+		Before:       orNull(ne.Before),
+		After:        orNull(ne.After),
+		RequestID:    ne.RequestID,
+		DateCreated:  now.UTC(),
+	}
+
+	diff, err := Diff(e.Before, e.After)
+	if err != nil {
+		return nil, err
+	}
+	e.Diff = diff
+
+	const q = `INSERT INTO audit_log
+	(entry_id, actor, action, resource_type, resource_id, before, after, diff, request_id, date_created)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = db.ExecContext(ctx, q,
+		e.ID, e.Actor, e.Action, e.ResourceType, e.ResourceID,
+		[]byte(e.Before), []byte(e.After), []byte(e.Diff),
+		e.RequestID, e.DateCreated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("inserting audit entry: %w", err)
+	}
+
+	return &e, nil
+}
+
+// filterQuery selects the entries matching a Filter, oldest first.
+const filterQuery = `SELECT * FROM audit_log
+	WHERE ($1 = '' OR actor = $1)
+	AND ($2 = '' OR action = $2)
+	AND ($3 = '' OR resource_type = $3)
+	AND ($4 = '' OR resource_id = $4)
+	AND ($5::timestamp IS NULL OR date_created >= $5)
+	AND ($6::timestamp IS NULL OR date_created < $6)
+	AND seq > $7
+	ORDER BY seq
+	LIMIT NULLIF($8, 0)`
+
+// List returns the entries of the audit log matching the filter.
+func List(ctx context.Context, db *sqlx.DB, f Filter) ([]Entry, error) {
+	list := []Entry{}
+
+	if err := db.SelectContext(ctx, &list, filterQuery, f.args()...); err != nil {
+		return nil, fmt.Errorf("selecting audit entries: %w", err)
+	}
+
+	return list, nil
+}
+
+// Export writes the entries matching the filter to w as JSON lines. Entries
+// are streamed so the whole log never has to fit in memory. It returns the
+// number of entries written.
+func Export(ctx context.Context, db *sqlx.DB, f Filter, w io.Writer) (int, error) {
+	rows, err := db.QueryxContext(ctx, filterQuery, f.args()...)
+	if err != nil {
+		return 0, fmt.Errorf("selecting audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+
+	var n int
+	for rows.Next() {
+		var e Entry
+		if err := rows.StructScan(&e); err != nil {
+			return n, fmt.Errorf("scanning audit entry: %w", err)
+		}
+		if err := enc.Encode(e); err != nil {
+			return n, fmt.Errorf("writing audit entry: %w", err)
+		}
+		n++
+	}
+
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("reading audit entries: %w", err)
+	}
+
+	return n, nil
+}
+
+// Diff compares two JSON objects field by field. The result maps each field
+// whose value differs to a Change. Either side may be null.
+func Diff(before, after json.RawMessage) (json.RawMessage, error) {
+	var b, a map[string]interface{}
+	if err := json.Unmarshal(orNull(before), &b); err != nil {
+		return nil, fmt.Errorf("decoding before: %w", err)
+	}
+	if err := json.Unmarshal(orNull(after), &a); err != nil {
+		return nil, fmt.Errorf("decoding after: %w", err)
+	}
+
+	changes := make(map[string]Change)
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !reflect.DeepEqual(v, w) {
+			changes[k] = Change{Before: v, After: a[k]}
+		}
+	}
+	for k, w := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = Change{After: w}
+		}
+	}
+
+	return json.Marshal(changes)
+}
+
+// args gives the query arguments of the filter.
+func (f Filter) args() []interface{} {
+	return []interface{}{
+		f.Actor, f.Action, f.ResourceType, f.ResourceID,
+		f.Since, f.Until, f.AfterSeq, f.Limit,
+	}
+}
+
+// orNull gives the JSON null for an empty document.
+func orNull(doc json.RawMessage) json.RawMessage {
+	if len(bytes.TrimSpace(doc)) == 0 {
+		return null
+	}
+	return doc
+}
@@ -0,0 +1,62 @@
+package audit_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		want   map[string]audit.Change
+	}{
+		{
+			name:   "create",
+			before: "null",
+			after:  `{"id":"a","cost":10}`,
+			want: map[string]audit.Change{
+				"id":   {After: "a"},
+				"cost": {After: float64(10)},
+			},
+		},
+		{
+			name:   "update",
+			before: `{"id":"a","cost":10,"tags":["x"]}`,
+			after:  `{"id":"a","cost":12,"tags":["x"]}`,
+			want: map[string]audit.Change{
+				"cost": {Before: float64(10), After: float64(12)},
+			},
+		},
+		{
+			name:   "delete",
+			before: `{"id":"a"}`,
+			after:  "",
+			want: map[string]audit.Change{
+				"id": {Before: "a"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff, err := audit.Diff(json.RawMessage(tt.before), json.RawMessage(tt.after))
+			if err != nil {
+				t.Fatalf("diffing: %v", err)
+			}
+
+			var got map[string]audit.Change
+			if err := json.Unmarshal(diff, &got); err != nil {
+				t.Fatalf("decoding diff: %v", err)
+			}
+
+			if d := cmp.Diff(tt.want, got); d != "" {
+				t.Fatalf("unexpected diff:\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Entry is a change recorded in the audit log. Before and After hold the
+// resource as JSON, null when it did not exist, and Diff maps every field
+// that changed to its before and after values.
+type Entry struct {
+	Seq          int64           `db:"seq" json:"seq"`
+	ID           string          `db:"entry_id" json:"id"`
+	Actor        string          `db:"actor" json:"actor"`
+	Action       string          `db:"action" json:"action"`
+	ResourceType string          `db:"resource_type" json:"resource_type"`
+	ResourceID   string          `db:"resource_id" json:"resource_id"`
+	Before       json.RawMessage `db:"before" json:"before"`
+	After        json.RawMessage `db:"after" json:"after"`
+	Diff         json.RawMessage `db:"diff" json:"diff"`
+	RequestID    string          `db:"request_id" json:"request_id"`
+	DateCreated  time.Time       `db:"date_created" json:"date_created"`
+}
+
+// NewEntry is what is needed to record a change. Before and After are the
+// JSON documents of the resource, nil when it did not exist.
+type NewEntry struct {
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Before       json.RawMessage
+	After        json.RawMessage
+	RequestID    string
+}
+
+// Origin is who made a change and through which request.
+type Origin struct {
+	Actor     string
+	RequestID string
+}
+
+// Filter selects entries of the audit log. Empty fields match everything. Only
+// entries after the AfterSeq cursor are returned, at most Limit of them when
+// Limit is not 0.
+type Filter struct {
+	Actor        string
+	Action       string
+	ResourceType string
+	ResourceID   string
+	Since        *time.Time
+	Until        *time.Time
+	AfterSeq     int64
+	Limit        int
+}
+
+// Change is the before and after value of a single field.
+type Change struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
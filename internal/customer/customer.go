@@ -0,0 +1,295 @@
+// Package customer keeps records of the people buying at our events.
+package customer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/jmoiron/sqlx"
+)
+
+// Predefined errors for known failure scenarios
+var (
+	ErrNotFound  = errors.New("customer not found")
+	ErrInvalidID = errors.New("id provided was not a valid UUID")
+	ErrMergeSelf = errors.New("a customer can not be merged into itself")
+)
+
+// selectCustomers selects customers along with the totals of their sales.
+const selectCustomers = `SELECT
+	c.*,
+	COUNT(s.sale_id) AS purchases,
+	COALESCE(SUM(s.paid), 0) AS spent
+FROM customers AS c
+LEFT JOIN sales AS s ON s.customer_id = c.customer_id`
+
+// likeEscaper escapes the wildcards of a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// List returns the customers whose name, email or phone contain the search
+// term, or every customer when it is blank.
+func List(ctx context.Context, db *sqlx.DB, search string) ([]Customer, error) {
+	list := []Customer{}
+
+	const q = selectCustomers + `
+	WHERE $1::text = '' OR c.name ILIKE '%' || $1 || '%'
+		OR c.email ILIKE '%' || $1 || '%'
+		OR c.phone ILIKE '%' || $1 || '%'
+	GROUP BY c.customer_id
+	ORDER BY c.name`
+
+	if err := db.SelectContext(ctx, &list, q, likeEscaper.Replace(search)); err != nil {
+		return nil, fmt.Errorf("selecting customers: %w", err)
+	}
+
+	return list, nil
+}
+
+// Retrieve gives a single Customer
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Customer, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var c Customer
+
+	const q = selectCustomers + `
+	WHERE c.customer_id = $1
+	GROUP BY c.customer_id`
+
+	if err := db.GetContext(ctx, &c, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Create records a new Customer
+func Create(ctx context.Context, db *sqlx.DB, nc NewCustomer, now time.Time) (*Customer, error) {
+	c := Customer{
+		ID:               uuid.New().String(),
+		Name:             nc.Name,
+		Email:            nc.Email,
+		Phone:            nc.Phone,
+		Notes:            nc.Notes,
+		MarketingConsent: nc.MarketingConsent,
+		DateCreated:      now.UTC(),
+		DateUpdated:      now.UTC(),
+	}
+
+	const q = `INSERT INTO customers
+	(customer_id, name, email, phone, notes, marketing_consent, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	if _, err := db.ExecContext(ctx, q, c.ID, c.Name, c.Email, c.Phone, c.Notes, c.MarketingConsent, c.DateCreated, c.DateUpdated); err != nil {
+		return nil, fmt.Errorf("inserting customer: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Update modifies a Customer. It will error if the specified ID is invalid or
+// does not reference an existing Customer.
+func Update(ctx context.Context, db *sqlx.DB, id string, update UpdateCustomer, now time.Time) error {
+	c, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if update.Name != nil {
+		c.Name = *update.Name
+	}
+	if update.Email != nil {
+		c.Email = *update.Email
+	}
+	if update.Phone != nil {
+		c.Phone = *update.Phone
+	}
+	if update.Notes != nil {
+		c.Notes = *update.Notes
+	}
+	if update.MarketingConsent != nil {
+		c.MarketingConsent = *update.MarketingConsent
+	}
+	c.DateUpdated = now.UTC()
+
+	return save(ctx, db, *c)
+}
+
+// Purchases gives the sales of a Customer across all events, oldest first.
+func Purchases(ctx context.Context, db *sqlx.DB, id string) ([]product.Sale, error) {
+	if _, err := Retrieve(ctx, db, id); err != nil {
+		return nil, err
+	}
+
+	sales := []product.Sale{}
+
+	const q = `SELECT * FROM sales WHERE customer_id = $1 ORDER BY date_created`
+
+	if err := db.SelectContext(ctx, &sales, q, id); err != nil {
+		return nil, fmt.Errorf("selecting purchases: %w", err)
+	}
+
+	return sales, nil
+}
+
+// MergeInto folds a duplicate record into a Customer. The sales,
+// reservations, offers and bids of the duplicate move over and the duplicate
+// is removed. Details of the customer win; blank ones are filled in from the
+// duplicate and its notes are appended.
+func MergeInto(ctx context.Context, db *sqlx.DB, id, duplicateID string, now time.Time) (*Customer, error) {
+	for _, v := range []string{id, duplicateID} {
+		if _, err := uuid.Parse(v); err != nil {
+			return nil, ErrInvalidID
+		}
+	}
+	if id == duplicateID {
+		return nil, ErrMergeSelf
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const lock = `SELECT * FROM customers WHERE customer_id IN ($1, $2) FOR UPDATE`
+
+	var rows []Customer
+	if err := tx.SelectContext(ctx, &rows, lock, id, duplicateID); err != nil {
+		return nil, fmt.Errorf("locking customers: %w", err)
+	}
+	if len(rows) != 2 {
+		return nil, ErrNotFound
+	}
+
+	c, dup := rows[0], rows[1]
+	if c.ID != id {
+		c, dup = dup, c
+	}
+
+	if c.Name == "" {
+		c.Name = dup.Name
+	}
+	if c.Email == "" {
+		c.Email = dup.Email
+	}
+	if c.Phone == "" {
+		c.Phone = dup.Phone
+	}
+	switch {
+	case c.Notes == "":
+		c.Notes = dup.Notes
+	case dup.Notes != "":
+		c.Notes += "\n" + dup.Notes
+	}
+	c.DateUpdated = now.UTC()
+
+	if err := save(ctx, tx, c); err != nil {
+		return nil, err
+	}
+
+	for _, table := range []string{"sales", "reservations", "offers", "bids"} {
+		move := `UPDATE ` + table + ` SET customer_id = $1 WHERE customer_id = $2`
+		if _, err := tx.ExecContext(ctx, move, id, duplicateID); err != nil {
+			return nil, fmt.Errorf("moving %s: %w", table, err)
+		}
+	}
+
+	const remove = `DELETE FROM customers WHERE customer_id = $1`
+	if _, err := tx.ExecContext(ctx, remove, duplicateID); err != nil {
+		return nil, fmt.Errorf("deleting duplicate: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing merge: %w", err)
+	}
+
+	return Retrieve(ctx, db, id)
+}
+
+// Anonymize removes every personal detail of a Customer. The record itself
+// stays with only its ID and dates so its sales still add up to the same
+// totals. The notes on their reservations and the names on their offers and
+// bids are blanked as well. Sales, reservations, offers and bids, and the
+// audit entries written for them, refer to the customer by that ID alone and
+// are otherwise kept as they are.
+func Anonymize(ctx context.Context, db *sqlx.DB, id string, now time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const q = `UPDATE customers SET
+		"name" = '',
+		"email" = '',
+		"phone" = '',
+		"notes" = '',
+		"marketing_consent" = FALSE,
+		"date_anonymized" = COALESCE(date_anonymized, $2),
+		"date_updated" = $2
+		WHERE customer_id = $1`
+
+	res, err := tx.ExecContext(ctx, q, id, now.UTC())
+	if err != nil {
+		return fmt.Errorf("anonymizing customer: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("anonymizing customer: %w", err)
+	} else if n == 0 {
+		return ErrNotFound
+	}
+
+	const notes = `UPDATE reservations SET "note" = '' WHERE customer_id = $1`
+	if _, err := tx.ExecContext(ctx, notes, id); err != nil {
+		return fmt.Errorf("anonymizing reservations: %w", err)
+	}
+
+	const buyers = `UPDATE offers SET "buyer" = '' WHERE customer_id = $1`
+	if _, err := tx.ExecContext(ctx, buyers, id); err != nil {
+		return fmt.Errorf("anonymizing offers: %w", err)
+	}
+
+	const bidders = `UPDATE bids SET "bidder" = '' WHERE customer_id = $1`
+	if _, err := tx.ExecContext(ctx, bidders, id); err != nil {
+		return fmt.Errorf("anonymizing bids: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing anonymize: %w", err)
+	}
+
+	return nil
+}
+
+// save writes the details of a Customer.
+func save(ctx context.Context, db sqlx.ExecerContext, c Customer) error {
+	const q = `UPDATE customers SET
+		"name" = $2,
+		"email" = $3,
+		"phone" = $4,
+		"notes" = $5,
+		"marketing_consent" = $6,
+		"date_updated" = $7
+		WHERE customer_id = $1`
+
+	if _, err := db.ExecContext(ctx, q, c.ID, c.Name, c.Email, c.Phone, c.Notes, c.MarketingConsent, c.DateUpdated); err != nil {
+		return fmt.Errorf("updating customer: %w", err)
+	}
+
+	return nil
+}
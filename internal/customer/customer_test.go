@@ -0,0 +1,125 @@
+package customer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/customer"
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/product"
+)
+
+func TestCustomers(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	jane, err := customer.Create(ctx, db, customer.NewCustomer{Name: "Jane Doe", Email: "jane@example.com", MarketingConsent: true}, now)
+	if err != nil {
+		t.Fatalf("creating customer: %s", err)
+	}
+	dup, err := customer.Create(ctx, db, customer.NewCustomer{Name: "J. Doe", Phone: "555-0100", Notes: "prefers cash"}, now)
+	if err != nil {
+		t.Fatalf("creating customer: %s", err)
+	}
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Lamp", Cost: 20, Quantity: 5}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+	for _, id := range []string{jane.ID, dup.ID} {
+		if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{CustomerID: id, Quantity: 1}, p.ID, now); err != nil {
+			t.Fatalf("adding sale: %s", err)
+		}
+	}
+
+	found, err := customer.List(ctx, db, "doe")
+	if err != nil {
+		t.Fatalf("searching customers: %s", err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected both customers found, got %d", len(found))
+	}
+
+	merged, err := customer.MergeInto(ctx, db, jane.ID, dup.ID, now)
+	if err != nil {
+		t.Fatalf("merging: %s", err)
+	}
+	if merged.Name != "Jane Doe" || merged.Phone != "555-0100" || merged.Notes != "prefers cash" {
+		t.Fatalf("expected details kept and filled in, got %+v", merged)
+	}
+	if merged.Purchases != 2 || merged.Spent != 40 {
+		t.Fatalf("expected 2 purchases worth 40, got %d worth %d", merged.Purchases, merged.Spent)
+	}
+	if _, err := customer.Retrieve(ctx, db, dup.ID); err != customer.ErrNotFound {
+		t.Fatalf("expected the duplicate removed, got %v", err)
+	}
+
+	hold := product.NewReservation{CustomerID: jane.ID, Quantity: 1, Note: "Jane, picks up after 5", ExpiresAt: now.Add(time.Hour)}
+	res, err := product.Reserve(ctx, db, event.DefaultID, p.ID, hold, now)
+	if err != nil {
+		t.Fatalf("reserving: %s", err)
+	}
+
+	o, err := product.MakeOffer(ctx, db, event.DefaultID, p.ID, product.NewOffer{CustomerID: jane.ID, Buyer: "Jane", Quantity: 1, Amount: 15}, now)
+	if err != nil {
+		t.Fatalf("making offer: %s", err)
+	}
+
+	a, err := product.CreateAuction(ctx, db, event.DefaultID, p.ID, product.NewAuction{StartsAt: now, EndsAt: now.Add(time.Hour), MinIncrement: 1}, now)
+	if err != nil {
+		t.Fatalf("creating auction: %s", err)
+	}
+	if _, err := product.PlaceBid(ctx, db, event.DefaultID, a.ID, product.NewBid{CustomerID: jane.ID, Bidder: "Jane", Amount: 25}, now); err != nil {
+		t.Fatalf("bidding: %s", err)
+	}
+
+	if err := customer.Anonymize(ctx, db, jane.ID, now); err != nil {
+		t.Fatalf("anonymizing: %s", err)
+	}
+
+	got, err := customer.Retrieve(ctx, db, jane.ID)
+	if err != nil {
+		t.Fatalf("retrieving customer: %s", err)
+	}
+	if got.Name != "" || got.Email != "" || got.Phone != "" || got.MarketingConsent || got.DateAnonymized == nil {
+		t.Fatalf("expected personal details removed, got %+v", got)
+	}
+	if got.Purchases != 2 || got.Spent != 40 {
+		t.Fatalf("expected totals kept, got %d purchases worth %d", got.Purchases, got.Spent)
+	}
+
+	if res, err = product.RetrieveReservation(ctx, db, event.DefaultID, res.ID); err != nil {
+		t.Fatalf("retrieving reservation: %s", err)
+	}
+	if res.Note != "" {
+		t.Fatalf("expected the reservation note removed, got %q", res.Note)
+	}
+
+	if o, err = product.RetrieveOffer(ctx, db, event.DefaultID, o.ID); err != nil {
+		t.Fatalf("retrieving offer: %s", err)
+	}
+	if o.Buyer != "" {
+		t.Fatalf("expected the buyer removed from the offer, got %q", o.Buyer)
+	}
+
+	bids, err := product.ListBids(ctx, db, event.DefaultID, a.ID)
+	if err != nil {
+		t.Fatalf("listing bids: %s", err)
+	}
+	if len(bids) != 1 || bids[0].Bidder != "" {
+		t.Fatalf("expected the bidder removed from the bid, got %+v", bids)
+	}
+
+	sales, err := customer.Purchases(ctx, db, jane.ID)
+	if err != nil {
+		t.Fatalf("listing purchases: %s", err)
+	}
+	if len(sales) != 2 {
+		t.Fatalf("expected 2 purchases, got %d", len(sales))
+	}
+}
@@ -0,0 +1,44 @@
+package customer
+
+import "time"
+
+// Customer is someone who bought at one of our events. Purchases and Spent
+// are derived from the sales linked to the customer. An anonymized customer
+// has had all personal details removed but keeps its sales.
+type Customer struct {
+	ID               string     `db:"customer_id" json:"id"`
+	Name             string     `db:"name" json:"name"`
+	Email            string     `db:"email" json:"email,omitempty"`
+	Phone            string     `db:"phone" json:"phone,omitempty"`
+	Notes            string     `db:"notes" json:"notes,omitempty"`
+	MarketingConsent bool       `db:"marketing_consent" json:"marketing_consent"`
+	Purchases        int        `db:"purchases" json:"purchases"`
+	Spent            int        `db:"spent" json:"spent"`
+	DateAnonymized   *time.Time `db:"date_anonymized" json:"date_anonymized,omitempty"`
+	DateCreated      time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated      time.Time  `db:"date_updated" json:"date_updated"`
+}
+
+// NewCustomer is what we require from clients to record a new Customer.
+type NewCustomer struct {
+	Name             string `json:"name" validate:"required"`
+	Email            string `json:"email" validate:"omitempty,email"`
+	Phone            string `json:"phone"`
+	Notes            string `json:"notes"`
+	MarketingConsent bool   `json:"marketing_consent"`
+}
+
+// UpdateCustomer defines what information may be provided to modify an
+// existing Customer. All fields are optional.
+type UpdateCustomer struct {
+	Name             *string `json:"name" validate:"omitempty,min=1"`
+	Email            *string `json:"email" validate:"omitempty,email"`
+	Phone            *string `json:"phone"`
+	Notes            *string `json:"notes"`
+	MarketingConsent *bool   `json:"marketing_consent"`
+}
+
+// Merge names a duplicate to fold into a Customer.
+type Merge struct {
+	DuplicateID string `json:"duplicate_id" validate:"required,uuid"`
+}
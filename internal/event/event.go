@@ -0,0 +1,266 @@
+// Package event manages the garage sale events products and sales are
+// scoped to.
+package event
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// DefaultID identifies the event that holds everything created outside of a
+// specific event, including all data from before events existed.
+const DefaultID = "00000000-0000-0000-0000-000000000001"
+
+// foreignKeyViolation is the postgres error code for a violated foreign key.
+const foreignKeyViolation = "23503"
+
+// Predefined errors for known failure scenarios
+var (
+	ErrNotFound      = errors.New("event not found")
+	ErrInvalidID     = errors.New("id provided was not a valid UUID")
+	ErrInvalidDates  = errors.New("event must end after it starts")
+	ErrInvalidPlace  = errors.New("latitude and longitude must be given together")
+	ErrInvalidZone   = errors.New("timezone is not known")
+	ErrInvalidHours  = errors.New("opening hours must be HH:MM and close after they open")
+	ErrInUse         = errors.New("event still has products or sellers")
+	ErrDeleteDefault = errors.New("the default event can not be deleted")
+)
+
+// List returns all events, the most recent first.
+func List(ctx context.Context, db *sqlx.DB) ([]Event, error) {
+	list := []Event{}
+
+	const q = `SELECT * FROM events ORDER BY starts_at DESC NULLS LAST, date_created DESC`
+
+	if err := db.SelectContext(ctx, &list, q); err != nil {
+		return nil, fmt.Errorf("selecting events: %w", err)
+	}
+
+	if err := attachHours(ctx, db, list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Retrieve gives a single Event
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Event, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var e Event
+
+	const q = `SELECT * FROM events WHERE event_id = $1`
+
+	if err := db.GetContext(ctx, &e, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	list := []Event{e}
+	if err := attachHours(ctx, db, list); err != nil {
+		return nil, err
+	}
+
+	return &list[0], nil
+}
+
+// Create makes a new Event
+func Create(ctx context.Context, db *sqlx.DB, ne NewEvent, now time.Time) (*Event, error) {
+	e := Event{
+		ID:          uuid.New().String(),
+		Name:        ne.Name,
+		Location:    ne.Location,
+		Address:     ne.Address,
+		Latitude:    ne.Latitude,
+		Longitude:   ne.Longitude,
+		Timezone:    ne.Timezone,
+		Hours:       ne.Hours,
+		StartsAt:    ne.StartsAt,
+		EndsAt:      ne.EndsAt,
+		Commission:  ne.Commission,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+
+	if e.Timezone == "" {
+		e.Timezone = "UTC"
+	}
+	if e.Hours == nil {
+		e.Hours = []Hours{}
+	}
+
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const q = `INSERT INTO events
+	(event_id, name, location, address, latitude, longitude, timezone,
+		starts_at, ends_at, commission, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+
+	_, err = tx.ExecContext(ctx, q,
+		e.ID, e.Name, e.Location, e.Address, e.Latitude, e.Longitude, e.Timezone,
+		e.StartsAt, e.EndsAt, e.Commission, e.DateCreated, e.DateUpdated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("inserting event: %w", err)
+	}
+
+	if err := saveHours(ctx, tx, e.ID, e.Hours); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing event: %w", err)
+	}
+
+	return &e, nil
+}
+
+// Update modifies an Event. It will error if the specified ID is invalid or
+// does not reference an existing Event.
+func Update(ctx context.Context, db *sqlx.DB, id string, update UpdateEvent, now time.Time) error {
+	e, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if update.Name != nil {
+		e.Name = *update.Name
+	}
+	if update.Location != nil {
+		e.Location = *update.Location
+	}
+	if update.Address != nil {
+		e.Address = *update.Address
+	}
+	if update.Latitude != nil {
+		e.Latitude = update.Latitude
+	}
+	if update.Longitude != nil {
+		e.Longitude = update.Longitude
+	}
+	if update.Timezone != nil {
+		e.Timezone = *update.Timezone
+	}
+	if update.Hours != nil {
+		e.Hours = *update.Hours
+	}
+	if update.StartsAt != nil {
+		e.StartsAt = update.StartsAt
+	}
+	if update.EndsAt != nil {
+		e.EndsAt = update.EndsAt
+	}
+	if update.Commission != nil {
+		e.Commission = *update.Commission
+	}
+	e.DateUpdated = now.UTC()
+
+	if err := e.validate(); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const q = `UPDATE events SET
+		"name" = $2,
+		"location" = $3,
+		"address" = $4,
+		"latitude" = $5,
+		"longitude" = $6,
+		"timezone" = $7,
+		"starts_at" = $8,
+		"ends_at" = $9,
+		"commission" = $10,
+		"date_updated" = $11
+		WHERE event_id = $1`
+
+	_, err = tx.ExecContext(ctx, q,
+		id, e.Name, e.Location, e.Address, e.Latitude, e.Longitude, e.Timezone,
+		e.StartsAt, e.EndsAt, e.Commission, e.DateUpdated,
+	)
+	if err != nil {
+		return fmt.Errorf("updating event: %w", err)
+	}
+
+	if update.Hours != nil {
+		if err := saveHours(ctx, tx, id, e.Hours); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing event: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the event identified by a given ID. Events that still have
+// products or sellers can not be deleted.
+func Delete(ctx context.Context, db *sqlx.DB, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+	if id == DefaultID {
+		return ErrDeleteDefault
+	}
+
+	const q = `DELETE FROM events WHERE event_id = $1`
+
+	if _, err := db.ExecContext(ctx, q, id); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return ErrInUse
+		}
+		return fmt.Errorf("deleting event (id: %s): %w", id, err)
+	}
+
+	return nil
+}
+
+// validate checks the dates, place and opening hours of the event make sense.
+func (e Event) validate() error {
+	if e.StartsAt != nil && e.EndsAt != nil && !e.EndsAt.After(*e.StartsAt) {
+		return ErrInvalidDates
+	}
+	if (e.Latitude == nil) != (e.Longitude == nil) {
+		return ErrInvalidPlace
+	}
+	if _, err := time.LoadLocation(e.Timezone); err != nil {
+		return ErrInvalidZone
+	}
+	for _, h := range e.Hours {
+		opens, err := time.Parse("15:04", h.Opens)
+		if err != nil {
+			return ErrInvalidHours
+		}
+		closes, err := time.Parse("15:04", h.Closes)
+		if err != nil || !closes.After(opens) {
+			return ErrInvalidHours
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package event_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+)
+
+func TestNearby(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	lat, lng := 45.8150, 15.9819
+	zagreb := event.NewEvent{
+		Name:      "Zagreb Yard Sale",
+		Address:   "Ilica 1, Zagreb",
+		Latitude:  &lat,
+		Longitude: &lng,
+		Timezone:  "Europe/Zagreb",
+		Hours:     []event.Hours{{Weekday: 6, Opens: "09:00", Closes: "17:00"}},
+	}
+	z, err := event.Create(ctx, db, zagreb, now)
+	if err != nil {
+		t.Fatalf("creating event: %s", err)
+	}
+
+	splitLat, splitLng := 43.5081, 16.4402
+	split := event.NewEvent{Name: "Split Flea Market", Latitude: &splitLat, Longitude: &splitLng}
+	if _, err := event.Create(ctx, db, split, now); err != nil {
+		t.Fatalf("creating event: %s", err)
+	}
+
+	near := event.Nearby{Latitude: 45.8131, Longitude: 15.9770, Radius: 5}
+	list, err := event.FindNearby(ctx, db, near, now)
+	if err != nil {
+		t.Fatalf("finding nearby events: %s", err)
+	}
+	if len(list) != 1 || list[0].ID != z.ID {
+		t.Fatalf("expected only the Zagreb event within 5km, got %+v", list)
+	}
+	if d := *list[0].Distance; d <= 0 || d > 1 {
+		t.Fatalf("expected the Zagreb event under a kilometre away, got %.3fkm", d)
+	}
+	if len(list[0].Hours) != 1 || list[0].Hours[0].Opens != "09:00" {
+		t.Fatalf("expected the opening hours of the event, got %+v", list[0].Hours)
+	}
+
+	near.Radius = 500
+	if list, err = event.FindNearby(ctx, db, near, now); err != nil {
+		t.Fatalf("finding nearby events: %s", err)
+	}
+	if len(list) != 2 || list[0].ID != z.ID {
+		t.Fatalf("expected both events within 500km, closest first, got %+v", list)
+	}
+
+	// Saturday 6 June 2026 at 10:00 in Zagreb, then the Sunday after.
+	saturday := time.Date(2026, 6, 6, 8, 0, 0, 0, time.UTC)
+	near.OpenNow = true
+	if list, err = event.FindNearby(ctx, db, near, saturday); err != nil {
+		t.Fatalf("finding open events: %s", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected both events open on Saturday morning, got %d", len(list))
+	}
+	if list, err = event.FindNearby(ctx, db, near, saturday.AddDate(0, 0, 1)); err != nil {
+		t.Fatalf("finding open events: %s", err)
+	}
+	if len(list) != 1 || list[0].ID == z.ID {
+		t.Fatalf("expected the Zagreb event closed on Sunday, got %+v", list)
+	}
+}
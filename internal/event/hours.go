@@ -0,0 +1,67 @@
+package event
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	// Timezones of events are checked against the embedded database so they
+	// do not depend on the zoneinfo of the host.
+	_ "time/tzdata"
+)
+
+// attachHours loads the opening hours of every event of the list.
+func attachHours(ctx context.Context, db sqlx.QueryerContext, list []Event) error {
+	ids := make([]string, len(list))
+	for i, e := range list {
+		ids[i] = e.ID
+	}
+
+	var hours []Hours
+
+	const q = `SELECT event_id, weekday,
+		to_char(opens, 'HH24:MI') AS opens,
+		to_char(closes, 'HH24:MI') AS closes
+	FROM event_hours
+	WHERE event_id = ANY($1)
+	ORDER BY weekday, opens`
+
+	if err := sqlx.SelectContext(ctx, db, &hours, q, pq.Array(ids)); err != nil {
+		return fmt.Errorf("selecting opening hours: %w", err)
+	}
+
+	m := make(map[string][]Hours)
+	for _, h := range hours {
+		m[h.EventID] = append(m[h.EventID], h)
+	}
+
+	for i := range list {
+		list[i].Hours = m[list[i].ID]
+		if list[i].Hours == nil {
+			list[i].Hours = []Hours{}
+		}
+	}
+
+	return nil
+}
+
+// saveHours replaces the opening hours of an event.
+func saveHours(ctx context.Context, tx sqlx.ExecerContext, eventID string, hours []Hours) error {
+	const remove = `DELETE FROM event_hours WHERE event_id = $1`
+
+	if _, err := tx.ExecContext(ctx, remove, eventID); err != nil {
+		return fmt.Errorf("deleting opening hours: %w", err)
+	}
+
+	const q = `INSERT INTO event_hours (event_id, weekday, opens, closes) VALUES ($1, $2, $3, $4)`
+
+	for _, h := range hours {
+		if _, err := tx.ExecContext(ctx, q, eventID, h.Weekday, h.Opens, h.Closes); err != nil {
+			return fmt.Errorf("inserting opening hours: %w", err)
+		}
+	}
+
+	return nil
+}
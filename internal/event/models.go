@@ -0,0 +1,73 @@
+package event
+
+import "time"
+
+// Event is a single garage sale, such as one household's sale on a given
+// weekend. Every product and sale belongs to exactly one event. Commission is
+// the percentage of their takings the event keeps from consignment sellers
+// that do not have a rate of their own. Events with a Latitude and Longitude
+// can be found by buyers nearby; Hours are given in the Timezone of the event.
+type Event struct {
+	ID          string     `db:"event_id" json:"id"`
+	Name        string     `db:"name" json:"name"`
+	Location    string     `db:"location" json:"location"`
+	Address     string     `db:"address" json:"address"`
+	Latitude    *float64   `db:"latitude" json:"latitude,omitempty"`
+	Longitude   *float64   `db:"longitude" json:"longitude,omitempty"`
+	Timezone    string     `db:"timezone" json:"timezone"`
+	Hours       []Hours    `db:"-" json:"hours"`
+	Distance    *float64   `db:"distance" json:"distance,omitempty"`
+	StartsAt    *time.Time `db:"starts_at" json:"starts_at,omitempty"`
+	EndsAt      *time.Time `db:"ends_at" json:"ends_at,omitempty"`
+	Commission  int        `db:"commission" json:"commission"`
+	DateCreated time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated time.Time  `db:"date_updated" json:"date_updated"`
+}
+
+// Hours is when an event is open on a day of the week, Sunday being 0. Opens
+// and Closes are local times such as "09:00" and "17:30".
+type Hours struct {
+	EventID string `db:"event_id" json:"-"`
+	Weekday int    `db:"weekday" json:"weekday" validate:"gte=0,lte=6"`
+	Opens   string `db:"opens" json:"opens" validate:"required"`
+	Closes  string `db:"closes" json:"closes" validate:"required"`
+}
+
+// NewEvent is what we require from clients to make a new Event.
+type NewEvent struct {
+	Name       string     `json:"name" validate:"required"`
+	Location   string     `json:"location"`
+	Address    string     `json:"address"`
+	Latitude   *float64   `json:"latitude" validate:"omitempty,gte=-90,lte=90"`
+	Longitude  *float64   `json:"longitude" validate:"omitempty,gte=-180,lte=180"`
+	Timezone   string     `json:"timezone"`
+	Hours      []Hours    `json:"hours" validate:"dive"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	Commission int        `json:"commission" validate:"gte=0,lte=100"`
+}
+
+// UpdateEvent defines what information may be provided to modify an existing
+// Event. All fields are optional. Hours, when given, replace all opening
+// hours of the event.
+type UpdateEvent struct {
+	Name       *string    `json:"name" validate:"omitempty,min=1"`
+	Location   *string    `json:"location"`
+	Address    *string    `json:"address"`
+	Latitude   *float64   `json:"latitude" validate:"omitempty,gte=-90,lte=90"`
+	Longitude  *float64   `json:"longitude" validate:"omitempty,gte=-180,lte=180"`
+	Timezone   *string    `json:"timezone"`
+	Hours      *[]Hours   `json:"hours" validate:"omitempty,dive"`
+	StartsAt   *time.Time `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at"`
+	Commission *int       `json:"commission" validate:"omitempty,gte=0,lte=100"`
+}
+
+// Nearby is a search for events around a point. Radius is in kilometres.
+// With OpenNow only events open at the time of the search are found.
+type Nearby struct {
+	Latitude  float64
+	Longitude float64
+	Radius    float64
+	OpenNow   bool
+}
@@ -0,0 +1,63 @@
+package event
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrInvalidSearch is returned for a nearby search without a sensible point
+// or radius.
+var ErrInvalidSearch = errors.New("search needs a latitude, longitude and positive radius")
+
+// nearbyQuery finds the events within a radius of a point, the closest first.
+// Distances are great-circle distances in kilometres. An event is open now
+// when the time falls within its dates, if it has any, and within its opening
+// hours for the day, if it has any, both in the timezone of the event.
+const nearbyQuery = `SELECT * FROM (
+	SELECT e.*, 6371 * 2 * ASIN(SQRT(LEAST(1,
+		POWER(SIN(RADIANS(e.latitude - $1) / 2), 2) +
+		COS(RADIANS($1)) * COS(RADIANS(e.latitude)) *
+		POWER(SIN(RADIANS(e.longitude - $2) / 2), 2)
+	))) AS distance
+	FROM events AS e
+	WHERE e.latitude IS NOT NULL AND e.longitude IS NOT NULL
+) AS e
+WHERE e.distance <= $3
+AND (NOT $4 OR (
+	(e.starts_at IS NULL OR e.starts_at <= $5)
+	AND (e.ends_at IS NULL OR e.ends_at > $5)
+	AND (
+		NOT EXISTS (SELECT 1 FROM event_hours AS h WHERE h.event_id = e.event_id)
+		OR EXISTS (
+			SELECT 1 FROM event_hours AS h,
+				LATERAL (SELECT ($5::timestamp AT TIME ZONE 'UTC') AT TIME ZONE e.timezone AS t) AS l
+			WHERE h.event_id = e.event_id
+			AND h.weekday = EXTRACT(DOW FROM l.t)
+			AND l.t::time >= h.opens AND l.t::time < h.closes
+		)
+	)
+))
+ORDER BY e.distance, e.name`
+
+// FindNearby gives the events around a point, the closest first.
+func FindNearby(ctx context.Context, db *sqlx.DB, n Nearby, now time.Time) ([]Event, error) {
+	if n.Latitude < -90 || n.Latitude > 90 || n.Longitude < -180 || n.Longitude > 180 || n.Radius <= 0 {
+		return nil, ErrInvalidSearch
+	}
+
+	list := []Event{}
+
+	if err := db.SelectContext(ctx, &list, nearbyQuery, n.Latitude, n.Longitude, n.Radius, n.OpenNow, now.UTC()); err != nil {
+		return nil, fmt.Errorf("selecting nearby events: %w", err)
+	}
+
+	if err := attachHours(ctx, db, list); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
@@ -0,0 +1,113 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Lease is how long a key stays held by a request that has not completed.
+// Should the request never Complete or Release the key, say because the
+// server went down while handling it, the key is freed once the lease runs
+// out.
+const Lease = time.Minute
+
+// Predefined errors for known failure scenarios
+var (
+	ErrKeyReused  = errors.New("idempotency key was already used for a different request")
+	ErrInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrLeaseLost  = errors.New("idempotency key is no longer held by this request")
+)
+
+// Reserve claims a key within a scope for a request identified by
+// fingerprint. It returns a nil Record when the key was free and is now held
+// by holder for the Lease, who must Complete or Release it before then. When
+// the key was used before by the same request the stored Record is returned
+// so its response can be replayed.
+func Reserve(ctx context.Context, db *sqlx.DB, scope, key, holder, fingerprint string, now time.Time) (*Record, error) {
+	now = now.UTC()
+
+	const purge = `DELETE FROM idempotency_keys WHERE expires_at <= $1`
+
+	if _, err := db.ExecContext(ctx, purge, now); err != nil {
+		return nil, fmt.Errorf("purging expired idempotency keys: %w", err)
+	}
+
+	const q = `INSERT INTO idempotency_keys
+	(scope, idempotency_key, holder, fingerprint, status, content_type, body, date_created, expires_at)
+	VALUES ($1, $2, $3, $4, 0, '', '', $5, $6)
+	ON CONFLICT (scope, idempotency_key) DO NOTHING`
+
+	res, err := db.ExecContext(ctx, q, scope, key, holder, fingerprint, now, now.Add(Lease))
+	if err != nil {
+		return nil, fmt.Errorf("reserving idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("reserving idempotency key: %w", err)
+	} else if n == 1 {
+		return nil, nil
+	}
+
+	var rec Record
+
+	const s = `SELECT * FROM idempotency_keys WHERE scope = $1 AND idempotency_key = $2`
+
+	if err := db.GetContext(ctx, &rec, s, scope, key); err != nil {
+		if err == sql.ErrNoRows {
+			// The key expired between the insert and the select.
+			return Reserve(ctx, db, scope, key, holder, fingerprint, now)
+		}
+		return nil, fmt.Errorf("selecting idempotency key: %w", err)
+	}
+
+	if rec.Fingerprint != fingerprint {
+		return nil, ErrKeyReused
+	}
+	if rec.Status == 0 {
+		return nil, ErrInProgress
+	}
+
+	return &rec, nil
+}
+
+// Complete stores the response sent for the request holding the key. The
+// response is replayed to retries until expiresAt. It fails with ErrLeaseLost
+// when the lease of holder ran out and the key was freed or claimed by
+// another request meanwhile.
+func Complete(ctx context.Context, db *sqlx.DB, scope, key, holder string, status int, contentType string, body []byte, expiresAt time.Time) error {
+	const q = `UPDATE idempotency_keys SET
+		status = $4,
+		content_type = $5,
+		body = $6,
+		expires_at = $7
+		WHERE scope = $1 AND idempotency_key = $2 AND holder = $3 AND status = 0`
+
+	res, err := db.ExecContext(ctx, q, scope, key, holder, status, contentType, body, expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("completing idempotency key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("completing idempotency key: %w", err)
+	} else if n == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
+
+// Release frees a key held by holder whose request failed so that it can be
+// retried.
+func Release(ctx context.Context, db *sqlx.DB, scope, key, holder string) error {
+	const q = `DELETE FROM idempotency_keys
+	WHERE scope = $1 AND idempotency_key = $2 AND holder = $3 AND status = 0`
+
+	if _, err := db.ExecContext(ctx, q, scope, key, holder); err != nil {
+		return fmt.Errorf("releasing idempotency key: %w", err)
+	}
+
+	return nil
+}
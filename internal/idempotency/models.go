@@ -0,0 +1,19 @@
+package idempotency
+
+import "time"
+
+// Record is a stored idempotency key together with the response that was sent
+// for the first request made with it. Keys only need to be unique within
+// their Scope. A Status of 0 means the first request is still being processed
+// by the request identified by Holder.
+type Record struct {
+	Scope       string    `db:"scope"`
+	Key         string    `db:"idempotency_key"`
+	Holder      string    `db:"holder"`
+	Fingerprint string    `db:"fingerprint"`
+	Status      int       `db:"status"`
+	ContentType string    `db:"content_type"`
+	Body        []byte    `db:"body"`
+	DateCreated time.Time `db:"date_created"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
@@ -0,0 +1,365 @@
+// Package images manages the photos attached to products.
+package images
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// maxPixels guards against images that are small files but decode into huge
+// bitmaps.
+const maxPixels = 25_000_000
+
+// Predefined errors for known failure scenarios
+var (
+	ErrNotFound        = errors.New("image not found")
+	ErrInvalidID       = errors.New("id provided was not a valid UUID")
+	ErrUnsupportedType = errors.New("image must be a JPEG, PNG or GIF")
+	ErrInvalidImage    = errors.New("image could not be decoded")
+	ErrTooManyPixels   = errors.New("image dimensions are too large")
+	ErrUnknownSize     = errors.New("unknown image size")
+)
+
+// decoders are the image formats we accept, keyed by content type.
+var decoders = map[string]func(io.Reader) (image.Image, error){
+	"image/jpeg": jpeg.Decode,
+	"image/png":  png.Decode,
+	"image/gif":  gif.Decode,
+}
+
+// Add stores an uploaded image of a product together with its thumbnails.
+// The first image of a product becomes its primary image.
+func Add(ctx context.Context, db *sqlx.DB, st storage.Storage, productID string, data []byte, now time.Time) (*Image, error) {
+	contentType := http.DetectContentType(data)
+	decode, ok := decoders[contentType]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	decoded, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	src := toRGBA(decoded)
+
+	img := Image{
+		ID:          uuid.New().String(),
+		ProductID:   productID,
+		ContentType: contentType,
+		Width:       cfg.Width,
+		Height:      cfg.Height,
+		Size:        int64(len(data)),
+		DateCreated: now.UTC(),
+	}
+
+	if err := st.Put(ctx, key(img, "original"), bytes.NewReader(data), contentType); err != nil {
+		return nil, fmt.Errorf("storing original: %w", err)
+	}
+	for name, max := range Sizes {
+		var buf bytes.Buffer
+		if err := encodeThumbnail(&buf, thumbnail(src, max), contentType); err != nil {
+			return nil, fmt.Errorf("encoding %s thumbnail: %w", name, err)
+		}
+		if err := st.Put(ctx, key(img, name), &buf, thumbnailType(contentType)); err != nil {
+			return nil, fmt.Errorf("storing %s thumbnail: %w", name, err)
+		}
+	}
+
+	const q = `INSERT INTO product_images
+	(image_id, product_id, content_type, width, height, size, position, is_primary, date_created)
+	SELECT $1, $2, $3, $4, $5, $6,
+		COALESCE(MAX(position) + 1, 0),
+		COUNT(*) = 0,
+		$7
+	FROM product_images WHERE product_id = $2
+	RETURNING position, is_primary,
+		(SELECT event_id FROM products WHERE product_id = $2)`
+
+	row := db.QueryRowxContext(ctx, q, img.ID, img.ProductID, img.ContentType, img.Width, img.Height, img.Size, img.DateCreated)
+	if err := row.Scan(&img.Position, &img.Primary, &img.EventID); err != nil {
+		removeObjects(ctx, st, img)
+		return nil, fmt.Errorf("inserting image: %w", err)
+	}
+
+	img.setURLs()
+	return &img, nil
+}
+
+// List gives all images of a product, primary first and then by position.
+func List(ctx context.Context, db *sqlx.DB, productID string) ([]Image, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	m, err := ForProducts(ctx, db, []string{productID})
+	if err != nil {
+		return nil, err
+	}
+
+	if m[productID] == nil {
+		return []Image{}, nil
+	}
+	return m[productID], nil
+}
+
+// ForProducts gives the images of many products at once, keyed by product ID.
+func ForProducts(ctx context.Context, db sqlx.QueryerContext, productIDs []string) (map[string][]Image, error) {
+	var list []Image
+
+	const q = `SELECT i.*, p.event_id FROM product_images AS i
+	JOIN products AS p ON p.product_id = i.product_id
+	WHERE i.product_id = ANY($1)
+	ORDER BY i.is_primary DESC, i.position`
+
+	if err := sqlx.SelectContext(ctx, db, &list, q, pq.Array(productIDs)); err != nil {
+		return nil, fmt.Errorf("selecting images: %w", err)
+	}
+
+	m := make(map[string][]Image)
+	for _, img := range list {
+		img.setURLs()
+		m[img.ProductID] = append(m[img.ProductID], img)
+	}
+
+	return m, nil
+}
+
+// Retrieve gives a single image of a product.
+func Retrieve(ctx context.Context, db *sqlx.DB, productID, imageID string) (*Image, error) {
+	if _, err := uuid.Parse(productID); err != nil {
+		return nil, ErrInvalidID
+	}
+	if _, err := uuid.Parse(imageID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var img Image
+
+	const q = `SELECT i.*, p.event_id FROM product_images AS i
+	JOIN products AS p ON p.product_id = i.product_id
+	WHERE i.product_id = $1 AND i.image_id = $2`
+
+	if err := db.GetContext(ctx, &img, q, productID, imageID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	img.setURLs()
+	return &img, nil
+}
+
+// Open gives the content of one size of an image along with its content type.
+// The caller must close the returned reader.
+func Open(ctx context.Context, db *sqlx.DB, st storage.Storage, productID, imageID, size string) (io.ReadCloser, string, error) {
+	img, err := Retrieve(ctx, db, productID, imageID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	contentType := img.ContentType
+	if size != "original" {
+		if _, ok := Sizes[size]; !ok {
+			return nil, "", ErrUnknownSize
+		}
+		contentType = thumbnailType(img.ContentType)
+	}
+
+	r, err := st.Get(ctx, key(*img, size))
+	if err != nil {
+		if err == storage.ErrNotFound {
+			return nil, "", ErrNotFound
+		}
+		return nil, "", err
+	}
+
+	return r, contentType, nil
+}
+
+// Update moves an image to another position or makes it the primary image.
+func Update(ctx context.Context, db *sqlx.DB, productID, imageID string, update UpdateImage) error {
+	if _, err := Retrieve(ctx, db, productID, imageID); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if update.Position != nil {
+		var ids []string
+
+		const q = `SELECT image_id FROM product_images
+		WHERE product_id = $1
+		ORDER BY position
+		FOR UPDATE`
+
+		if err := tx.SelectContext(ctx, &ids, q, productID); err != nil {
+			return fmt.Errorf("selecting images: %w", err)
+		}
+
+		order := make([]string, 0, len(ids))
+		for _, id := range ids {
+			if id != imageID {
+				order = append(order, id)
+			}
+		}
+		pos := *update.Position
+		if pos > len(order) {
+			pos = len(order)
+		}
+		order = append(order[:pos], append([]string{imageID}, order[pos:]...)...)
+
+		if err := renumber(ctx, tx, order); err != nil {
+			return err
+		}
+	}
+
+	if update.Primary != nil && *update.Primary {
+		const q = `UPDATE product_images SET is_primary = (image_id = $2) WHERE product_id = $1`
+
+		if _, err := tx.ExecContext(ctx, q, productID, imageID); err != nil {
+			return fmt.Errorf("setting primary image: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes an image and its files. When the primary image is removed
+// the next image in line takes its place.
+func Delete(ctx context.Context, db *sqlx.DB, st storage.Storage, productID, imageID string) error {
+	img, err := Retrieve(ctx, db, productID, imageID)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	const q = `DELETE FROM product_images WHERE image_id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, imageID); err != nil {
+		return fmt.Errorf("deleting image: %w", err)
+	}
+
+	var ids []string
+
+	const s = `SELECT image_id FROM product_images WHERE product_id = $1 ORDER BY position FOR UPDATE`
+
+	if err := tx.SelectContext(ctx, &ids, s, productID); err != nil {
+		return fmt.Errorf("selecting images: %w", err)
+	}
+	if err := renumber(ctx, tx, ids); err != nil {
+		return err
+	}
+
+	if img.Primary && len(ids) > 0 {
+		const u = `UPDATE product_images SET is_primary = true WHERE image_id = $1`
+
+		if _, err := tx.ExecContext(ctx, u, ids[0]); err != nil {
+			return fmt.Errorf("promoting primary image: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	removeObjects(ctx, st, *img)
+	return nil
+}
+
+// RemoveFiles deletes the stored files of images whose rows are already gone,
+// such as those of a deleted product.
+func RemoveFiles(ctx context.Context, st storage.Storage, list []Image) {
+	for _, img := range list {
+		removeObjects(ctx, st, img)
+	}
+}
+
+// renumber stores the given order of images as their positions.
+func renumber(ctx context.Context, tx *sqlx.Tx, ids []string) error {
+	const q = `UPDATE product_images SET position = $2 WHERE image_id = $1`
+
+	for i, id := range ids {
+		if _, err := tx.ExecContext(ctx, q, id, i); err != nil {
+			return fmt.Errorf("positioning image: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// removeObjects deletes every stored size of an image. Failures only leave
+// orphaned files behind so they are ignored.
+func removeObjects(ctx context.Context, st storage.Storage, img Image) {
+	st.Delete(ctx, key(img, "original"))
+	for name := range Sizes {
+		st.Delete(ctx, key(img, name))
+	}
+}
+
+// key gives the storage key of one size of an image.
+func key(img Image, size string) string {
+	return "products/" + img.ProductID + "/" + img.ID + "/" + size
+}
+
+// setURLs fills in where each size of the image can be downloaded, under the
+// event the product belongs to.
+func (img *Image) setURLs() {
+	base := "/v1/events/" + img.EventID + "/products/" + img.ProductID + "/images/" + img.ID + "/"
+
+	img.URLs = map[string]string{"original": base + "original"}
+	for name := range Sizes {
+		img.URLs[name] = base + name
+	}
+}
+
+// thumbnailType gives the content type thumbnails of an image are stored in.
+// Photos stay JPEG, everything else becomes PNG to keep transparency.
+func thumbnailType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+func encodeThumbnail(w io.Writer, img image.Image, contentType string) error {
+	if thumbnailType(contentType) == "image/jpeg" {
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	}
+	return png.Encode(w, img)
+}
@@ -0,0 +1,27 @@
+package images
+
+import "time"
+
+// Image is a photo of a product. URLs maps the name of every available size,
+// including "original", to the path it can be downloaded from.
+type Image struct {
+	ID          string            `db:"image_id" json:"id"`
+	ProductID   string            `db:"product_id" json:"-"`
+	EventID     string            `db:"event_id" json:"-"`
+	ContentType string            `db:"content_type" json:"content_type"`
+	Width       int               `db:"width" json:"width"`
+	Height      int               `db:"height" json:"height"`
+	Size        int64             `db:"size" json:"size"`
+	Position    int               `db:"position" json:"position"`
+	Primary     bool              `db:"is_primary" json:"primary"`
+	URLs        map[string]string `db:"-" json:"urls"`
+	DateCreated time.Time         `db:"date_created" json:"date_created"`
+}
+
+// UpdateImage defines how an existing Image may be changed. Position moves the
+// image within the product's images and Primary set to true makes it the
+// image shown first.
+type UpdateImage struct {
+	Position *int  `json:"position" validate:"omitempty,gte=0"`
+	Primary  *bool `json:"primary"`
+}
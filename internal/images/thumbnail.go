@@ -0,0 +1,78 @@
+package images
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Thumbnail sizes generated for every upload, keyed by name. The value is the
+// length of the longest side in pixels.
+var Sizes = map[string]int{
+	"small":  160,
+	"medium": 480,
+	"large":  1024,
+}
+
+// toRGBA gives the pixels of an image as RGBA with its origin at 0,0, which
+// is what thumbnail works on. Decoded images are converted once and then
+// shared by every thumbnail size.
+func toRGBA(src image.Image) *image.RGBA {
+	if rgba, ok := src.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) {
+		return rgba
+	}
+
+	b := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, b.Min, draw.Src)
+	return rgba
+}
+
+// thumbnail scales an image down so its longest side is at most max pixels,
+// averaging the source pixels that fall into each target pixel. Images that
+// are already small enough are returned unchanged.
+func thumbnail(rgba *image.RGBA, max int) image.Image {
+	w, h := rgba.Rect.Dx(), rgba.Rect.Dy()
+	if w <= max && h <= max {
+		return rgba
+	}
+
+	tw, th := max, h*max/w
+	if h > w {
+		tw, th = w*max/h, max
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := y*h/th, (y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := x*w/tw, (x+1)*w/tw
+
+			var r, g, bl, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(rgba.Pix[i])
+					g += int(rgba.Pix[i+1])
+					bl += int(rgba.Pix[i+2])
+					a += int(rgba.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+
+			o := dst.PixOffset(x, y)
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(bl / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
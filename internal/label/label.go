@@ -0,0 +1,188 @@
+// Package label renders printable price labels carrying a barcode or QR code.
+package label
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Symbologies a label code can be printed in.
+const (
+	Code128 = "code128"
+	QR      = "qr"
+)
+
+// ErrUnknownSymbology is returned for a symbology we can not print.
+var ErrUnknownSymbology = errors.New("symbology must be code128 or qr")
+
+// Label is what gets printed on a single sticker. Code is what the barcode
+// encodes, usually a product ID or variant SKU.
+type Label struct {
+	Name  string
+	Price int
+	Code  string
+}
+
+// Pixel size of PNG labels, roughly 70x37mm at 200 DPI.
+const (
+	pngWidth  = 560
+	pngHeight = 296
+	pngMargin = 16
+)
+
+// PNG renders a label as a PNG image.
+func PNG(w io.Writer, l Label, symbology string) error {
+	img := image.NewRGBA(image.Rect(0, 0, pngWidth, pngHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	inner := pngWidth - 2*pngMargin
+
+	switch symbology {
+	case Code128:
+		drawText(img, truncate(l.Name, inner/(7*3)), pngMargin, pngMargin, 3)
+		drawText(img, formatPrice(l.Price), pngMargin, pngMargin+13*3+8, 3)
+
+		top := pngMargin + 2*(13*3+8)
+		code, err := encode(l.Code, symbology, inner, pngHeight-top-pngMargin-13*2-4)
+		if err != nil {
+			return err
+		}
+		draw.Draw(img, code.Bounds().Add(image.Pt(pngMargin, top)), code, image.Point{}, draw.Src)
+		drawText(img, truncate(l.Code, inner/(7*2)), pngMargin, pngHeight-pngMargin-13*2, 2)
+
+	case QR:
+		side := pngHeight - 2*pngMargin
+		code, err := encode(l.Code, symbology, side, side)
+		if err != nil {
+			return err
+		}
+		draw.Draw(img, code.Bounds().Add(image.Pt(pngWidth-pngMargin-side, pngMargin)), code, image.Point{}, draw.Src)
+
+		text := inner - side
+		drawText(img, truncate(l.Name, text/(7*2)), pngMargin, pngMargin, 2)
+		drawText(img, formatPrice(l.Price), pngMargin, pngMargin+13*2+8, 2)
+
+	default:
+		return ErrUnknownSymbology
+	}
+
+	return png.Encode(w, img)
+}
+
+// encode builds a barcode image of the code scaled to fit the given size.
+func encode(content, symbology string, width, height int) (image.Image, error) {
+	var (
+		bc  barcode.Barcode
+		err error
+	)
+	switch symbology {
+	case Code128:
+		bc, err = code128.Encode(content)
+	case QR:
+		bc, err = qr.Encode(content, qr.M, qr.Auto)
+	default:
+		return nil, ErrUnknownSymbology
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", symbology, err)
+	}
+
+	// Scale by whole modules so every bar keeps the same width.
+	b := bc.Bounds()
+	if symbology == Code128 {
+		factor := width / b.Dx()
+		if factor < 1 {
+			return nil, fmt.Errorf("code %q is too long for a label", content)
+		}
+		return barcode.Scale(bc, b.Dx()*factor, height)
+	}
+
+	// QR codes need a quiet zone of four modules on every side to scan.
+	const quiet = 4
+	side := width
+	if height < side {
+		side = height
+	}
+	factor := side / (b.Dx() + 2*quiet)
+	if factor < 1 {
+		return nil, fmt.Errorf("code %q is too long for a label", content)
+	}
+	scaled, err := barcode.Scale(bc, b.Dx()*factor, b.Dy()*factor)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(padded, padded.Bounds(), image.White, image.Point{}, draw.Src)
+	offset := (side - b.Dx()*factor) / 2
+	draw.Draw(padded, scaled.Bounds().Add(image.Pt(offset, offset)), scaled, image.Point{}, draw.Src)
+
+	return padded, nil
+}
+
+// drawText writes a line of text with its top left corner at x, y, scaling
+// the built in bitmap font by a whole factor.
+func drawText(dst draw.Image, s string, x, y, scale int) {
+	face := basicfont.Face7x13
+	small := image.NewRGBA(image.Rect(0, 0, len(s)*face.Advance, face.Height))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+
+	d := font.Drawer{
+		Dst:  small,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(0, face.Ascent),
+	}
+	d.DrawString(s)
+
+	b := small.Bounds()
+	for sy := 0; sy < b.Dy(); sy++ {
+		for sx := 0; sx < b.Dx(); sx++ {
+			c := small.RGBAAt(sx, sy)
+			if c == (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+				continue
+			}
+			r := image.Rect(x+sx*scale, y+sy*scale, x+(sx+1)*scale, y+(sy+1)*scale)
+			draw.Draw(dst, r, image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+}
+
+// truncate shortens s to at most n characters, marking the cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// formatPrice gives the price as it is printed on a label.
+func formatPrice(price int) string {
+	return fmt.Sprintf("Price: %d", price)
+}
+
+// pngBytes encodes a barcode image for embedding in a PDF.
+func pngBytes(img image.Image) (*bytes.Reader, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf.Bytes()), nil
+}
@@ -0,0 +1,44 @@
+package label_test
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/ivan-sabo/garagesale/internal/label"
+)
+
+func TestRender(t *testing.T) {
+	l := label.Label{
+		Name:  "Comic Books",
+		Price: 50,
+		Code:  "fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01",
+	}
+
+	for _, sym := range []string{label.Code128, label.QR} {
+		var buf bytes.Buffer
+		if err := label.PNG(&buf, l, sym); err != nil {
+			t.Fatalf("rendering %s png: %v", sym, err)
+		}
+		if _, err := png.Decode(&buf); err != nil {
+			t.Fatalf("decoding %s png: %v", sym, err)
+		}
+
+		buf.Reset()
+		if err := label.PDF(&buf, l, sym); err != nil {
+			t.Fatalf("rendering %s pdf: %v", sym, err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+			t.Fatalf("expected %s pdf output to start with %%PDF", sym)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := label.Sheet(&buf, []label.Label{l, l, l}, label.QR); err != nil {
+		t.Fatalf("rendering sheet: %v", err)
+	}
+
+	if err := label.PNG(&buf, l, "ean13"); err != label.ErrUnknownSymbology {
+		t.Fatalf("expected %v, got %v", label.ErrUnknownSymbology, err)
+	}
+}
@@ -0,0 +1,128 @@
+package label
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Label and sheet geometry in millimeters. Sheets use the common A4 layout
+// of three columns by eight rows of 70x37mm stickers.
+const (
+	labelWidth  = 70.0
+	labelHeight = 37.0
+	labelMargin = 3.0
+
+	sheetColumns = 3
+	sheetRows    = 8
+	sheetTop     = (297.0 - sheetRows*labelHeight) / 2
+)
+
+// PDF renders a single label as a one page PDF sized to the label.
+func PDF(w io.Writer, l Label, symbology string) error {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: labelWidth, Ht: labelHeight},
+	})
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	if err := drawPDFLabel(pdf, l, symbology, 0, 0, "code0"); err != nil {
+		return err
+	}
+
+	return pdf.Output(w)
+}
+
+// Sheet renders labels on as many A4 pages as needed.
+func Sheet(w io.Writer, labels []Label, symbology string) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+
+	perPage := sheetColumns * sheetRows
+	for i, l := range labels {
+		if i%perPage == 0 {
+			pdf.AddPage()
+		}
+		cell := i % perPage
+		x := float64(cell%sheetColumns) * labelWidth
+		y := sheetTop + float64(cell/sheetColumns)*labelHeight
+
+		if err := drawPDFLabel(pdf, l, symbology, x, y, fmt.Sprintf("code%d", i)); err != nil {
+			return err
+		}
+	}
+
+	if len(labels) == 0 {
+		pdf.AddPage()
+	}
+
+	return pdf.Output(w)
+}
+
+// drawPDFLabel draws one label with its top left corner at x, y. Every
+// barcode image is registered under a unique name.
+func drawPDFLabel(pdf *fpdf.Fpdf, l Label, symbology string, x, y float64, name string) error {
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	inner := labelWidth - 2*labelMargin
+
+	var codeW, codeH, codeX, codeY, textW float64
+	var pxW, pxH int
+	switch symbology {
+	case Code128:
+		codeW, codeH = inner, 14
+		codeX, codeY = x+labelMargin, y+labelMargin+13
+		textW = inner
+		pxW, pxH = 800, 160
+	case QR:
+		codeH = labelHeight - 2*labelMargin
+		codeW = codeH
+		codeX, codeY = x+labelWidth-labelMargin-codeW, y+labelMargin
+		textW = inner - codeW - labelMargin
+		pxW, pxH = 400, 400
+	default:
+		return ErrUnknownSymbology
+	}
+
+	img, err := encode(l.Code, symbology, pxW, pxH)
+	if err != nil {
+		return err
+	}
+	r, err := pngBytes(img)
+	if err != nil {
+		return err
+	}
+	opts := fpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader(name, opts, r)
+	pdf.ImageOptions(name, codeX, codeY, codeW, codeH, false, opts, 0, "")
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetXY(x+labelMargin, y+labelMargin)
+	pdf.CellFormat(textW, 5, tr(fit(pdf, l.Name, textW)), "", 2, "L", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(textW, 5, formatPrice(l.Price), "", 2, "L", false, 0, "")
+
+	if symbology == Code128 {
+		pdf.SetFont("Courier", "", 6)
+		pdf.SetXY(x+labelMargin, codeY+codeH+0.5)
+		pdf.CellFormat(textW, 3, tr(fit(pdf, l.Code, textW)), "", 0, "C", false, 0, "")
+	}
+
+	return pdf.Error()
+}
+
+// fit shortens s until it fits into width with the current font.
+func fit(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdf.GetStringWidth(string(r)+"...") > width {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "..."
+}
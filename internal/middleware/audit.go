@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+)
+
+// Audit attaches the origin of a request to its context so the changes it
+// makes are recorded in the audit log along with them. The actor is whoever
+// the X-Actor header names.
+func Audit() web.Middleware {
+
+	// This is the actual middleware function to be executed
+	f := func(before web.Handler) web.Handler {
+
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			o := audit.Origin{
+				Actor:     r.Header.Get("X-Actor"),
+				RequestID: r.Header.Get("X-Request-ID"),
+			}
+
+			return before(w, r.WithContext(audit.NewContext(r.Context(), o)))
+		}
+
+		return h
+	}
+
+	return f
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+)
+
+// Cache lets browsers and shared caches keep successful responses for maxAge
+// and serve them stale for as long again while they revalidate. Every such
+// response carries an ETag of its body, so revalidating a response that has
+// not changed gets a 304 Not Modified without the body.
+func Cache(maxAge time.Duration) web.Middleware {
+	control := fmt.Sprintf("public, max-age=%d, stale-while-revalidate=%d", int(maxAge.Seconds()), int(maxAge.Seconds()))
+
+	// This is the actual middleware function to be executed
+	f := func(before web.Handler) web.Handler {
+
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			buf := buffer{header: make(http.Header)}
+			if err := before(&buf, r); err != nil {
+				return err
+			}
+
+			for k, v := range buf.header {
+				w.Header()[k] = v
+			}
+			if buf.status == 0 {
+				buf.status = http.StatusOK
+			}
+
+			if buf.status == http.StatusOK {
+				sum := sha256.Sum256(buf.body.Bytes())
+				etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Cache-Control", control)
+
+				if matchesETag(r.Header.Get("If-None-Match"), etag) {
+					w.WriteHeader(http.StatusNotModified)
+					return nil
+				}
+			}
+
+			w.WriteHeader(buf.status)
+			if _, err := w.Write(buf.body.Bytes()); err != nil {
+				return fmt.Errorf("writing to client: %w", err)
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return f
+}
+
+// matchesETag reports whether an If-None-Match header names the given ETag.
+func matchesETag(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "W/")
+		if v == etag || v == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// buffer holds on to a response until the handler is done with it.
+type buffer struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *buffer) Header() http.Header {
+	return b.header
+}
+
+func (b *buffer) WriteHeader(status int) {
+	if b.status == 0 {
+		b.status = status
+	}
+}
+
+func (b *buffer) Write(p []byte) (int, error) {
+	if b.status == 0 {
+		b.status = http.StatusOK
+	}
+	return b.body.Write(p)
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+)
+
+// CSRFField is the form field the CSRF token is sent back in.
+const CSRFField = "csrf_token"
+
+// csrfCookie is the cookie holding the CSRF token of a browser.
+const csrfCookie = "csrf_token"
+
+// ErrCSRF is returned for form posts without the token of the browser.
+var ErrCSRF = errors.New("form has expired or did not come from this site, please reload it and try again")
+
+// csrfKey is the context key the token of a request is kept under.
+type csrfKey struct{}
+
+// CSRF protects the form posts of a browser against cross-site request
+// forgery with a double submit token. Every browser is given a random token
+// in a cookie which the pages put in their forms as CSRFField. Requests other
+// than GET and HEAD must send the same token back in the form.
+func CSRF(path string) web.Middleware {
+
+	// This is the actual middleware function to be executed
+	f := func(before web.Handler) web.Handler {
+
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			var token string
+			if c, err := r.Cookie(csrfCookie); err == nil && len(c.Value) == 43 {
+				token = c.Value
+			}
+
+			switch r.Method {
+			case http.MethodGet, http.MethodHead:
+				if token == "" {
+					b := make([]byte, 32)
+					if _, err := rand.Read(b); err != nil {
+						return fmt.Errorf("generating csrf token: %w", err)
+					}
+					token = base64.RawURLEncoding.EncodeToString(b)
+
+					http.SetCookie(w, &http.Cookie{
+						Name:     csrfCookie,
+						Value:    token,
+						Path:     path,
+						HttpOnly: true,
+						SameSite: http.SameSiteStrictMode,
+					})
+				}
+			default:
+				sent := r.PostFormValue(CSRFField)
+				if token == "" || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+					return web.NewRequestError(ErrCSRF, http.StatusForbidden)
+				}
+			}
+
+			ctx := context.WithValue(r.Context(), csrfKey{}, token)
+			return before(w, r.WithContext(ctx))
+		}
+
+		return h
+	}
+
+	return f
+}
+
+// CSRFToken gives the CSRF token forms of the request must include.
+func CSRFToken(r *http.Request) string {
+	token, _ := r.Context().Value(csrfKey{}).(string)
+	return token
+}
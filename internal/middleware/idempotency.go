@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/idempotency"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+	"github.com/jmoiron/sqlx"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key header we accept.
+const maxIdempotencyKey = 255
+
+// idempotencyTimeout bounds recording the outcome of a request once its
+// handler returned.
+const idempotencyTimeout = 5 * time.Second
+
+// completeRetry is how long to wait before trying again to store the outcome
+// of a request.
+const completeRetry = 250 * time.Millisecond
+
+// Idempotency makes a handler safe to retry. Requests carrying an
+// Idempotency-Key header are only processed once, retries with the same key
+// and body get the original response replayed. Keys are scoped by method and
+// path so clients only need them unique per endpoint. Keys are forgotten
+// after ttl.
+func Idempotency(db *sqlx.DB, log *log.Logger, ttl time.Duration) web.Middleware {
+
+	// This is the actual middleware function to be executed
+	f := func(before web.Handler) web.Handler {
+
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				return before(w, r)
+			}
+			if len(key) > maxIdempotencyKey {
+				return web.NewRequestError(fmt.Errorf("idempotency key must be at most %d characters", maxIdempotencyKey), http.StatusBadRequest)
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return fmt.Errorf("reading request body: %w", err)
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			scope := r.Method + " " + r.URL.Path
+			holder := uuid.New().String()
+			sum := sha256.Sum256(body)
+			fingerprint := hex.EncodeToString(sum[:])
+
+			rec, err := idempotency.Reserve(r.Context(), db, scope, key, holder, fingerprint, time.Now())
+			if err != nil {
+				switch {
+				case errors.Is(err, idempotency.ErrKeyReused):
+					return web.NewRequestError(err, http.StatusUnprocessableEntity)
+				case errors.Is(err, idempotency.ErrInProgress):
+					return web.NewRequestError(err, http.StatusConflict)
+				default:
+					return err
+				}
+			}
+
+			// The request was seen before so replay what we sent back then.
+			if rec != nil {
+				w.Header().Set("content-type", rec.ContentType)
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(rec.Status)
+				if _, err := w.Write(rec.Body); err != nil {
+					return fmt.Errorf("writing to client: %w", err)
+				}
+				return nil
+			}
+
+			// What the handler did is recorded even when the client went away
+			// and canceled the request context meanwhile.
+			ctx, cancel := context.WithTimeout(context.Background(), idempotencyTimeout)
+			defer cancel()
+
+			rw := recorder{ResponseWriter: w}
+			if err := before(&rw, r); err != nil {
+
+				// Failed requests are not remembered so the client can retry.
+				if err := idempotency.Release(ctx, db, scope, key, holder); err != nil {
+					return err
+				}
+				return err
+			}
+
+			if rw.status == 0 {
+				rw.status = http.StatusOK
+			}
+
+			// The response was sent already so failing to store it is only
+			// logged. A key left unstored is freed when its lease runs out and
+			// a retry would run the handler a second time, so storing it is
+			// retried until idempotencyTimeout first.
+			for {
+				err := idempotency.Complete(ctx, db, scope, key, holder, rw.status, rw.Header().Get("content-type"), rw.body.Bytes(), time.Now().Add(ttl))
+				if err == nil {
+					return nil
+				}
+				if errors.Is(err, idempotency.ErrLeaseLost) {
+					log.Printf("Error : idempotency key %q : %v", key, err)
+					return nil
+				}
+
+				select {
+				case <-time.After(completeRetry):
+				case <-ctx.Done():
+					log.Printf("Error : idempotency key %q : %v", key, err)
+					return nil
+				}
+			}
+		}
+
+		return h
+	}
+
+	return f
+}
+
+// recorder passes a response through to the client while keeping a copy of
+// its status and body.
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/platform/web"
+)
+
+// RequestID makes sure every request carries an X-Request-ID header. Clients
+// may send their own, otherwise one is generated. The ID is echoed in the
+// response so clients can refer to the request.
+func RequestID() web.Middleware {
+
+	// This is the actual middleware function to be executed
+	f := func(before web.Handler) web.Handler {
+
+		h := func(w http.ResponseWriter, r *http.Request) error {
+			id := r.Header.Get("X-Request-ID")
+			if id == "" {
+				id = uuid.New().String()
+				r.Header.Set("X-Request-ID", id)
+			}
+			w.Header().Set("X-Request-ID", id)
+
+			return before(w, r)
+		}
+
+		return h
+	}
+
+	return f
+}
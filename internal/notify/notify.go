@@ -0,0 +1,89 @@
+// Package notify sends messages to customers and staff without holding up
+// the requests that trigger them.
+package notify
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Message is a plain text message to one or more recipients.
+type Message struct {
+	To      []string
+	Subject string
+	Body    string
+}
+
+// Notifier knows how to deliver a message.
+type Notifier interface {
+	Send(ctx context.Context, m Message) error
+}
+
+// Queue delivers messages in the background. Enqueueing never blocks, when
+// the queue is full the message is dropped and logged.
+type Queue struct {
+	notifier Notifier
+	log      *log.Logger
+	messages chan Message
+	attempts int
+	backoff  time.Duration
+}
+
+// NewQueue constructs a Queue holding up to size messages that tries every
+// message three times before giving up on it.
+func NewQueue(n Notifier, log *log.Logger, size int) *Queue {
+	return &Queue{
+		notifier: n,
+		log:      log,
+		messages: make(chan Message, size),
+		attempts: 3,
+		backoff:  time.Second,
+	}
+}
+
+// Enqueue schedules a message for delivery. It reports false if the queue is
+// full.
+func (q *Queue) Enqueue(m Message) bool {
+	select {
+	case q.messages <- m:
+		return true
+	default:
+		q.log.Printf("notify : queue full, dropping %q to %v", m.Subject, m.To)
+		return false
+	}
+}
+
+// Run delivers queued messages until the context is canceled.
+func (q *Queue) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m := <-q.messages:
+			q.deliver(ctx, m)
+		}
+	}
+}
+
+// deliver sends a message, retrying with a growing delay on failure.
+func (q *Queue) deliver(ctx context.Context, m Message) {
+	delay := q.backoff
+	for attempt := 1; ; attempt++ {
+		err := q.notifier.Send(ctx, m)
+		if err == nil {
+			return
+		}
+		if attempt == q.attempts {
+			q.log.Printf("notify : giving up on %q to %v after %d attempts : %v", m.Subject, m.To, attempt, err)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+			delay *= 2
+		}
+	}
+}
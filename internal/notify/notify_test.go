@@ -0,0 +1,168 @@
+package notify_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/notify"
+)
+
+func TestSMTP(t *testing.T) {
+	srv := startFakeSMTP(t)
+
+	m, err := notify.Render(notify.TemplateLowStock, []string{"staff@example.com"}, map[string]interface{}{
+		"ID":        "fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01",
+		"Name":      "Comic Books",
+		"Remaining": 2,
+		"Threshold": 3,
+		"Sold":      40,
+	})
+	if err != nil {
+		t.Fatalf("rendering message: %v", err)
+	}
+	if exp := "Low stock: Comic Books"; m.Subject != exp {
+		t.Fatalf("expected subject %q, got %q", exp, m.Subject)
+	}
+
+	s := notify.SMTP{Addr: srv.addr, From: "shop@example.com"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.Send(ctx, m); err != nil {
+		t.Fatalf("sending message: %v", err)
+	}
+
+	select {
+	case mail := <-srv.mails:
+		if mail.from != "shop@example.com" {
+			t.Fatalf("expected sender shop@example.com, got %s", mail.from)
+		}
+		if len(mail.to) != 1 || mail.to[0] != "staff@example.com" {
+			t.Fatalf("expected recipient staff@example.com, got %v", mail.to)
+		}
+		if !strings.Contains(mail.data, "Subject: Low stock: Comic Books") {
+			t.Fatalf("expected subject header in message:\n%s", mail.data)
+		}
+		if !strings.Contains(mail.data, "Remaining: 2 (threshold 3)") {
+			t.Fatalf("expected rendered body in message:\n%s", mail.data)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake smtp server did not receive a message")
+	}
+}
+
+func TestQueue(t *testing.T) {
+	sent := make(chan notify.Message, 1)
+	q := notify.NewQueue(notifierFunc(func(ctx context.Context, m notify.Message) error {
+		sent <- m
+		return nil
+	}), log.New(io.Discard, "", 0), 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go q.Run(ctx)
+
+	if !q.Enqueue(notify.Message{Subject: "hello"}) {
+		t.Fatal("expected message to be queued")
+	}
+
+	select {
+	case m := <-sent:
+		if m.Subject != "hello" {
+			t.Fatalf("expected subject hello, got %q", m.Subject)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("queued message was not delivered")
+	}
+}
+
+type notifierFunc func(context.Context, notify.Message) error
+
+func (f notifierFunc) Send(ctx context.Context, m notify.Message) error {
+	return f(ctx, m)
+}
+
+type fakeMail struct {
+	from string
+	to   []string
+	data string
+}
+
+type fakeSMTP struct {
+	addr  string
+	mails chan fakeMail
+}
+
+// startFakeSMTP runs a bare bones SMTP server accepting any message.
+func startFakeSMTP(t *testing.T) *fakeSMTP {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	srv := fakeSMTP{addr: ln.Addr().String(), mails: make(chan fakeMail, 1)}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+
+		var mail fakeMail
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch upper := strings.ToUpper(cmd); {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				reply("250 fake")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				mail.from = strings.Trim(cmd[len("MAIL FROM:"):], "<> ")
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				mail.to = append(mail.to, strings.Trim(cmd[len("RCPT TO:"):], "<> "))
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 go ahead")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				mail.data = data.String()
+				reply("250 OK")
+				srv.mails <- mail
+			case upper == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return &srv
+}
@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Names of the built in message templates.
+const (
+	TemplateReceipt  = "receipt"
+	TemplateLowStock = "low_stock"
+)
+
+// templates hold the subject on the first line and the body after it.
+var templates = template.Must(template.New("").Parse(`
+{{define "receipt"}}Your receipt from {{.Shop}}
+Thank you for your purchase! Here is your receipt:
+
+{{.Receipt}}
+{{end}}
+
+{{define "low_stock"}}Low stock: {{.Name}}
+{{.Name}} is about to sell out.
+
+Remaining: {{.Remaining}} (threshold {{.Threshold}})
+Sold:      {{.Sold}}
+Product:   {{.ID}}
+{{end}}
+`))
+
+// Render builds a message from a template. The first line of the rendered
+// template becomes the subject.
+func Render(name string, to []string, data interface{}) (Message, error) {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return Message{}, fmt.Errorf("rendering %s: %w", name, err)
+	}
+
+	subject, body, _ := strings.Cut(buf.String(), "\n")
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject),
+		Body:    body,
+	}, nil
+}
@@ -0,0 +1,171 @@
+// Package openapi builds OpenAPI 3 documents describing an API. Schemas are
+// derived from the Go types the API sends and receives, reading the json tags
+// for the property names and the validate tags for their constraints.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Document is an OpenAPI 3 document.
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	types    map[reflect.Type]string
+	errorRef *Schema
+}
+
+// Info describes the API as a whole.
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the schemas operations refer to.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation is what a method of a path does.
+type Operation struct {
+	Summary     string              `json:"summary"`
+	Tags        []string            `json:"tags,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter is a path or query parameter of an Operation.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required,omitempty"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody is the body an Operation takes.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response is a response of an Operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType gives the schema of a body in one content type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route describes an operation to add to a Document. Request and Response
+// are values of the types of the JSON bodies, nil when there is none.
+// Requests uploading a multipart form name its Files instead and responses
+// that are not JSON give their ContentType. Query lists the names of the
+// query parameters.
+type Route struct {
+	Summary     string
+	Tag         string
+	Query       []string
+	Request     interface{}
+	Files       []string
+	Response    interface{}
+	Status      int
+	ContentType string
+}
+
+// New starts a Document. Every operation added to it answers errors with a
+// body of the type of errorBody.
+func New(title, version string, errorBody interface{}) *Document {
+	d := Document{
+		OpenAPI:    "3.0.3",
+		Info:       Info{Title: title, Version: version},
+		Paths:      map[string]map[string]*Operation{},
+		Components: Components{Schemas: map[string]*Schema{}},
+		types:      map[reflect.Type]string{},
+	}
+	d.errorRef = d.Schema(errorBody)
+
+	return &d
+}
+
+// pathParam finds the parameters in a path like /products/{id}.
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Add describes what method does on path.
+func (d *Document) Add(method, path string, r Route) {
+	op := Operation{
+		Summary:   r.Summary,
+		Responses: map[string]Response{},
+	}
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+	}
+	for _, q := range r.Query {
+		op.Parameters = append(op.Parameters, Parameter{Name: q, In: "query", Schema: &Schema{Type: "string"}})
+	}
+
+	switch {
+	case r.Request != nil:
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"application/json": {Schema: d.Schema(r.Request)}},
+		}
+	case len(r.Files) > 0:
+		form := Schema{Type: "object", Properties: map[string]*Schema{}, Required: r.Files}
+		for _, f := range r.Files {
+			form.Properties[f] = &Schema{Type: "string", Format: "binary"}
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{"multipart/form-data": {Schema: &form}},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	resp := Response{Description: http.StatusText(status)}
+	switch {
+	case r.ContentType != "":
+		resp.Content = map[string]MediaType{r.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}}}
+	case r.Response != nil:
+		resp.Content = map[string]MediaType{"application/json": {Schema: d.Schema(r.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = resp
+
+	op.Responses["default"] = Response{
+		Description: "Error",
+		Content:     map[string]MediaType{"application/json": {Schema: d.errorRef}},
+	}
+
+	if d.Paths[path] == nil {
+		d.Paths[path] = map[string]*Operation{}
+	}
+	key := strings.ToLower(method)
+	if _, ok := d.Paths[path][key]; ok {
+		panic(fmt.Sprintf("openapi: %s %s added twice", method, path))
+	}
+	d.Paths[path][key] = &op
+}
+
+// Has tells whether the Document describes method on path.
+func (d *Document) Has(method, path string) bool {
+	_, ok := d.Paths[path][strings.ToLower(method)]
+	return ok
+}
@@ -0,0 +1,242 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema describes a JSON value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+)
+
+// Schema gives the schema of the type of v. Named struct types are added to
+// the components of the Document and referred to.
+func (d *Document) Schema(v interface{}) *Schema {
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := d.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + d.component(t)}
+	}
+
+	return &Schema{}
+}
+
+// component adds a named struct type to the schemas of the Document, giving
+// the name it is kept under. Types of different packages sharing a name are
+// told apart by the name of their package.
+func (d *Document) component(t reflect.Type) string {
+	if name, ok := d.types[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := d.Components.Schemas[name]; taken {
+		pkg := t.PkgPath()
+		pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	// The name is taken before the fields are described so types referring
+	// to themselves end.
+	d.types[t] = name
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.object(t)
+
+	return name
+}
+
+// object describes the fields of a struct the way encoding/json sends them.
+func (d *Document) object(t reflect.Type) *Schema {
+	s := Schema{Type: "object", Properties: map[string]*Schema{}}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := d.object(ft)
+				for n, p := range embedded.Properties {
+					s.Properties[n] = p
+				}
+				s.Required = append(s.Required, embedded.Required...)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		p := d.schema(f.Type)
+		if constrain(p, f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = p
+	}
+
+	return &s
+}
+
+// constrain adds the rules of a validate tag to a schema, reporting whether
+// they make the value required. Rules after dive are about the items of a
+// slice.
+func constrain(s *Schema, tag string) bool {
+	if tag == "" {
+		return false
+	}
+
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		if rule == "dive" {
+			if s.Items != nil {
+				constrain(s.Items, strings.Join(rules[i+1:], ","))
+			}
+			rules = rules[:i]
+			break
+		}
+	}
+
+	var required bool
+	for _, rule := range rules {
+		name, arg, _ := strings.Cut(rule, "=")
+
+		// Rules cannot be added next to a reference.
+		if s.Ref != "" && name != "required" {
+			continue
+		}
+
+		switch name {
+		case "required":
+			required = true
+		case "uuid", "email", "url", "uri":
+			s.Format = name
+		case "oneof":
+			s.Enum = strings.Fields(arg)
+		case "gte", "gt", "lte", "lt":
+			n, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				continue
+			}
+			switch name {
+			case "gte", "gt":
+				s.Minimum = &n
+				s.ExclusiveMinimum = name == "gt"
+			default:
+				s.Maximum = &n
+				s.ExclusiveMaximum = name == "lt"
+			}
+		case "min", "max", "len":
+			n, err := strconv.Atoi(arg)
+			if err != nil {
+				continue
+			}
+			bound(s, name, n)
+		}
+	}
+
+	return required
+}
+
+// bound adds a min, max or len rule, which the validator applies to the
+// length of strings and slices and to the value of numbers.
+func bound(s *Schema, rule string, n int) {
+	lower := rule == "min" || rule == "len"
+	upper := rule == "max" || rule == "len"
+
+	switch s.Type {
+	case "string":
+		if lower {
+			s.MinLength = &n
+		}
+		if upper {
+			s.MaxLength = &n
+		}
+	case "array":
+		if lower {
+			s.MinItems = &n
+		}
+		if upper {
+			s.MaxItems = &n
+		}
+	case "integer", "number":
+		f := float64(n)
+		if lower {
+			s.Minimum = &f
+		}
+		if upper {
+			s.Maximum = &f
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package storage provides a minimal blob store abstraction with a local
+// filesystem and an S3 compatible implementation.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no object exists under a key.
+var ErrNotFound = errors.New("object not found")
+
+// Storage knows how to save, read and remove objects identified by a key.
+// Keys are slash separated paths such as "products/1/image.jpg".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
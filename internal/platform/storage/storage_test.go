@@ -0,0 +1,115 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ivan-sabo/garagesale/internal/platform/storage"
+)
+
+func TestLocal(t *testing.T) {
+	testStorage(t, &storage.Local{Dir: t.TempDir()})
+}
+
+func TestS3(t *testing.T) {
+	srv := httptest.NewServer(newFakeS3(t, "garagesale", "test-key"))
+	defer srv.Close()
+
+	testStorage(t, &storage.S3{
+		Endpoint:  srv.URL,
+		Region:    "us-east-1",
+		Bucket:    "garagesale",
+		AccessKey: "test-key",
+		SecretKey: "test-secret",
+		Client:    srv.Client(),
+	})
+}
+
+func testStorage(t *testing.T, st storage.Storage) {
+	t.Helper()
+	ctx := context.Background()
+
+	const key = "products/42/photo one.jpg"
+
+	if err := st.Put(ctx, key, strings.NewReader("pixels"), "image/jpeg"); err != nil {
+		t.Fatalf("putting object: %v", err)
+	}
+
+	r, err := st.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("getting object: %v", err)
+	}
+	got, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("reading object: %v", err)
+	}
+	if string(got) != "pixels" {
+		t.Fatalf("expected object content %q, got %q", "pixels", got)
+	}
+
+	if err := st.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting object: %v", err)
+	}
+	if _, err := st.Get(ctx, key); err != storage.ErrNotFound {
+		t.Fatalf("expected %v after delete, got %v", storage.ErrNotFound, err)
+	}
+	if err := st.Delete(ctx, key); err != nil {
+		t.Fatalf("deleting missing object: %v", err)
+	}
+}
+
+// newFakeS3 is a stand-in for an S3 service holding a single bucket. It checks
+// requests carry a SigV4 authorization and a correct payload hash.
+func newFakeS3(t *testing.T, bucket, accessKey string) http.Handler {
+	var mu sync.Mutex
+	objects := map[string][]byte{}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+accessKey+"/") {
+			t.Errorf("request without SigV4 authorization: %q", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(sum[:]) {
+			t.Errorf("payload hash mismatch: %s", got)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		prefix := "/" + bucket + "/"
+		if !strings.HasPrefix(r.URL.EscapedPath(), prefix) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		key := strings.TrimPrefix(r.URL.EscapedPath(), prefix)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			objects[key] = body
+		case http.MethodGet:
+			obj, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(obj)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
@@ -52,6 +52,13 @@ func Decode(r *http.Request, val interface{}) error {
 		return NewRequestError(err, http.StatusBadRequest)
 	}
 
+	return Validate(val)
+}
+
+// Validate checks the validation tags of a struct value. Failed checks are
+// reported as an *Error listing a FieldError for every field, named after its
+// JSON tag.
+func Validate(val interface{}) error {
 	if err := validate.Struct(val); err != nil {
 
 		// Use a type assertrion to get the real error values
@@ -26,9 +26,12 @@ func NewApp(l *log.Logger, mw ...Middleware) *App {
 	}
 }
 
-// Handle connects a method and URL pattern to a particular application handler
-func (a *App) Handle(method, pattern string, h Handler) {
+// Handle connects a method and URL pattern to a particular application
+// handler. Any route specific middleware runs after the application wide
+// middleware.
+func (a *App) Handle(method, pattern string, h Handler, mw ...Middleware) {
 
+	h = wrapMiddleware(mw, h)
 	h = wrapMiddleware(a.mw, h)
 
 	fn := func(w http.ResponseWriter, r *http.Request) {
@@ -42,3 +45,10 @@ func (a *App) Handle(method, pattern string, h Handler) {
 func (a *App) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	a.mux.ServeHTTP(w, r)
 }
+
+// Walk calls fn with the method and URL pattern of every registered route.
+func (a *App) Walk(fn func(method, pattern string) error) error {
+	return chi.Walk(a.mux, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		return fn(method, route)
+	})
+}
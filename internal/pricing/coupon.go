@@ -0,0 +1,111 @@
+package pricing
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Postgres error codes for violated constraints.
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// ListCoupons returns all coupons of an event
+func ListCoupons(ctx context.Context, db *sqlx.DB, eventID string) ([]Coupon, error) {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	list := []Coupon{}
+
+	const q = `SELECT * FROM coupons WHERE event_id = $1 ORDER BY date_created`
+
+	if err := db.SelectContext(ctx, &list, q, eventID); err != nil {
+		return nil, fmt.Errorf("selecting coupons: %w", err)
+	}
+
+	return list, nil
+}
+
+// CreateCoupon makes a new Coupon at an event. Codes are case insensitive,
+// stored in upper case and unique within the event.
+func CreateCoupon(ctx context.Context, db *sqlx.DB, eventID string, nc NewCoupon, now time.Time) (*Coupon, error) {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	c := Coupon{
+		ID:          uuid.New().String(),
+		EventID:     eventID,
+		Code:        strings.ToUpper(strings.TrimSpace(nc.Code)),
+		Kind:        nc.Kind,
+		Amount:      nc.Amount,
+		MaxUses:     nc.MaxUses,
+		ExpiresAt:   nc.ExpiresAt,
+		DateCreated: now.UTC(),
+	}
+
+	if err := (Discount{Kind: c.Kind, Amount: c.Amount}).validate(); err != nil {
+		return nil, err
+	}
+
+	const q = `INSERT INTO coupons
+	(coupon_id, event_id, code, kind, amount, max_uses, uses, expires_at, date_created)
+	VALUES($1, $2, $3, $4, $5, $6, 0, $7, $8)`
+
+	if _, err := db.ExecContext(ctx, q, c.ID, c.EventID, c.Code, c.Kind, c.Amount, c.MaxUses, c.ExpiresAt, c.DateCreated); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) {
+			switch pqErr.Code {
+			case uniqueViolation:
+				return nil, ErrCouponCodeExists
+			case foreignKeyViolation:
+				return nil, ErrEventNotFound
+			}
+		}
+		return nil, fmt.Errorf("inserting coupon: %w", err)
+	}
+
+	return &c, nil
+}
+
+// Redeem uses up one use of the coupon of an event identified by code. It is
+// meant to be called inside the transaction that records the sale so a coupon
+// is never used more often than allowed.
+func Redeem(ctx context.Context, tx sqlx.ExtContext, eventID, code string, now time.Time) (*Coupon, error) {
+	var c Coupon
+
+	const q = `SELECT * FROM coupons WHERE event_id = $1 AND code = $2 FOR UPDATE`
+
+	if err := sqlx.GetContext(ctx, tx, &c, q, eventID, strings.ToUpper(strings.TrimSpace(code))); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("selecting coupon: %w", err)
+	}
+
+	if c.ExpiresAt != nil && !now.Before(*c.ExpiresAt) {
+		return nil, ErrCouponExpired
+	}
+	if c.MaxUses > 0 && c.Uses >= c.MaxUses {
+		return nil, ErrCouponExhausted
+	}
+
+	const u = `UPDATE coupons SET uses = uses + 1 WHERE coupon_id = $1`
+
+	if _, err := tx.ExecContext(ctx, u, c.ID); err != nil {
+		return nil, fmt.Errorf("redeeming coupon: %w", err)
+	}
+	c.Uses++
+
+	return &c, nil
+}
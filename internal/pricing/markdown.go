@@ -0,0 +1,124 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ListMarkdowns returns all scheduled markdowns of an event
+func ListMarkdowns(ctx context.Context, db *sqlx.DB, eventID string) ([]Markdown, error) {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	list := []Markdown{}
+
+	const q = `SELECT * FROM markdowns WHERE event_id = $1 ORDER BY starts_at`
+
+	if err := db.SelectContext(ctx, &list, q, eventID); err != nil {
+		return nil, fmt.Errorf("selecting markdowns: %w", err)
+	}
+
+	return list, nil
+}
+
+// CreateMarkdown schedules a new Markdown at an event. A markdown of a single
+// product must name a product of the event. One that ends must end after it
+// starts and not be over already.
+func CreateMarkdown(ctx context.Context, db *sqlx.DB, eventID string, nm NewMarkdown, now time.Time) (*Markdown, error) {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return nil, ErrInvalidID
+	}
+	if nm.EndsAt != nil && (!nm.EndsAt.After(nm.StartsAt) || !nm.EndsAt.After(now)) {
+		return nil, ErrMarkdownTimes
+	}
+
+	m := Markdown{
+		ID:          uuid.New().String(),
+		EventID:     eventID,
+		ProductID:   nm.ProductID,
+		Category:    nm.Category,
+		Kind:        nm.Kind,
+		Amount:      nm.Amount,
+		StartsAt:    nm.StartsAt.UTC(),
+		DateCreated: now.UTC(),
+	}
+	if nm.EndsAt != nil {
+		endsAt := nm.EndsAt.UTC()
+		m.EndsAt = &endsAt
+	}
+
+	if err := (Discount{Kind: m.Kind, Amount: m.Amount}).validate(); err != nil {
+		return nil, err
+	}
+
+	if m.ProductID != nil {
+		var n int
+
+		const q = `SELECT COUNT(*) FROM products WHERE product_id = $1 AND event_id = $2`
+
+		if err := db.GetContext(ctx, &n, q, *m.ProductID, eventID); err != nil {
+			return nil, fmt.Errorf("selecting product: %w", err)
+		}
+		if n == 0 {
+			return nil, ErrProductNotFound
+		}
+	}
+
+	const q = `INSERT INTO markdowns
+	(markdown_id, event_id, product_id, category, kind, amount, starts_at, ends_at, date_created)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	if _, err := db.ExecContext(ctx, q, m.ID, m.EventID, m.ProductID, m.Category, m.Kind, m.Amount, m.StartsAt, m.EndsAt, m.DateCreated); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("inserting markdown: %w", err)
+	}
+
+	return &m, nil
+}
+
+// DeleteMarkdown removes the markdown of an event identified by a given ID.
+func DeleteMarkdown(ctx context.Context, db *sqlx.DB, eventID, id string) error {
+	for _, v := range []string{eventID, id} {
+		if _, err := uuid.Parse(v); err != nil {
+			return ErrInvalidID
+		}
+	}
+
+	const q = `DELETE FROM markdowns WHERE markdown_id = $1 AND event_id = $2`
+
+	if _, err := db.ExecContext(ctx, q, id, eventID); err != nil {
+		return fmt.Errorf("deleting markdown (id: %s): %w", id, err)
+	}
+
+	return nil
+}
+
+// ActiveMarkdowns gives the markdowns of an event that apply to a product at
+// a given time.
+func ActiveMarkdowns(ctx context.Context, db sqlx.QueryerContext, eventID, productID, category string, now time.Time) ([]Markdown, error) {
+	list := []Markdown{}
+
+	const q = `SELECT * FROM markdowns
+	WHERE event_id = $4 AND starts_at <= $3 AND (ends_at IS NULL OR ends_at > $3)
+	AND (
+		product_id = $1
+		OR (product_id IS NULL AND category = $2)
+		OR (product_id IS NULL AND category = '')
+	)`
+
+	if err := sqlx.SelectContext(ctx, db, &list, q, productID, category, now, eventID); err != nil {
+		return nil, fmt.Errorf("selecting active markdowns: %w", err)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,75 @@
+package pricing
+
+import "time"
+
+// Kinds of discount that can be applied to a price.
+const (
+	KindPercent = "percent"
+	KindFixed   = "fixed"
+)
+
+// Discount describes a reduction of a price. For KindPercent the Amount is a
+// percentage between 0 and 100, for KindFixed it is an absolute amount in the
+// same unit as product cost.
+type Discount struct {
+	Kind   string `db:"kind" json:"kind"`
+	Amount int    `db:"amount" json:"amount"`
+}
+
+// Coupon is a discount code customers can redeem when buying something at an
+// event. A MaxUses of 0 means the coupon can be used an unlimited number of
+// times.
+type Coupon struct {
+	ID          string     `db:"coupon_id" json:"id"`
+	EventID     string     `db:"event_id" json:"event_id"`
+	Code        string     `db:"code" json:"code"`
+	Kind        string     `db:"kind" json:"kind"`
+	Amount      int        `db:"amount" json:"amount"`
+	MaxUses     int        `db:"max_uses" json:"max_uses"`
+	Uses        int        `db:"uses" json:"uses"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	DateCreated time.Time  `db:"date_created" json:"date_created"`
+}
+
+// NewCoupon is what we require from clients to make a new Coupon.
+type NewCoupon struct {
+	Code      string     `json:"code" validate:"required"`
+	Kind      string     `json:"kind" validate:"required,oneof=percent fixed"`
+	Amount    int        `json:"amount" validate:"gte=0"`
+	MaxUses   int        `json:"max_uses" validate:"gte=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Markdown is a time based price reduction at an event. It applies to a
+// single product when ProductID is set, to every product of a category when
+// Category is set and to everything when neither is set. A nil EndsAt means
+// the markdown never ends.
+type Markdown struct {
+	ID          string     `db:"markdown_id" json:"id"`
+	EventID     string     `db:"event_id" json:"event_id"`
+	ProductID   *string    `db:"product_id" json:"product_id,omitempty"`
+	Category    string     `db:"category" json:"category,omitempty"`
+	Kind        string     `db:"kind" json:"kind"`
+	Amount      int        `db:"amount" json:"amount"`
+	StartsAt    time.Time  `db:"starts_at" json:"starts_at"`
+	EndsAt      *time.Time `db:"ends_at" json:"ends_at,omitempty"`
+	DateCreated time.Time  `db:"date_created" json:"date_created"`
+}
+
+// NewMarkdown is what we require from clients to schedule a new Markdown.
+type NewMarkdown struct {
+	ProductID *string    `json:"product_id" validate:"omitempty,uuid"`
+	Category  string     `json:"category"`
+	Kind      string     `json:"kind" validate:"required,oneof=percent fixed"`
+	Amount    int        `json:"amount" validate:"gte=0"`
+	StartsAt  time.Time  `json:"starts_at" validate:"required"`
+	EndsAt    *time.Time `json:"ends_at"`
+}
+
+// Price is the breakdown of what a customer pays for some amount of a
+// product.
+type Price struct {
+	Gross    int `json:"gross"`
+	Discount int `json:"discount"`
+	Paid     int `json:"paid"`
+}
@@ -0,0 +1,67 @@
+package pricing
+
+import "errors"
+
+// Predefined errors for known failure scenarios
+var (
+	ErrInvalidID        = errors.New("id provided was not a valid UUID")
+	ErrInvalidDiscount  = errors.New("percentage discounts must be between 0 and 100")
+	ErrCouponNotFound   = errors.New("coupon not found")
+	ErrCouponExpired    = errors.New("coupon has expired")
+	ErrCouponExhausted  = errors.New("coupon has no uses left")
+	ErrCouponCodeExists = errors.New("coupon code already exists")
+	ErrMarkdownTimes    = errors.New("markdown must end in the future and after it starts")
+	ErrEventNotFound    = errors.New("event not found")
+	ErrProductNotFound  = errors.New("product not found")
+)
+
+// Off returns how much the discount takes off the given amount. The result is
+// never larger than the amount itself.
+func (d Discount) Off(amount int) int {
+	var off int
+	switch d.Kind {
+	case KindPercent:
+		off = amount * d.Amount / 100
+	case KindFixed:
+		off = d.Amount
+	}
+
+	if off > amount {
+		return amount
+	}
+	if off < 0 {
+		return 0
+	}
+	return off
+}
+
+// validate checks the discount amount makes sense for its kind.
+func (d Discount) validate() error {
+	if d.Kind == KindPercent && (d.Amount < 0 || d.Amount > 100) {
+		return ErrInvalidDiscount
+	}
+	return nil
+}
+
+// Calculate works out the price for a gross amount. Only the markdown giving
+// the biggest reduction is applied, the coupon, if any, is applied on top of
+// the marked down amount.
+func Calculate(gross int, markdowns []Markdown, coupon *Coupon) Price {
+	var best int
+	for _, m := range markdowns {
+		if off := (Discount{Kind: m.Kind, Amount: m.Amount}).Off(gross); off > best {
+			best = off
+		}
+	}
+
+	discount := best
+	if coupon != nil {
+		discount += Discount{Kind: coupon.Kind, Amount: coupon.Amount}.Off(gross - best)
+	}
+
+	return Price{
+		Gross:    gross,
+		Discount: discount,
+		Paid:     gross - discount,
+	}
+}
@@ -0,0 +1,170 @@
+package pricing_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
+)
+
+func TestCalculate(t *testing.T) {
+	half := pricing.Markdown{Kind: pricing.KindPercent, Amount: 50}
+	tenOff := pricing.Markdown{Kind: pricing.KindFixed, Amount: 10}
+
+	tests := []struct {
+		name      string
+		gross     int
+		markdowns []pricing.Markdown
+		coupon    *pricing.Coupon
+		want      pricing.Price
+	}{
+		{
+			name:  "no discounts",
+			gross: 100,
+			want:  pricing.Price{Gross: 100, Discount: 0, Paid: 100},
+		},
+		{
+			name:      "best markdown wins",
+			gross:     100,
+			markdowns: []pricing.Markdown{tenOff, half},
+			want:      pricing.Price{Gross: 100, Discount: 50, Paid: 50},
+		},
+		{
+			name:      "coupon applies after markdown",
+			gross:     100,
+			markdowns: []pricing.Markdown{half},
+			coupon:    &pricing.Coupon{Kind: pricing.KindPercent, Amount: 10},
+			want:      pricing.Price{Gross: 100, Discount: 55, Paid: 45},
+		},
+		{
+			name:   "fixed discount never exceeds price",
+			gross:  30,
+			coupon: &pricing.Coupon{Kind: pricing.KindFixed, Amount: 50},
+			want:   pricing.Price{Gross: 30, Discount: 30, Paid: 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pricing.Calculate(tt.gross, tt.markdowns, tt.coupon)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("price did not match expected: see diff \n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMarkdownTimes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		startsAt time.Time
+		endsAt   time.Time
+	}{
+		{name: "ends before it starts", startsAt: future, endsAt: now.Add(30 * time.Minute)},
+		{name: "ends when it starts", startsAt: future, endsAt: future},
+		{name: "already over", startsAt: past.Add(-time.Hour), endsAt: past},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nm := pricing.NewMarkdown{Kind: pricing.KindPercent, Amount: 10, StartsAt: tt.startsAt, EndsAt: &tt.endsAt}
+
+			// The times are checked before the database is used.
+			if _, err := pricing.CreateMarkdown(ctx, nil, event.DefaultID, nm, now); err != pricing.ErrMarkdownTimes {
+				t.Fatalf("expected %v, got %v", pricing.ErrMarkdownTimes, err)
+			}
+		})
+	}
+}
+
+func TestCoupons(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// redeem uses a coupon in a transaction of its own like a sale would.
+	redeem := func(code string, at time.Time) error {
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		if _, err := pricing.Redeem(ctx, tx, event.DefaultID, code, at); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	expires := now.Add(time.Hour)
+	nc := pricing.NewCoupon{Code: "twice", Kind: pricing.KindFixed, Amount: 5, MaxUses: 2, ExpiresAt: &expires}
+	if _, err := pricing.CreateCoupon(ctx, db, event.DefaultID, nc, now); err != nil {
+		t.Fatalf("creating coupon: %s", err)
+	}
+	if _, err := pricing.CreateCoupon(ctx, db, event.DefaultID, nc, now); err != pricing.ErrCouponCodeExists {
+		t.Fatalf("expected %v creating the code twice, got %v", pricing.ErrCouponCodeExists, err)
+	}
+
+	if err := redeem("TWICE", expires); err != pricing.ErrCouponExpired {
+		t.Fatalf("expected %v at the expiry, got %v", pricing.ErrCouponExpired, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := redeem(" twice ", now); err != nil {
+			t.Fatalf("redeeming use %d: %s", i+1, err)
+		}
+	}
+	if err := redeem("TWICE", now); err != pricing.ErrCouponExhausted {
+		t.Fatalf("expected %v past the usage limit, got %v", pricing.ErrCouponExhausted, err)
+	}
+	if err := redeem("NOPE", now); err != pricing.ErrCouponNotFound {
+		t.Fatalf("expected %v for an unknown code, got %v", pricing.ErrCouponNotFound, err)
+	}
+
+	// Racing redemptions never use a coupon more often than allowed.
+	nc = pricing.NewCoupon{Code: "RACE", Kind: pricing.KindPercent, Amount: 10, MaxUses: 3}
+	if _, err := pricing.CreateCoupon(ctx, db, event.DefaultID, nc, now); err != nil {
+		t.Fatalf("creating coupon: %s", err)
+	}
+
+	const racers = 10
+	errs := make(chan error, racers)
+	for i := 0; i < racers; i++ {
+		go func() {
+			errs <- redeem("RACE", now)
+		}()
+	}
+
+	var used int
+	for i := 0; i < racers; i++ {
+		switch err := <-errs; err {
+		case nil:
+			used++
+		case pricing.ErrCouponExhausted:
+		default:
+			t.Fatalf("redeeming: %s", err)
+		}
+	}
+	if used != nc.MaxUses {
+		t.Fatalf("expected %d redemptions, got %d", nc.MaxUses, used)
+	}
+
+	list, err := pricing.ListCoupons(ctx, db, event.DefaultID)
+	if err != nil {
+		t.Fatalf("listing coupons: %s", err)
+	}
+	for _, c := range list {
+		if c.Code == "RACE" && c.Uses != nc.MaxUses {
+			t.Fatalf("expected the coupon used %d times, got %d", nc.MaxUses, c.Uses)
+		}
+	}
+}
@@ -0,0 +1,521 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SnipeWindow is how long an auction keeps going after its last bid. Bids in
+// the final SnipeWindow push the end back so others get a chance to answer.
+const SnipeWindow = 2 * time.Minute
+
+// MaxSaleAttempts is how many times the lot of a won auction is tried to be
+// sold before the auction is given up as failed.
+const MaxSaleAttempts = 5
+
+// Predefined errors for auction failure scenarios
+var (
+	ErrAuctionNotFound = errors.New("auction not found")
+	ErrAuctionClosed   = errors.New("auction is not taking bids")
+	ErrAuctionNotWon   = errors.New("auction has not been won")
+	ErrAuctionMismatch = errors.New("sale does not match the auction")
+	ErrAuctionTimes    = errors.New("auction must end in the future and after it starts")
+	ErrBidTooLow       = errors.New("bid does not beat the high bid by the minimum increment")
+)
+
+// ListAuctions gives the auctions of a Product of an event, the most recent
+// first.
+func ListAuctions(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Auction, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	list := []Auction{}
+
+	const q = `SELECT a.* FROM auctions AS a
+	JOIN products AS p ON p.product_id = a.product_id
+	WHERE a.product_id = $1 AND p.event_id = $2
+	ORDER BY a.date_created DESC`
+
+	if err := db.SelectContext(ctx, &list, q, productID, eventID); err != nil {
+		return nil, fmt.Errorf("selecting auctions: %w", err)
+	}
+
+	return list, nil
+}
+
+// RetrieveAuction gives a single Auction of an event
+func RetrieveAuction(ctx context.Context, db *sqlx.DB, eventID, id string) (*Auction, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	var a Auction
+
+	const q = `SELECT a.* FROM auctions AS a
+	JOIN products AS p ON p.product_id = a.product_id
+	WHERE a.auction_id = $1 AND p.event_id = $2`
+
+	if err := db.GetContext(ctx, &a, q, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAuctionNotFound
+		}
+		return nil, fmt.Errorf("selecting auction: %w", err)
+	}
+
+	return &a, nil
+}
+
+// CreateAuction puts a unit of a Product of an event up for auction. The
+// unit is held by a reservation of the auction from the start so it can not
+// be sold or reserved while bidding runs; with nothing available the product
+// can not be auctioned.
+func CreateAuction(ctx context.Context, db *sqlx.DB, eventID, productID string, na NewAuction, now time.Time) (*Auction, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	if !na.EndsAt.After(na.StartsAt) || !na.EndsAt.After(now) {
+		return nil, ErrAuctionTimes
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st, err := lockStock(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := retrieve(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	a := Auction{
+		ID:           uuid.New().String(),
+		ProductID:    p.ID,
+		StartsAt:     na.StartsAt.UTC(),
+		EndsAt:       na.EndsAt.UTC(),
+		ReservePrice: na.ReservePrice,
+		MinIncrement: na.MinIncrement,
+		Status:       AuctionOpen,
+		DateCreated:  now.UTC(),
+		DateUpdated:  now.UTC(),
+	}
+
+	available := st.Available
+	switch {
+	case na.VariantID != "":
+		v, err := RetrieveVariant(ctx, tx, eventID, p.ID, na.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		a.VariantID = &v.ID
+		available = v.Available
+	case len(p.Variants) > 0:
+		return nil, ErrVariantRequired
+	}
+
+	if available < 1 {
+		return nil, ErrInsufficientStock
+	}
+
+	const q = `INSERT INTO auctions
+	(auction_id, product_id, variant_id, starts_at, ends_at, reserve_price, min_increment,
+		status, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = tx.ExecContext(ctx, q,
+		a.ID, a.ProductID, a.VariantID, a.StartsAt, a.EndsAt, a.ReservePrice, a.MinIncrement,
+		a.Status, a.DateCreated, a.DateUpdated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("inserting auction: %w", err)
+	}
+
+	const hold = `INSERT INTO reservations
+	(reservation_id, product_id, variant_id, auction_id, note, quantity, status,
+		expires_at, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, 'auction', 1, 'active', $5, $6, $6)`
+
+	if _, err := tx.ExecContext(ctx, hold, uuid.New().String(), a.ProductID, a.VariantID, a.ID, a.EndsAt, a.DateCreated); err != nil {
+		return nil, fmt.Errorf("holding auction lot: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "auction", a.ID, nil, a, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing auction: %w", err)
+	}
+
+	return &a, nil
+}
+
+// ListBids gives the bids of an Auction of an event, the highest first.
+func ListBids(ctx context.Context, db *sqlx.DB, eventID, auctionID string) ([]Bid, error) {
+	if _, err := RetrieveAuction(ctx, db, eventID, auctionID); err != nil {
+		return nil, err
+	}
+
+	list := []Bid{}
+
+	const q = `SELECT * FROM bids WHERE auction_id = $1 ORDER BY amount DESC`
+
+	if err := db.SelectContext(ctx, &list, q, auctionID); err != nil {
+		return nil, fmt.Errorf("selecting bids: %w", err)
+	}
+
+	return list, nil
+}
+
+// PlaceBid bids in an open Auction of an event. The auction is locked while
+// the bid is checked against the high bid, so of two bids racing for the same
+// amount only one gets in.
+func PlaceBid(ctx context.Context, db *sqlx.DB, eventID, auctionID string, nb NewBid, now time.Time) (*Bid, error) {
+	if err := checkIDs(eventID, auctionID); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var a Auction
+
+	const lock = `SELECT a.* FROM auctions AS a
+	JOIN products AS p ON p.product_id = a.product_id
+	WHERE a.auction_id = $1 AND p.event_id = $2
+	FOR UPDATE OF a`
+
+	if err := tx.GetContext(ctx, &a, lock, auctionID, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAuctionNotFound
+		}
+		return nil, fmt.Errorf("locking auction: %w", err)
+	}
+
+	if a.Status != AuctionOpen || now.Before(a.StartsAt) || !now.Before(a.EndsAt) {
+		return nil, ErrAuctionClosed
+	}
+	if a.HighBid != nil && nb.Amount < *a.HighBid+a.MinIncrement {
+		return nil, ErrBidTooLow
+	}
+
+	b := Bid{
+		ID:          uuid.New().String(),
+		AuctionID:   a.ID,
+		Bidder:      nb.Bidder,
+		Amount:      nb.Amount,
+		DateCreated: now.UTC(),
+	}
+	if nb.CustomerID != "" {
+		b.CustomerID = &nb.CustomerID
+	}
+
+	const q = `INSERT INTO bids
+	(bid_id, auction_id, customer_id, bidder, amount, date_created)
+	VALUES ($1, $2, $3, $4, $5, $6)`
+
+	if _, err := tx.ExecContext(ctx, q, b.ID, b.AuctionID, b.CustomerID, b.Bidder, b.Amount, b.DateCreated); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && b.CustomerID != nil {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, fmt.Errorf("inserting bid: %w", err)
+	}
+
+	if a.EndsAt.Sub(now) < SnipeWindow {
+		a.EndsAt = now.Add(SnipeWindow).UTC()
+	}
+
+	const update = `UPDATE auctions SET
+		"high_bid" = $2,
+		"bids" = bids + 1,
+		"ends_at" = $3,
+		"date_updated" = $4
+		WHERE auction_id = $1`
+
+	if _, err := tx.ExecContext(ctx, update, a.ID, b.Amount, a.EndsAt, now.UTC()); err != nil {
+		return nil, fmt.Errorf("updating auction: %w", err)
+	}
+
+	// The hold on the lot shows when the auction ends; it does not expire on
+	// its own.
+	const extend = `UPDATE reservations SET "expires_at" = $2
+		WHERE auction_id = $1 AND status = 'active'`
+
+	if _, err := tx.ExecContext(ctx, extend, a.ID, a.EndsAt); err != nil {
+		return nil, fmt.Errorf("extending auction lot hold: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "bid", b.ID, nil, b.audited(), now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing bid: %w", err)
+	}
+
+	return &b, nil
+}
+
+// CloseAuctions ends every open auction that ended by now and sells the lot
+// of each won auction to its highest bidder. It returns how many auctions
+// were sold. Won auctions whose sale failed are tried again on the next call
+// until they failed MaxSaleAttempts times; then they are failed for good and
+// their lot goes back on sale.
+func CloseAuctions(ctx context.Context, db *sqlx.DB, now time.Time) (int, error) {
+	var due []struct {
+		ID        string `db:"auction_id"`
+		ProductID string `db:"product_id"`
+		EventID   string `db:"event_id"`
+	}
+
+	const q = `SELECT a.auction_id, a.product_id, p.event_id FROM auctions AS a
+	JOIN products AS p ON p.product_id = a.product_id
+	WHERE (a.status = 'open' AND a.ends_at <= $1) OR a.status = 'won'`
+
+	if err := db.SelectContext(ctx, &due, q, now.UTC()); err != nil {
+		return 0, fmt.Errorf("selecting ended auctions: %w", err)
+	}
+
+	var sold int
+	var lastErr error
+	for _, d := range due {
+		won, err := closeAuction(ctx, db, d.ID, now)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if !won {
+			continue
+		}
+
+		ns := NewSale{AuctionID: d.ID, Quantity: 1}
+		if _, err := AddSale(ctx, db, d.EventID, ns, d.ProductID, now); err != nil {
+			lastErr = fmt.Errorf("selling auction %q: %w", d.ID, err)
+			if err := failSale(ctx, db, d.ID, err, now); err != nil {
+				lastErr = err
+			}
+			continue
+		}
+		sold++
+	}
+
+	return sold, lastErr
+}
+
+// failSale records a failed attempt to sell the lot of a won Auction. Once
+// MaxSaleAttempts failed the auction is failed and its lot released.
+func failSale(ctx context.Context, db *sqlx.DB, id string, saleErr error, now time.Time) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+
+	const q = `UPDATE auctions SET
+		"sale_attempts" = sale_attempts + 1,
+		"last_error" = $2,
+		"status" = CASE WHEN sale_attempts + 1 >= $3 THEN 'failed' ELSE status END,
+		"date_updated" = $4
+		WHERE auction_id = $1 AND status = 'won'
+		RETURNING status`
+
+	if err := tx.GetContext(ctx, &status, q, id, saleErr.Error(), MaxSaleAttempts, now.UTC()); err != nil {
+		if err == sql.ErrNoRows {
+
+			// Someone else sold or failed the auction in the meantime.
+			return nil
+		}
+		return fmt.Errorf("recording failed sale of auction %q: %w", id, err)
+	}
+
+	if status == AuctionFailed {
+		if err := releaseAuctionHold(ctx, tx, id, now); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing failed sale of auction %q: %w", id, err)
+	}
+
+	return nil
+}
+
+// closeAuction ends an Auction that is due, deciding whether it was won. It
+// reports whether the lot is waiting to be sold.
+func closeAuction(ctx context.Context, db *sqlx.DB, id string, now time.Time) (bool, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var a Auction
+
+	const lock = `SELECT * FROM auctions WHERE auction_id = $1 FOR UPDATE`
+
+	if err := tx.GetContext(ctx, &a, lock, id); err != nil {
+		return false, fmt.Errorf("locking auction: %w", err)
+	}
+
+	switch {
+	case a.Status == AuctionWon:
+		return true, nil
+	case a.Status != AuctionOpen || a.EndsAt.After(now):
+		// A late bid moved the end or another closer got here first.
+		return false, nil
+	}
+
+	a.Status = AuctionUnsold
+	if a.HighBid != nil && *a.HighBid >= a.ReservePrice {
+		a.Status = AuctionWon
+	}
+
+	const q = `UPDATE auctions SET
+		"status" = $2,
+		"date_updated" = $3
+		WHERE auction_id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, a.ID, a.Status, now.UTC()); err != nil {
+		return false, fmt.Errorf("closing auction: %w", err)
+	}
+
+	// A won lot stays held for the sale to the highest bidder, an unsold one
+	// goes back on sale.
+	if a.Status == AuctionUnsold {
+		if err := releaseAuctionHold(ctx, tx, a.ID, now); err != nil {
+			return false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("committing auction: %w", err)
+	}
+
+	return a.Status == AuctionWon, nil
+}
+
+// Auctioneer closes auctions once they end and sells their lots.
+type Auctioneer struct {
+	DB       *sqlx.DB
+	Log      *log.Logger
+	Interval time.Duration
+}
+
+// Run closes auctions every Interval until the context is canceled.
+func (a *Auctioneer) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := CloseAuctions(ctx, a.DB, time.Now())
+		if err != nil {
+			a.Log.Printf("auctions : closing : %v", err)
+		}
+		if n > 0 {
+			a.Log.Printf("auctions : sold %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// takeAuction locks a won Auction of a product for the sale of its lot and
+// gives the winning bid.
+func takeAuction(ctx context.Context, tx sqlx.QueryerContext, productID, id string) (*Auction, *Bid, error) {
+	var a Auction
+
+	const q = `SELECT * FROM auctions
+	WHERE auction_id = $1 AND product_id = $2
+	FOR UPDATE`
+
+	if err := sqlx.GetContext(ctx, tx, &a, q, id, productID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrAuctionNotFound
+		}
+		return nil, nil, fmt.Errorf("selecting auction: %w", err)
+	}
+
+	if a.Status != AuctionWon {
+		return nil, nil, ErrAuctionNotWon
+	}
+
+	var b Bid
+
+	const winner = `SELECT * FROM bids WHERE auction_id = $1
+	ORDER BY amount DESC, date_created
+	LIMIT 1`
+
+	if err := sqlx.GetContext(ctx, tx, &b, winner, a.ID); err != nil {
+		return nil, nil, fmt.Errorf("selecting winning bid: %w", err)
+	}
+
+	return &a, &b, nil
+}
+
+// auctionHold locks the active Reservation holding the lot of an Auction for
+// the sale of the lot. It gives nil when the lot is not held, leaving the sale
+// to find an available unit like any other.
+func auctionHold(ctx context.Context, tx sqlx.QueryerContext, auctionID string) (*Reservation, error) {
+	var r Reservation
+
+	const q = `SELECT * FROM reservations
+	WHERE auction_id = $1 AND status = 'active'
+	FOR UPDATE`
+
+	if err := sqlx.GetContext(ctx, tx, &r, q, auctionID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("selecting auction lot hold: %w", err)
+	}
+
+	return &r, nil
+}
+
+// releaseAuctionHold puts the lot held for an Auction back on sale.
+func releaseAuctionHold(ctx context.Context, tx sqlx.ExecerContext, auctionID string, now time.Time) error {
+	const q = `UPDATE reservations SET
+		"status" = 'released',
+		"date_updated" = $2
+		WHERE auction_id = $1 AND status = 'active'`
+
+	if _, err := tx.ExecContext(ctx, q, auctionID, now.UTC()); err != nil {
+		return fmt.Errorf("releasing auction lot hold: %w", err)
+	}
+
+	return nil
+}
+
+// audited gives the Bid as it is written to the audit log, without the name
+// of the bidder for the same reason reservations leave out their note.
+func (b Bid) audited() Bid {
+	b.Bidder = ""
+	return b
+}
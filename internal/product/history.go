@@ -0,0 +1,147 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/images"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrVersionNotFound is returned for a product version that does not exist.
+var ErrVersionNotFound = errors.New("product version not found")
+
+// ListVersions gives every version of a Product of an event, oldest first.
+func ListVersions(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Version, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	list := []Version{}
+
+	const q = `SELECT v.* FROM product_versions AS v
+	JOIN products AS p ON p.product_id = v.product_id
+	WHERE v.product_id = $1 AND p.event_id = $2
+	ORDER BY v.version`
+
+	if err := db.SelectContext(ctx, &list, q, productID, eventID); err != nil {
+		return nil, fmt.Errorf("selecting versions: %w", err)
+	}
+
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return list, nil
+}
+
+// RetrieveAsOf gives a Product of an event as it was at a point in time. Its
+// fields come from the version in effect then and its stock, sales and
+// revenue only count what happened up to that time. Images, variants and
+// reservations are not versioned and left out. Versions recorded before
+// minimum prices or archiving were kept have no minimum and are not archived,
+// as neither applied then.
+func RetrieveAsOf(ctx context.Context, db *sqlx.DB, eventID, id string, asOf time.Time) (*Product, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	var p Product
+
+	const q = `
+	SELECT
+		p.product_id, p.event_id, v.seller_id, v.name, v.category, v.cost, v.low_stock_threshold,
+		COALESCE(v.min_price, 0) AS min_price, COALESCE(v.archived, FALSE) AS archived,
+		p.date_created, v.valid_from AS date_updated,
+		COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+			WHERE m.product_id = p.product_id AND m.kind <> 'sale' AND m.date_created <= $2
+			AND ` + ownStock + `), 0) AS quantity,
+		COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+			WHERE m.product_id = p.product_id AND m.date_created <= $2
+			AND ` + ownStock + `), 0) AS on_hand,
+		COALESCE((SELECT SUM(s.paid) FROM sales AS s
+			WHERE s.product_id = p.product_id AND s.date_created <= $2), 0) AS revenue,
+		COALESCE((SELECT SUM(s.quantity) FROM sales AS s
+			WHERE s.product_id = p.product_id AND s.date_created <= $2), 0) AS sold
+	FROM products AS p
+	JOIN product_versions AS v ON v.product_id = p.product_id
+	WHERE p.product_id = $1 AND v.valid_from <= $2 AND p.event_id = $3
+	ORDER BY v.version DESC
+	LIMIT 1`
+
+	if err := db.GetContext(ctx, &p, q, id, asOf.UTC(), eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	p.Images = []images.Image{}
+	p.Variants = []Variant{}
+
+	// Reservations are not kept in history, everything on hand was available.
+	p.Available = p.OnHand
+
+	return &p, nil
+}
+
+// RevertTo restores the fields of a Product, including its seller and minimum
+// price and whether it is archived, to those of a previous version. A minimum
+// price or archived flag the version did not record is left as it is. The revert is an update of its own so it is recorded
+// as a new version and history is never rewritten. Stock is kept in the
+// inventory ledger and is not reverted.
+func RevertTo(ctx context.Context, db *sqlx.DB, eventID, id string, version int, now time.Time) error {
+	if err := checkIDs(eventID, id); err != nil {
+		return err
+	}
+
+	var v Version
+
+	const q = `SELECT v.* FROM product_versions AS v
+	JOIN products AS p ON p.product_id = v.product_id
+	WHERE v.product_id = $1 AND v.version = $2 AND p.event_id = $3`
+
+	if err := db.GetContext(ctx, &v, q, id, version, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrVersionNotFound
+		}
+		return fmt.Errorf("selecting version: %w", err)
+	}
+
+	var sellerID string
+	if v.SellerID != nil {
+		sellerID = *v.SellerID
+	}
+
+	update := UpdateProduct{
+		Name:     &v.Name,
+		Category: &v.Category,
+		Cost:     &v.Cost,
+		LowStock: &v.LowStock,
+		SellerID: &sellerID,
+		MinPrice: v.MinPrice,
+		Archived: v.Archived,
+	}
+
+	return Update(ctx, db, eventID, id, update, now)
+}
+
+// recordVersion stores the current fields of a product as its next version.
+// It is meant to be called inside the transaction that changes the product,
+// after the product was locked with lockStock so concurrent changes do not
+// take the same version number. New products need no lock.
+func recordVersion(ctx context.Context, tx sqlx.ExecerContext, p Product) error {
+	const q = `INSERT INTO product_versions
+	(product_id, version, seller_id, name, category, cost, low_stock_threshold, min_price, archived, valid_from)
+	SELECT $1, COALESCE(MAX(version), 0) + 1, $2, $3, $4, $5, $6, $7, $8, $9
+	FROM product_versions WHERE product_id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, p.ID, p.SellerID, p.Name, p.Category, p.Cost, p.LowStock, p.MinPrice, p.Archived, p.DateUpdated); err != nil {
+		return fmt.Errorf("inserting product version: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
+	"github.com/jmoiron/sqlx"
+)
+
+// Predefined errors for inventory failure scenarios
+var (
+	ErrInvalidAdjustment = errors.New("only corrections may take a negative quantity")
+	ErrInsufficientStock = errors.New("not enough stock on hand")
+	ErrRefundOnly        = errors.New("only refunds may name a sale")
+	ErrRefundExceedsSale = errors.New("refund exceeds the units sold")
+)
+
+// ownStock restricts the movements of a product p to those its stock is
+// counted from. Products with variants only count the movements of their
+// variants, other products only those recorded against the product itself.
+const ownStock = `(m.variant_id IS NOT NULL) =
+	EXISTS (SELECT 1 FROM product_variants AS pv WHERE pv.product_id = p.product_id)`
+
+// productOnHand and productReserved add up the units of a product p on hand
+// and held by active reservations.
+const (
+	productOnHand = `COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+		WHERE m.product_id = p.product_id AND ` + ownStock + `), 0)`
+	productReserved = `COALESCE((SELECT SUM(r.quantity) FROM reservations AS r
+		WHERE r.product_id = p.product_id AND r.status = 'active'), 0)`
+)
+
+// stockColumns derive the quantity stocked, on hand, reserved and available
+// of a product p from the inventory ledger and its reservations.
+const stockColumns = `
+	COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+		WHERE m.product_id = p.product_id AND m.kind <> 'sale' AND ` + ownStock + `), 0) AS quantity,
+	` + productOnHand + ` AS on_hand,
+	` + productReserved + ` AS reserved,
+	` + productOnHand + ` - ` + productReserved + ` AS available`
+
+// variantOnHand and variantReserved add up the units of a variant v on hand
+// and held by active reservations.
+const (
+	variantOnHand = `COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+		WHERE m.variant_id = v.variant_id), 0)`
+	variantReserved = `COALESCE((SELECT SUM(r.quantity) FROM reservations AS r
+		WHERE r.variant_id = v.variant_id AND r.status = 'active'), 0)`
+)
+
+// variantStockColumns derive the quantity stocked, on hand, reserved and
+// available of a variant v from the inventory ledger and its reservations.
+const variantStockColumns = `
+	COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+		WHERE m.variant_id = v.variant_id AND m.kind <> 'sale'), 0) AS quantity,
+	` + variantOnHand + ` AS on_hand,
+	` + variantReserved + ` AS reserved,
+	` + variantOnHand + ` - ` + variantReserved + ` AS available`
+
+// ListMovements gives the inventory ledger of a Product of an event, oldest
+// first.
+func ListMovements(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Movement, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	list := []Movement{}
+
+	const q = `SELECT m.* FROM inventory_movements AS m
+	JOIN products AS p ON p.product_id = m.product_id
+	WHERE m.product_id = $1 AND p.event_id = $2
+	ORDER BY m.date_created, m.movement_id`
+
+	if err := db.SelectContext(ctx, &list, q, productID, eventID); err != nil {
+		return nil, fmt.Errorf("selecting movements: %w", err)
+	}
+
+	return list, nil
+}
+
+// Adjust records a manual change of the stock of a Product of an event. Units
+// going out can not take the stock on hand below zero. A refund naming a sale
+// can not return more units than were sold in it and goes back to the variant
+// that was sold.
+func Adjust(ctx context.Context, db *sqlx.DB, eventID, productID string, na NewAdjustment, actor string, now time.Time) (*Movement, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	quantity := na.Quantity
+	switch na.Kind {
+	case MovementReceived, MovementRefund:
+		if quantity < 0 {
+			return nil, ErrInvalidAdjustment
+		}
+	case MovementDamaged, MovementLost:
+		if quantity < 0 {
+			return nil, ErrInvalidAdjustment
+		}
+		quantity = -quantity
+	}
+	if na.SaleID != "" && na.Kind != MovementRefund {
+		return nil, ErrRefundOnly
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st, err := lockStock(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := retrieve(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Movement{
+		ID:          uuid.New().String(),
+		ProductID:   p.ID,
+		Kind:        na.Kind,
+		Quantity:    quantity,
+		Reason:      na.Reason,
+		Actor:       actor,
+		DateCreated: now.UTC(),
+	}
+
+	if na.SaleID != "" {
+		var sale struct {
+			VariantID *string `db:"variant_id"`
+			Quantity  int     `db:"quantity"`
+			Refunded  int     `db:"refunded"`
+		}
+
+		const q = `SELECT s.variant_id, s.quantity,
+			COALESCE((SELECT SUM(m.quantity) FROM inventory_movements AS m
+				WHERE m.sale_id = s.sale_id AND m.kind = 'refund'), 0) AS refunded
+		FROM sales AS s
+		WHERE s.sale_id = $1 AND s.product_id = $2`
+
+		if err := tx.GetContext(ctx, &sale, q, na.SaleID, p.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return nil, ErrSaleNotFound
+			}
+			return nil, fmt.Errorf("selecting sale: %w", err)
+		}
+		if sale.Refunded+quantity > sale.Quantity {
+			return nil, ErrRefundExceedsSale
+		}
+
+		m.SaleID = &na.SaleID
+		if sale.VariantID != nil {
+			na.VariantID = *sale.VariantID
+		}
+	}
+
+	onHand := st.OnHand
+	switch {
+	case na.VariantID != "":
+		v, err := RetrieveVariant(ctx, tx, eventID, p.ID, na.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		m.VariantID = &v.ID
+		onHand = v.OnHand
+	case len(p.Variants) > 0:
+		return nil, ErrVariantRequired
+	}
+
+	if onHand+m.Quantity < 0 {
+		return nil, ErrInsufficientStock
+	}
+
+	if err := recordMovement(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	ae := AdjustmentEvent{
+		Movement: m,
+		EventID:  p.EventID,
+		Category: p.Category,
+		OnHand:   st.OnHand + m.Quantity,
+	}
+	if err := webhook.Publish(ctx, tx, webhook.EventInventoryAdjusted, ae, now); err != nil {
+		return nil, err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "movement", m.ID, nil, m, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing adjustment: %w", err)
+	}
+
+	return &m, nil
+}
+
+// stock is the stock of a product as selected by stockColumns.
+type stock struct {
+	Quantity  int `db:"quantity"`
+	OnHand    int `db:"on_hand"`
+	Reserved  int `db:"reserved"`
+	Available int `db:"available"`
+}
+
+// lockStock locks a product of an event so concurrent changes to it, its
+// stock and its reservations see each other and gives its current stock. It
+// is meant to be called inside the transaction that makes the change.
+func lockStock(ctx context.Context, tx sqlx.ExtContext, eventID, productID string) (*stock, error) {
+	const lock = `SELECT product_id FROM products WHERE product_id = $1 AND event_id = $2 FOR UPDATE`
+	if _, err := tx.ExecContext(ctx, lock, productID, eventID); err != nil {
+		return nil, fmt.Errorf("locking product: %w", err)
+	}
+
+	var st stock
+
+	q := `SELECT` + stockColumns + ` FROM products AS p WHERE p.product_id = $1 AND p.event_id = $2`
+	if err := sqlx.GetContext(ctx, tx, &st, q, productID, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("selecting stock: %w", err)
+	}
+
+	return &st, nil
+}
+
+// recordMovement adds an entry to the inventory ledger. It is meant to be
+// called inside the transaction that makes the change.
+func recordMovement(ctx context.Context, tx sqlx.ExecerContext, m Movement) error {
+	const q = `INSERT INTO inventory_movements
+	(movement_id, product_id, variant_id, sale_id, kind, quantity, reason, actor, date_created)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := tx.ExecContext(ctx, q,
+		m.ID, m.ProductID, m.VariantID, m.SaleID,
+		m.Kind, m.Quantity, m.Reason, m.Actor, m.DateCreated,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting movement: %w", err)
+	}
+
+	return nil
+}
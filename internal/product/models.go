@@ -1,24 +1,51 @@
 package product
 
-import "time"
+import (
+	"time"
 
-// Product is something we sell
+	"github.com/ivan-sabo/garagesale/internal/images"
+)
+
+// Product is something we sell at an event. Quantity is the number of units
+// ever stocked and OnHand what is left of them, both derived from the
+// inventory ledger. Reserved units are on hand but held for a customer and
+// Available is what is left to sell. Offers below MinPrice are rejected
+// right away; zero accepts any offer. Archived products stay on the books but
+// are no longer shown in the public storefront.
+// When a product comes in variants its Quantity is the total of the variant
+// quantities while Sold and Revenue add up the sales of every variant. Products
+// sold on consignment are linked to their seller.
 type Product struct {
-	ID          string    `db:"product_id" json:"id"`
-	Name        string    `db:"name" json:"name"`
-	Cost        int       `db:"cost" json:"cost"`
-	Quantity    int       `db:"quantity" json:"quantity"`
-	Sold        int       `db:"sold" json:"sold"`
-	Revenue     int       `db:"revenue" json:"revenue"`
-	DateCreated time.Time `db:"date_created" json:"date_created"`
-	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+	ID          string         `db:"product_id" json:"id"`
+	EventID     string         `db:"event_id" json:"event_id"`
+	SellerID    *string        `db:"seller_id" json:"seller_id,omitempty"`
+	Name        string         `db:"name" json:"name"`
+	Category    string         `db:"category" json:"category"`
+	Cost        int            `db:"cost" json:"cost"`
+	Quantity    int            `db:"quantity" json:"quantity"`
+	OnHand      int            `db:"on_hand" json:"on_hand"`
+	Reserved    int            `db:"reserved" json:"reserved"`
+	Available   int            `db:"available" json:"available"`
+	LowStock    int            `db:"low_stock_threshold" json:"low_stock_threshold"`
+	MinPrice    int            `db:"min_price" json:"min_price"`
+	Archived    bool           `db:"archived" json:"archived"`
+	Sold        int            `db:"sold" json:"sold"`
+	Revenue     int            `db:"revenue" json:"revenue"`
+	Images      []images.Image `db:"-" json:"images"`
+	Variants    []Variant      `db:"-" json:"variants"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated time.Time      `db:"date_updated" json:"date_updated"`
 }
 
 // NewProduct is what we require from clients to make a new Product
 type NewProduct struct {
 	Name     string `json:"name" validate:"required"`
+	Category string `json:"category"`
 	Cost     int    `json:"cost" validate:"gte=0"`
 	Quantity int    `json:"quantity" validate:"gte=1"`
+	LowStock int    `json:"low_stock_threshold" validate:"gte=0"`
+	MinPrice int    `json:"min_price" validate:"gte=0"`
+	SellerID string `json:"seller_id" validate:"omitempty,uuid"`
 }
 
 // UpdateProduct defines what information may be provided to modify an
@@ -26,24 +53,384 @@ type NewProduct struct {
 // fields they want changed. It uses pointer fields so we can differentiate
 // between a field that was not provided and a field that was provided as
 // explicitly blank. Normally we do not want to use pointers to basic types but
-// we make exceptions around marshalling/unmarshalling
+// we make exceptions around marshalling/unmarshalling. A blank SellerID takes
+// the product off consignment.
 type UpdateProduct struct {
 	Name     *string `json:"name"`
+	Category *string `json:"category"`
 	Cost     *int    `json:"cost" validate:"omitempty,gte=0"`
 	Quantity *int    `json:"quantity" validate:"omitempty,gte=1"`
+	LowStock *int    `json:"low_stock_threshold" validate:"omitempty,gte=0"`
+	MinPrice *int    `json:"min_price" validate:"omitempty,gte=0"`
+	SellerID *string `json:"seller_id" validate:"omitempty,uuid"`
+	Archived *bool   `json:"archived"`
 }
 
-// Sale represents one item of a transaction where some amount of a product was sold
-type Sale struct {
-	ID          string    `db:"sale_id" json:"id"`
+// PublicProduct is what the public storefront shows of a Product. It leaves
+// out cost, stock figures other than what is available, sales and anything
+// else only staff should see. Price is what a buyer pays before tax, with the
+// best active markdown taken off.
+type PublicProduct struct {
+	ID        string          `json:"id"`
+	EventID   string          `json:"event_id"`
+	Name      string          `json:"name"`
+	Category  string          `json:"category"`
+	Price     int             `json:"price"`
+	Available int             `json:"available"`
+	Images    []PublicImage   `json:"images"`
+	Variants  []PublicVariant `json:"variants"`
+}
+
+// PublicVariant is what the public storefront shows of a Variant.
+type PublicVariant struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Price     int    `json:"price"`
+	Available int    `json:"available"`
+}
+
+// PublicImage is what the public storefront shows of a product image. URLs
+// point at the public download routes.
+type PublicImage struct {
+	ID      string            `json:"id"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Primary bool              `json:"primary"`
+	URLs    map[string]string `json:"urls"`
+}
+
+// Version is the state of the fields of a Product as of one of its updates.
+// A version is in effect from ValidFrom until the next version.
+type Version struct {
+	ProductID string    `db:"product_id" json:"product_id"`
+	Version   int       `db:"version" json:"version"`
+	SellerID  *string   `db:"seller_id" json:"seller_id,omitempty"`
+	Name      string    `db:"name" json:"name"`
+	Category  string    `db:"category" json:"category"`
+	Cost      int       `db:"cost" json:"cost"`
+	LowStock  int       `db:"low_stock_threshold" json:"low_stock_threshold"`
+	MinPrice  *int      `db:"min_price" json:"min_price,omitempty"`
+	Archived  *bool     `db:"archived" json:"archived,omitempty"`
+	ValidFrom time.Time `db:"valid_from" json:"valid_from"`
+}
+
+// Revert is what we require from clients to restore a previous Version of a
+// Product.
+type Revert struct {
+	Version int `json:"version" validate:"gte=1"`
+}
+
+// Variant is a version of a Product, such as a size or color, with its own
+// SKU and stock. A nil Cost means the variant sells for the product cost.
+type Variant struct {
+	ID          string    `db:"variant_id" json:"id"`
 	ProductID   string    `db:"product_id" json:"product_id"`
+	SKU         string    `db:"sku" json:"sku"`
+	Name        string    `db:"name" json:"name"`
+	Cost        *int      `db:"cost" json:"cost,omitempty"`
 	Quantity    int       `db:"quantity" json:"quantity"`
-	Paid        int       `db:"paid" json:"paid"`
+	OnHand      int       `db:"on_hand" json:"on_hand"`
+	Reserved    int       `db:"reserved" json:"reserved"`
+	Available   int       `db:"available" json:"available"`
+	Sold        int       `db:"sold" json:"sold"`
+	Revenue     int       `db:"revenue" json:"revenue"`
 	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewVariant is what we require from clients to add a Variant to a Product.
+type NewVariant struct {
+	SKU      string `json:"sku" validate:"required"`
+	Name     string `json:"name" validate:"required"`
+	Cost     *int   `json:"cost" validate:"omitempty,gte=0"`
+	Quantity int    `json:"quantity" validate:"gte=0"`
+}
+
+// UpdateVariant defines what information may be provided to modify an
+// existing Variant. All fields are optional.
+type UpdateVariant struct {
+	SKU      *string `json:"sku" validate:"omitempty,min=1"`
+	Name     *string `json:"name" validate:"omitempty,min=1"`
+	Cost     *int    `json:"cost" validate:"omitempty,gte=0"`
+	Quantity *int    `json:"quantity" validate:"omitempty,gte=0"`
 }
 
-// NewSale is what we require from clients for recording new transations
+// Sale represents one item of a transaction where some amount of a product was
+// sold. Paid is always computed by the server from the product cost, any
+// discounts that applied at the time of the sale and the sales tax. Net, Tax
+// and Gross break the amount down for tax reporting; Paid equals Gross. Sales
+// of consignment products keep the seller the product had at the time.
+// Remaining is what was left of the product available to sell right after
+// the sale and is only set by AddSale.
+type Sale struct {
+	ID           string    `db:"sale_id" json:"id"`
+	EventID      string    `db:"event_id" json:"event_id"`
+	SellerID     *string   `db:"seller_id" json:"seller_id,omitempty"`
+	CustomerID   *string   `db:"customer_id" json:"customer_id,omitempty"`
+	ProductID    string    `db:"product_id" json:"product_id"`
+	VariantID    *string   `db:"variant_id" json:"variant_id,omitempty"`
+	Quantity     int       `db:"quantity" json:"quantity"`
+	Paid         int       `db:"paid" json:"paid"`
+	Discount     int       `db:"discount" json:"discount"`
+	CouponCode   string    `db:"coupon_code" json:"coupon_code,omitempty"`
+	Jurisdiction string    `db:"jurisdiction" json:"jurisdiction,omitempty"`
+	TaxRate      int       `db:"tax_rate" json:"tax_rate"`
+	Net          int       `db:"net" json:"net"`
+	Tax          int       `db:"tax" json:"tax"`
+	Gross        int       `db:"gross" json:"gross"`
+	DateCreated  time.Time `db:"date_created" json:"date_created"`
+	Remaining    int       `db:"-" json:"-"`
+}
+
+// NewSale is what we require from clients for recording new transations. The
+// VariantID is required for products that come in variants. When an Email is
+// given the customer is sent the receipt. The optional Coupon is a code that
+// will be redeemed against the sale and the Jurisdiction selects which tax
+// rates apply. CustomerID links the sale to a known customer. A sale naming a
+// ReservationID takes the units held by it and one naming an OfferID is made
+// at the price agreed in the offer. AuctionID sells the lot of a won auction
+// at the winning bid.
 type NewSale struct {
-	Quantity int `json:"quantity"`
-	Paid     int `json:"paid"`
+	VariantID     string `json:"variant_id" validate:"omitempty,uuid"`
+	CustomerID    string `json:"customer_id" validate:"omitempty,uuid"`
+	ReservationID string `json:"reservation_id" validate:"omitempty,uuid"`
+	OfferID       string `json:"offer_id" validate:"omitempty,uuid"`
+	AuctionID     string `json:"auction_id" validate:"omitempty,uuid"`
+	Email         string `json:"email" validate:"omitempty,email"`
+	Quantity      int    `json:"quantity" validate:"gte=1"`
+	Coupon        string `json:"coupon"`
+	Jurisdiction  string `json:"jurisdiction"`
+}
+
+// Statuses of a reservation. Only active reservations hold stock.
+const (
+	ReservationActive   = "active"
+	ReservationSold     = "sold"
+	ReservationReleased = "released"
+	ReservationExpired  = "expired"
+)
+
+// Reservation sets aside units of a Product, or one of its variants, for a
+// customer until ExpiresAt. It ends when the units are sold, when it is
+// released by hand or when it expires. A reservation naming an AuctionID
+// holds the lot of that auction until it is sold or goes unsold.
+type Reservation struct {
+	ID          string    `db:"reservation_id" json:"id"`
+	ProductID   string    `db:"product_id" json:"product_id"`
+	VariantID   *string   `db:"variant_id" json:"variant_id,omitempty"`
+	AuctionID   *string   `db:"auction_id" json:"auction_id,omitempty"`
+	CustomerID  *string   `db:"customer_id" json:"customer_id,omitempty"`
+	SaleID      *string   `db:"sale_id" json:"sale_id,omitempty"`
+	Note        string    `db:"note" json:"note,omitempty"`
+	Quantity    int       `db:"quantity" json:"quantity"`
+	Status      string    `db:"status" json:"status"`
+	ExpiresAt   time.Time `db:"expires_at" json:"expires_at"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewReservation is what we require from clients to hold stock. The
+// VariantID is required for products that come in variants. The Note is for
+// whoever hands the units over, such as the name of the buyer.
+type NewReservation struct {
+	VariantID  string    `json:"variant_id" validate:"omitempty,uuid"`
+	CustomerID string    `json:"customer_id" validate:"omitempty,uuid"`
+	Note       string    `json:"note"`
+	Quantity   int       `json:"quantity" validate:"gte=1"`
+	ExpiresAt  time.Time `json:"expires_at" validate:"required"`
+}
+
+// Statuses of an offer. Pending and countered offers wait for an answer,
+// accepted ones for the sale.
+const (
+	OfferPending   = "pending"
+	OfferCountered = "countered"
+	OfferAccepted  = "accepted"
+	OfferRejected  = "rejected"
+	OfferSold      = "sold"
+)
+
+// Actions staff can take on an offer.
+const (
+	OfferAccept  = "accept"
+	OfferReject  = "reject"
+	OfferCounter = "counter"
+)
+
+// Offer is a price per unit a buyer proposes for a Product. Staff may counter
+// it with a price of their own. Once accepted, Price is the agreed price per
+// unit and holds until ExpiresAt.
+type Offer struct {
+	ID            string     `db:"offer_id" json:"id"`
+	ProductID     string     `db:"product_id" json:"product_id"`
+	VariantID     *string    `db:"variant_id" json:"variant_id,omitempty"`
+	CustomerID    *string    `db:"customer_id" json:"customer_id,omitempty"`
+	SaleID        *string    `db:"sale_id" json:"sale_id,omitempty"`
+	Buyer         string     `db:"buyer" json:"buyer,omitempty"`
+	Quantity      int        `db:"quantity" json:"quantity"`
+	Amount        int        `db:"amount" json:"amount"`
+	CounterAmount *int       `db:"counter_amount" json:"counter_amount,omitempty"`
+	Price         *int       `db:"price" json:"price,omitempty"`
+	Status        string     `db:"status" json:"status"`
+	Reason        string     `db:"reason" json:"reason,omitempty"`
+	ExpiresAt     *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	DateCreated   time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated   time.Time  `db:"date_updated" json:"date_updated"`
+}
+
+// NewOffer is what we require from clients to make an offer. Amount is the
+// price offered per unit. The VariantID is required for products that come
+// in variants.
+type NewOffer struct {
+	VariantID  string `json:"variant_id" validate:"omitempty,uuid"`
+	CustomerID string `json:"customer_id" validate:"omitempty,uuid"`
+	Buyer      string `json:"buyer"`
+	Quantity   int    `json:"quantity" validate:"gte=1"`
+	Amount     int    `json:"amount" validate:"gte=0"`
+}
+
+// OfferResponse is how staff answer an offer. Counters take the Amount per
+// unit they would sell for. Accepting locks the price until ExpiresAt, an
+// hour from now when not given. Rejections may give a Reason.
+type OfferResponse struct {
+	Action    string     `json:"action" validate:"required,oneof=accept reject counter"`
+	Amount    *int       `json:"amount" validate:"omitempty,gte=0"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	Reason    string     `json:"reason"`
+}
+
+// Statuses of an auction. Open auctions take bids until they end. Those that
+// end with a bid at or above the reserve price are won and then sold to the
+// highest bidder; the rest go unsold. Won auctions whose lot could not be
+// sold after repeated attempts are failed.
+const (
+	AuctionOpen   = "open"
+	AuctionWon    = "won"
+	AuctionSold   = "sold"
+	AuctionUnsold = "unsold"
+	AuctionFailed = "failed"
+)
+
+// Auction sells a single unit of a Product, or one of its variants, to the
+// highest bidder. Bids are taken from StartsAt until EndsAt and each must
+// beat the high bid by at least MinIncrement. A bid close to the end pushes
+// EndsAt back so nobody can snipe the lot. SaleAttempts counts the failed
+// attempts to sell the lot of a won auction, LastError tells why the last one
+// failed.
+type Auction struct {
+	ID           string    `db:"auction_id" json:"id"`
+	ProductID    string    `db:"product_id" json:"product_id"`
+	VariantID    *string   `db:"variant_id" json:"variant_id,omitempty"`
+	SaleID       *string   `db:"sale_id" json:"sale_id,omitempty"`
+	StartsAt     time.Time `db:"starts_at" json:"starts_at"`
+	EndsAt       time.Time `db:"ends_at" json:"ends_at"`
+	ReservePrice int       `db:"reserve_price" json:"reserve_price"`
+	MinIncrement int       `db:"min_increment" json:"min_increment"`
+	HighBid      *int      `db:"high_bid" json:"high_bid,omitempty"`
+	Bids         int       `db:"bids" json:"bids"`
+	Status       string    `db:"status" json:"status"`
+	SaleAttempts int       `db:"sale_attempts" json:"sale_attempts"`
+	LastError    string    `db:"last_error" json:"last_error,omitempty"`
+	DateCreated  time.Time `db:"date_created" json:"date_created"`
+	DateUpdated  time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewAuction is what we require from clients to put a Product up for auction.
+// The VariantID is required for products that come in variants.
+type NewAuction struct {
+	VariantID    string    `json:"variant_id" validate:"omitempty,uuid"`
+	StartsAt     time.Time `json:"starts_at" validate:"required"`
+	EndsAt       time.Time `json:"ends_at" validate:"required"`
+	ReservePrice int       `json:"reserve_price" validate:"gte=0"`
+	MinIncrement int       `json:"min_increment" validate:"gte=1"`
+}
+
+// Bid is an amount a bidder offers for the lot of an Auction.
+type Bid struct {
+	ID          string    `db:"bid_id" json:"id"`
+	AuctionID   string    `db:"auction_id" json:"auction_id"`
+	CustomerID  *string   `db:"customer_id" json:"customer_id,omitempty"`
+	Bidder      string    `db:"bidder" json:"bidder,omitempty"`
+	Amount      int       `db:"amount" json:"amount"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewBid is what we require from clients to bid in an auction. A winning bid
+// naming a CustomerID links the sale to that customer.
+type NewBid struct {
+	CustomerID string `json:"customer_id" validate:"omitempty,uuid"`
+	Bidder     string `json:"bidder"`
+	Amount     int    `json:"amount" validate:"gte=1"`
+}
+
+// Kinds of inventory movement.
+const (
+	MovementReceived   = "received"
+	MovementDamaged    = "damaged"
+	MovementLost       = "lost"
+	MovementCorrection = "correction"
+	MovementSale       = "sale"
+	MovementRefund     = "refund"
+)
+
+// Movement is an entry of the inventory ledger. Quantity is positive for units
+// coming in and negative for units going out. Sale movements link the sale
+// that took the units.
+type Movement struct {
+	ID          string    `db:"movement_id" json:"id"`
+	ProductID   string    `db:"product_id" json:"product_id"`
+	VariantID   *string   `db:"variant_id" json:"variant_id,omitempty"`
+	SaleID      *string   `db:"sale_id" json:"sale_id,omitempty"`
+	Kind        string    `db:"kind" json:"kind"`
+	Quantity    int       `db:"quantity" json:"quantity"`
+	Reason      string    `db:"reason" json:"reason"`
+	Actor       string    `db:"actor" json:"actor,omitempty"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
+
+// NewAdjustment is what we require from clients to change stock by hand. The
+// Quantity is the number of units received, damaged, lost or refunded and
+// must be positive. Corrections take a signed change instead. The VariantID
+// is required for products that come in variants. Refunds may name the sale
+// the units were bought in. The sale itself is left as it was; what the
+// refund gave back is worked out from it in settlements and tax reports.
+type NewAdjustment struct {
+	VariantID string `json:"variant_id" validate:"omitempty,uuid"`
+	SaleID    string `json:"sale_id" validate:"omitempty,uuid"`
+	Kind      string `json:"kind" validate:"required,oneof=received damaged lost correction refund"`
+	Quantity  int    `json:"quantity" validate:"required"`
+	Reason    string `json:"reason" validate:"required"`
+}
+
+// AdjustmentEvent is published when stock is adjusted by hand. Besides the
+// movement itself it carries the event and stock of the product right after
+// it.
+type AdjustmentEvent struct {
+	Movement
+	EventID  string `json:"event_id"`
+	Category string `json:"category"`
+	OnHand   int    `json:"on_hand"`
+}
+
+// DayTotal adds up the sales of an event made on a single day, in UTC, and
+// the units refunded that day along with what was paid back for them.
+type DayTotal struct {
+	Day           time.Time `db:"day" json:"day"`
+	Sales         int       `db:"sales" json:"sales"`
+	Units         int       `db:"units" json:"units"`
+	Discount      int       `db:"discount" json:"discount"`
+	Net           int       `db:"net" json:"net"`
+	Tax           int       `db:"tax" json:"tax"`
+	Gross         int       `db:"gross" json:"gross"`
+	RefundedUnits int       `db:"refunded_units" json:"refunded_units"`
+	RefundedGross int       `db:"refunded_gross" json:"refunded_gross"`
+}
+
+// SaleEvent is published when a sale is recorded. Besides the sale itself it
+// carries the state of the product right after the sale.
+type SaleEvent struct {
+	Sale
+	Category  string `json:"category"`
+	Sold      int    `json:"sold"`
+	Remaining int    `json:"remaining"`
 }
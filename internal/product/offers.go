@@ -0,0 +1,263 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// OfferWindow is how long an accepted price holds when staff do not say.
+const OfferWindow = time.Hour
+
+// Predefined errors for offer failure scenarios
+var (
+	ErrOfferNotFound    = errors.New("offer not found")
+	ErrOfferClosed      = errors.New("offer has already been answered")
+	ErrOfferNotAccepted = errors.New("offer has not been accepted")
+	ErrOfferExpired     = errors.New("agreed price has expired")
+	ErrOfferMismatch    = errors.New("sale does not match the offer")
+	ErrCounterAmount    = errors.New("counters need an amount")
+)
+
+// ListOffers gives the offers made on a Product of an event, the most recent
+// first.
+func ListOffers(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Offer, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	list := []Offer{}
+
+	const q = `SELECT o.* FROM offers AS o
+	JOIN products AS p ON p.product_id = o.product_id
+	WHERE o.product_id = $1 AND p.event_id = $2
+	ORDER BY o.date_created DESC`
+
+	if err := db.SelectContext(ctx, &list, q, productID, eventID); err != nil {
+		return nil, fmt.Errorf("selecting offers: %w", err)
+	}
+
+	return list, nil
+}
+
+// RetrieveOffer gives a single Offer of an event
+func RetrieveOffer(ctx context.Context, db *sqlx.DB, eventID, id string) (*Offer, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	var o Offer
+
+	const q = `SELECT o.* FROM offers AS o
+	JOIN products AS p ON p.product_id = o.product_id
+	WHERE o.offer_id = $1 AND p.event_id = $2`
+
+	if err := db.GetContext(ctx, &o, q, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOfferNotFound
+		}
+		return nil, fmt.Errorf("selecting offer: %w", err)
+	}
+
+	return &o, nil
+}
+
+// MakeOffer records an offer on a Product of an event. Offers below the
+// minimum price of the product are rejected as they come in.
+func MakeOffer(ctx context.Context, db *sqlx.DB, eventID, productID string, no NewOffer, now time.Time) (*Offer, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := retrieve(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	o := Offer{
+		ID:          uuid.New().String(),
+		ProductID:   p.ID,
+		Buyer:       no.Buyer,
+		Quantity:    no.Quantity,
+		Amount:      no.Amount,
+		Status:      OfferPending,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+	if no.CustomerID != "" {
+		o.CustomerID = &no.CustomerID
+	}
+	if o.Amount < p.MinPrice {
+		o.Status = OfferRejected
+		o.Reason = "below the minimum price"
+	}
+
+	switch {
+	case no.VariantID != "":
+		v, err := RetrieveVariant(ctx, tx, eventID, p.ID, no.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		o.VariantID = &v.ID
+	case len(p.Variants) > 0:
+		return nil, ErrVariantRequired
+	}
+
+	const q = `INSERT INTO offers
+	(offer_id, product_id, variant_id, customer_id, buyer, quantity, amount,
+		status, reason, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+
+	_, err = tx.ExecContext(ctx, q,
+		o.ID, o.ProductID, o.VariantID, o.CustomerID, o.Buyer, o.Quantity, o.Amount,
+		o.Status, o.Reason, o.DateCreated, o.DateUpdated,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && o.CustomerID != nil {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, fmt.Errorf("inserting offer: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "offer", o.ID, nil, o.audited(), now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing offer: %w", err)
+	}
+
+	return &o, nil
+}
+
+// RespondToOffer accepts, rejects or counters an Offer of an event that is
+// waiting for an answer. Accepting a countered offer agrees to the counter.
+func RespondToOffer(ctx context.Context, db *sqlx.DB, eventID, id string, resp OfferResponse, now time.Time) (*Offer, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The offer is locked so two answers to it can not both go through.
+	var o Offer
+
+	const sel = `SELECT o.* FROM offers AS o
+	JOIN products AS p ON p.product_id = o.product_id
+	WHERE o.offer_id = $1 AND p.event_id = $2
+	FOR UPDATE OF o`
+
+	if err := tx.GetContext(ctx, &o, sel, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOfferNotFound
+		}
+		return nil, fmt.Errorf("selecting offer: %w", err)
+	}
+
+	if o.Status != OfferPending && o.Status != OfferCountered {
+		return nil, ErrOfferClosed
+	}
+	before := o
+
+	switch resp.Action {
+	case OfferAccept:
+		price := o.Amount
+		if o.CounterAmount != nil {
+			price = *o.CounterAmount
+		}
+		expires := now.Add(OfferWindow).UTC()
+		if resp.ExpiresAt != nil {
+			if !resp.ExpiresAt.After(now) {
+				return nil, ErrInvalidExpiry
+			}
+			expires = resp.ExpiresAt.UTC()
+		}
+		o.Status = OfferAccepted
+		o.Price = &price
+		o.ExpiresAt = &expires
+	case OfferReject:
+		o.Status = OfferRejected
+		o.Reason = resp.Reason
+	case OfferCounter:
+		if resp.Amount == nil {
+			return nil, ErrCounterAmount
+		}
+		o.Status = OfferCountered
+		o.CounterAmount = resp.Amount
+	}
+	o.DateUpdated = now.UTC()
+
+	const q = `UPDATE offers SET
+		"status" = $2,
+		"counter_amount" = $3,
+		"price" = $4,
+		"reason" = $5,
+		"expires_at" = $6,
+		"date_updated" = $7
+		WHERE offer_id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, o.ID, o.Status, o.CounterAmount, o.Price, o.Reason, o.ExpiresAt, o.DateUpdated); err != nil {
+		return nil, fmt.Errorf("updating offer: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionUpdate, "offer", o.ID, before.audited(), o.audited(), now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing offer: %w", err)
+	}
+
+	return &o, nil
+}
+
+// takeOffer locks an accepted Offer of a product for the sale made at its
+// price.
+func takeOffer(ctx context.Context, tx sqlx.QueryerContext, productID, id string, now time.Time) (*Offer, error) {
+	var o Offer
+
+	const q = `SELECT * FROM offers
+	WHERE offer_id = $1 AND product_id = $2
+	FOR UPDATE`
+
+	if err := sqlx.GetContext(ctx, tx, &o, q, id, productID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrOfferNotFound
+		}
+		return nil, fmt.Errorf("selecting offer: %w", err)
+	}
+
+	if o.Status != OfferAccepted {
+		return nil, ErrOfferNotAccepted
+	}
+	if o.ExpiresAt == nil || !o.ExpiresAt.After(now) {
+		return nil, ErrOfferExpired
+	}
+
+	return &o, nil
+}
+
+// audited gives the Offer as it is written to the audit log, without the name
+// of the buyer for the same reason reservations leave out their note.
+func (o Offer) audited() Offer {
+	o.Buyer = ""
+	return o
+}
@@ -8,129 +8,400 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/images"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
 	"github.com/jmoiron/sqlx"
 )
 
 // Predefined errors for known failure scenarios
 var (
-	ErrNotFound  = errors.New("product not found")
-	ErrInvalidID = errors.New("id provided was not a valid UUID")
+	ErrNotFound     = errors.New("product not found")
+	ErrSaleNotFound = errors.New("sale not found")
+	ErrInvalidID    = errors.New("id provided was not a valid UUID")
+
+	ErrEventNotFound    = errors.New("event not found")
+	ErrSellerNotFound   = errors.New("seller not found")
+	ErrCustomerNotFound = errors.New("customer not found")
 )
 
-// List returns all known products
-func List(ctx context.Context, db *sqlx.DB) ([]Product, error) {
+// List returns all products of an event
+func List(ctx context.Context, db *sqlx.DB, eventID string) ([]Product, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, err
+	}
+
 	list := []Product{}
 
-	const q = `SELECT
-		p.product_id, p.name, p.cost, p.quantity, p.date_updated, p.date_created,
+	q := `SELECT
+		p.product_id, p.event_id, p.seller_id, p.name, p.category, p.cost, p.low_stock_threshold, p.min_price, p.archived,
+		p.date_updated, p.date_created,` + stockColumns + `,
 		COALESCE(SUM(s.paid), 0) AS revenue,
 		COALESCE(SUM(s.quantity), 0) AS sold
 	FROM products AS p
 	LEFT JOIN sales AS s ON p.product_id = s.product_id
+	WHERE p.event_id = $1
 	GROUP BY p.product_id`
 
-	if err := db.SelectContext(ctx, &list, q); err != nil {
+	if err := db.SelectContext(ctx, &list, q, eventID); err != nil {
+		return nil, err
+	}
+
+	if err := attachImages(ctx, db, list); err != nil {
+		return nil, err
+	}
+	if err := attachVariants(ctx, db, eventID, list); err != nil {
 		return nil, err
 	}
 
 	return list, nil
 }
 
-// Retrieve gives a single product
-func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Product, error) {
+// Retrieve gives a single product of an event
+func Retrieve(ctx context.Context, db *sqlx.DB, eventID, id string) (*Product, error) {
+	return retrieve(ctx, db, eventID, id)
+}
 
-	if _, err := uuid.Parse(id); err != nil {
-		return nil, ErrInvalidID
+// retrieve gives a single product of an event as seen by db, which may be a
+// transaction that changed it.
+func retrieve(ctx context.Context, db sqlx.QueryerContext, eventID, id string) (*Product, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
 	}
 
 	var p Product
 
-	const q = `
+	q := `
 	SELECT
-		p.product_id, p.name, p.cost, p.quantity, p.date_updated, p.date_created,
+		p.product_id, p.event_id, p.seller_id, p.name, p.category, p.cost, p.low_stock_threshold, p.min_price, p.archived,
+		p.date_updated, p.date_created,` + stockColumns + `,
 		COALESCE(SUM(s.paid), 0) AS revenue,
 		COALESCE(SUM(s.quantity), 0) AS sold
 	FROM products AS p
 	LEFT JOIN sales AS s ON p.product_id = s.product_id
-	WHERE p.product_id = $1
+	WHERE p.product_id = $1 AND p.event_id = $2
 	GROUP BY p.product_id`
 
-	if err := db.GetContext(ctx, &p, q, id); err != nil {
+	if err := sqlx.GetContext(ctx, db, &p, q, id, eventID); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
 
-	return &p, nil
+	ps := []Product{p}
+	if err := attachImages(ctx, db, ps); err != nil {
+		return nil, err
+	}
+	if err := attachVariants(ctx, db, eventID, ps); err != nil {
+		return nil, err
+	}
+
+	return &ps[0], nil
 }
 
-// Create makes a new Product
-func Create(ctx context.Context, db *sqlx.DB, np NewProduct, now time.Time) (*Product, error) {
+// Create makes a new Product at an event
+func Create(ctx context.Context, db *sqlx.DB, eventID string, np NewProduct, now time.Time) (*Product, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, err
+	}
+
 	p := Product{
 		ID:          uuid.New().String(),
+		EventID:     eventID,
 		Name:        np.Name,
+		Category:    np.Category,
 		Cost:        np.Cost,
 		Quantity:    np.Quantity,
+		OnHand:      np.Quantity,
+		Available:   np.Quantity,
+		LowStock:    np.LowStock,
+		MinPrice:    np.MinPrice,
+		Images:      []images.Image{},
+		Variants:    []Variant{},
 		DateCreated: now.UTC(),
 		DateUpdated: now.UTC(),
 	}
 
+	if np.SellerID != "" {
+		p.SellerID = &np.SellerID
+	}
+
 	const q = `INSERT INTO products
-	(product_id, name, cost, quantity, date_created, date_updated)
-	VALUES($1, $2, $3, $4, $5, $6)`
+	(product_id, event_id, seller_id, name, category, cost, low_stock_threshold, min_price, date_created, date_updated)
+	VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if p.SellerID != nil {
+		if err := checkSeller(ctx, tx, eventID, *p.SellerID); err != nil {
+			return nil, err
+		}
+	}
 
-	if _, err := db.ExecContext(ctx, q, p.ID, p.Name, p.Cost, p.Quantity, p.DateCreated, p.DateUpdated); err != nil {
+	if _, err := tx.ExecContext(ctx, q, p.ID, p.EventID, p.SellerID, p.Name, p.Category, p.Cost, p.LowStock, p.MinPrice, p.DateCreated, p.DateUpdated); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, ErrEventNotFound
+		}
 		return nil, fmt.Errorf("inserting product: %w", err)
 	}
 
+	m := Movement{
+		ID:          uuid.New().String(),
+		ProductID:   p.ID,
+		Kind:        MovementReceived,
+		Quantity:    p.Quantity,
+		Reason:      "initial stock",
+		DateCreated: p.DateCreated,
+	}
+	if err := recordMovement(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	if err := recordVersion(ctx, tx, p); err != nil {
+		return nil, err
+	}
+
+	if err := webhook.Publish(ctx, tx, webhook.EventProductCreated, p, now); err != nil {
+		return nil, err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "product", p.ID, nil, p, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing product: %w", err)
+	}
+
 	return &p, nil
 }
 
 // Update modifies data about a Product. It will error if the specified ID is
-// invalid or does not reference an existing Product.
-func Update(ctx context.Context, db *sqlx.DB, id string, update UpdateProduct, now time.Time) error {
-	p, err := Retrieve(ctx, db, id)
+// invalid or does not reference an existing Product. Every update is kept as
+// a new version of the product. Changing the quantity records a correction in
+// the inventory ledger, which can not take the stock on hand below zero; the
+// stock of products with variants is changed through their variants.
+func Update(ctx context.Context, db *sqlx.DB, eventID, id string, update UpdateProduct, now time.Time) error {
+	if err := checkIDs(eventID, id); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The product is locked before it is read so concurrent updates apply
+	// one after the other, each to the product as the one before left it.
+	if _, err := lockStock(ctx, tx, eventID, id); err != nil {
+		return err
+	}
+
+	p, err := retrieve(ctx, tx, eventID, id)
 	if err != nil {
 		return err
 	}
+	before := *p
 
 	if update.Name != nil {
 		p.Name = *update.Name
 	}
+	if update.Category != nil {
+		p.Category = *update.Category
+	}
 	if update.Cost != nil {
 		p.Cost = *update.Cost
 	}
+	var correction int
 	if update.Quantity != nil {
+		if len(p.Variants) > 0 {
+			return ErrVariantRequired
+		}
+		correction = *update.Quantity - p.Quantity
+		if p.OnHand+correction < 0 {
+			return ErrInsufficientStock
+		}
 		p.Quantity = *update.Quantity
+		p.OnHand += correction
+		p.Available += correction
+	}
+	if update.LowStock != nil {
+		p.LowStock = *update.LowStock
 	}
-	p.DateUpdated = now
+	if update.MinPrice != nil {
+		p.MinPrice = *update.MinPrice
+	}
+	if update.SellerID != nil {
+		p.SellerID = update.SellerID
+		if *update.SellerID == "" {
+			p.SellerID = nil
+		}
+	}
+	if update.Archived != nil {
+		p.Archived = *update.Archived
+	}
+	p.DateUpdated = now.UTC()
 
 	const q = `UPDATE products SET
 		"name" = $2,
-		"cost" = $3,
-		"quantity" = $4,
-		"date_updated" = $5
-		WHERE product_id = $1`
+		"category" = $3,
+		"cost" = $4,
+		"low_stock_threshold" = $5,
+		"min_price" = $6,
+		"seller_id" = $7,
+		"archived" = $8,
+		"date_updated" = $9
+		WHERE product_id = $1 AND event_id = $10`
 
-	_, err = db.ExecContext(ctx, q, id, p.Name, p.Cost, p.Quantity, p.DateUpdated)
+	if p.SellerID != nil {
+		if err := checkSeller(ctx, tx, eventID, *p.SellerID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, q, id, p.Name, p.Category, p.Cost, p.LowStock, p.MinPrice, p.SellerID, p.Archived, p.DateUpdated, eventID)
 	if err != nil {
 		return fmt.Errorf("updating product: %w", err)
 	}
 
+	if correction != 0 {
+		m := Movement{
+			ID:          uuid.New().String(),
+			ProductID:   p.ID,
+			Kind:        MovementCorrection,
+			Quantity:    correction,
+			Reason:      fmt.Sprintf("quantity set to %d", p.Quantity),
+			DateCreated: now.UTC(),
+		}
+		if err := recordMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	if err := recordVersion(ctx, tx, *p); err != nil {
+		return err
+	}
+
+	if err := webhook.Publish(ctx, tx, webhook.EventProductUpdated, p, now); err != nil {
+		return err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionUpdate, "product", p.ID, before, p, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing product: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the product of an event identified by a given ID. The rows
+// of its images go with it; the images are returned so their files can be
+// removed from storage.
+func Delete(ctx context.Context, db *sqlx.DB, eventID, id string, now time.Time) ([]images.Image, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleting a product that does not exist is not an error but there is
+	// nothing to tell anyone about either.
+	before, err := retrieve(ctx, tx, eventID, id)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	const q = `DELETE FROM products WHERE product_id = $1 AND event_id = $2 RETURNING category`
+
+	data := struct {
+		ID       string `json:"id"`
+		EventID  string `json:"event_id"`
+		Category string `json:"category"`
+	}{ID: id, EventID: eventID}
+
+	if err := tx.GetContext(ctx, &data.Category, q, id, eventID); err != nil {
+
+		// Someone else deleted it first.
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("deleting product (id: %s): %w", id, err)
+	}
+
+	if err := webhook.Publish(ctx, tx, webhook.EventProductDeleted, data, now); err != nil {
+		return nil, err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionDelete, "product", id, before, nil, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing product deletion: %w", err)
+	}
+
+	return before.Images, nil
+}
+
+// checkIDs makes sure every given ID is a valid UUID.
+func checkIDs(ids ...string) error {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return ErrInvalidID
+		}
+	}
 	return nil
 }
 
-// Delete removes the product identified by a given ID.
-func Delete(ctx context.Context, db *sqlx.DB, id string) error {
-	if _, err := uuid.Parse(id); err != nil {
-		return ErrInvalidID
+// checkSeller makes sure a seller exists at the event of a product.
+func checkSeller(ctx context.Context, db sqlx.QueryerContext, eventID, sellerID string) error {
+	var n int
+
+	const q = `SELECT COUNT(*) FROM sellers WHERE seller_id = $1 AND event_id = $2`
+
+	if err := sqlx.GetContext(ctx, db, &n, q, sellerID, eventID); err != nil {
+		return fmt.Errorf("selecting seller: %w", err)
+	}
+	if n == 0 {
+		return ErrSellerNotFound
 	}
 
-	const q = `DELETE FROM products WHERE product_id = $1`
+	return nil
+}
 
-	if _, err := db.ExecContext(ctx, q, id); err != nil {
-		return fmt.Errorf("deleting product (id: %s): %w", id, err)
+// attachImages loads the images of every product in the list.
+func attachImages(ctx context.Context, db sqlx.QueryerContext, list []Product) error {
+	ids := make([]string, len(list))
+	for i, p := range list {
+		ids[i] = p.ID
+	}
+
+	m, err := images.ForProducts(ctx, db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range list {
+		list[i].Images = m[list[i].ID]
+		if list[i].Images == nil {
+			list[i].Images = []images.Image{}
+		}
 	}
 
 	return nil
@@ -2,11 +2,14 @@ package product_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/ivan-sabo/garagesale/internal/event"
 	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
 	"github.com/ivan-sabo/garagesale/internal/product"
 	"github.com/ivan-sabo/garagesale/internal/schema"
 )
@@ -25,12 +28,12 @@ func TestProducts(t *testing.T) {
 
 	now := time.Now().UTC()
 
-	p0, err := product.Create(ctx, db, np, now)
+	p0, err := product.Create(ctx, db, event.DefaultID, np, now)
 	if err != nil {
 		t.Fatalf("could not create product: %v", err)
 	}
 
-	p1, err := product.Retrieve(ctx, db, p0.ID)
+	p1, err := product.Retrieve(ctx, db, event.DefaultID, p0.ID)
 	if err != nil {
 		t.Fatalf("could not retrive product: %v", err)
 	}
@@ -50,7 +53,7 @@ func TestProductList(t *testing.T) {
 
 	ctx := context.Background()
 
-	ps, err := product.List(ctx, db)
+	ps, err := product.List(ctx, db, event.DefaultID)
 	if err != nil {
 		t.Fatalf("listing products: %s", err)
 	}
@@ -58,3 +61,507 @@ func TestProductList(t *testing.T) {
 		t.Fatalf("expected product list size %v, got %v", exp, got)
 	}
 }
+
+func TestVariants(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "T-Shirt", Cost: 10, Quantity: 1}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	fifteen := 15
+	small, err := product.AddVariant(ctx, db, event.DefaultID, p.ID, product.NewVariant{SKU: "TS-S", Name: "S", Quantity: 5}, now)
+	if err != nil {
+		t.Fatalf("adding variant: %s", err)
+	}
+	large, err := product.AddVariant(ctx, db, event.DefaultID, p.ID, product.NewVariant{SKU: "TS-L", Name: "L", Cost: &fifteen, Quantity: 3}, now)
+	if err != nil {
+		t.Fatalf("adding variant: %s", err)
+	}
+
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{Quantity: 1}, p.ID, now); err != product.ErrVariantRequired {
+		t.Fatalf("expected %v selling without a variant, got %v", product.ErrVariantRequired, err)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{VariantID: small.ID, Quantity: 2}, p.ID, now); err != nil {
+		t.Fatalf("selling small: %s", err)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{VariantID: large.ID, Quantity: 1}, p.ID, now); err != nil {
+		t.Fatalf("selling large: %s", err)
+	}
+
+	got, err := product.Retrieve(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("retrieving product: %s", err)
+	}
+
+	if exp := 8; got.Quantity != exp {
+		t.Fatalf("expected quantity %v, got %v", exp, got.Quantity)
+	}
+	if exp := 3; got.Sold != exp {
+		t.Fatalf("expected sold %v, got %v", exp, got.Sold)
+	}
+	if exp := 35; got.Revenue != exp {
+		t.Fatalf("expected revenue %v, got %v", exp, got.Revenue)
+	}
+	if exp := 2; len(got.Variants) != exp {
+		t.Fatalf("expected %v variants, got %v", exp, len(got.Variants))
+	}
+	if v := got.Variants[0]; v.Sold != 2 || v.Quantity-v.Sold != 3 {
+		t.Fatalf("expected small variant to have sold 2 with 3 left, got %+v", v)
+	}
+
+	if err := product.DeleteVariant(ctx, db, event.DefaultID, p.ID, small.ID, now); err != product.ErrVariantInUse {
+		t.Fatalf("expected %v deleting a sold variant, got %v", product.ErrVariantInUse, err)
+	}
+	unstocked, err := product.AddVariant(ctx, db, event.DefaultID, p.ID, product.NewVariant{SKU: "TS-XL", Name: "XL"}, now)
+	if err != nil {
+		t.Fatalf("adding variant: %s", err)
+	}
+	if err := product.DeleteVariant(ctx, db, event.DefaultID, p.ID, unstocked.ID, now); err != nil {
+		t.Fatalf("deleting a variant never stocked: %s", err)
+	}
+	if _, err := product.RetrieveVariant(ctx, db, event.DefaultID, p.ID, unstocked.ID); err != product.ErrVariantNotFound {
+		t.Fatalf("expected %v after deleting, got %v", product.ErrVariantNotFound, err)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Lamp", Cost: 20, Quantity: 10}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{Quantity: 3}, p.ID, now); err != nil {
+		t.Fatalf("selling: %s", err)
+	}
+
+	damaged := product.NewAdjustment{Kind: product.MovementDamaged, Quantity: 2, Reason: "dropped"}
+	m, err := product.Adjust(ctx, db, event.DefaultID, p.ID, damaged, "alice", now)
+	if err != nil {
+		t.Fatalf("adjusting: %s", err)
+	}
+	if exp := -2; m.Quantity != exp {
+		t.Fatalf("expected damaged movement of %v, got %v", exp, m.Quantity)
+	}
+
+	lost := product.NewAdjustment{Kind: product.MovementLost, Quantity: 6, Reason: "missing"}
+	if _, err := product.Adjust(ctx, db, event.DefaultID, p.ID, lost, "alice", now); err != product.ErrInsufficientStock {
+		t.Fatalf("expected %v losing more than on hand, got %v", product.ErrInsufficientStock, err)
+	}
+	two := 2
+	if err := product.Update(ctx, db, event.DefaultID, p.ID, product.UpdateProduct{Quantity: &two}, now); err != product.ErrInsufficientStock {
+		t.Fatalf("expected %v setting the quantity below what was sold, got %v", product.ErrInsufficientStock, err)
+	}
+
+	got, err := product.Retrieve(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("retrieving product: %s", err)
+	}
+	if got.Quantity != 8 || got.Sold != 3 || got.OnHand != 5 {
+		t.Fatalf("expected quantity 8, sold 3 and 5 on hand, got %v, %v and %v", got.Quantity, got.Sold, got.OnHand)
+	}
+
+	list, err := product.ListMovements(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("listing movements: %s", err)
+	}
+
+	var total int
+	for _, m := range list {
+		total += m.Quantity
+	}
+	if exp := 3; len(list) != exp {
+		t.Fatalf("expected %v movements, got %v", exp, len(list))
+	}
+	if total != got.OnHand {
+		t.Fatalf("expected movements to add up to %v on hand, got %v", got.OnHand, total)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	created := time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC)
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Chair", Cost: 30, Quantity: 4, MinPrice: 20}, created)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	name, cost, minPrice, archived := "Armchair", 45, 40, true
+	updated := created.Add(24 * time.Hour)
+	if err := product.Update(ctx, db, event.DefaultID, p.ID, product.UpdateProduct{Name: &name, Cost: &cost, MinPrice: &minPrice, Archived: &archived}, updated); err != nil {
+		t.Fatalf("updating product: %s", err)
+	}
+
+	versions, err := product.ListVersions(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("listing versions: %s", err)
+	}
+	if exp := 2; len(versions) != exp {
+		t.Fatalf("expected %v versions, got %v", exp, len(versions))
+	}
+
+	old, err := product.RetrieveAsOf(ctx, db, event.DefaultID, p.ID, created.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("retrieving as of: %s", err)
+	}
+	if old.Name != "Chair" || old.Cost != 30 || old.MinPrice != 20 || old.Archived || old.Quantity != 4 {
+		t.Fatalf("expected the product as created, got %+v", old)
+	}
+
+	if _, err := product.RetrieveAsOf(ctx, db, event.DefaultID, p.ID, created.Add(-time.Hour)); err != product.ErrNotFound {
+		t.Fatalf("expected %v before the product existed, got %v", product.ErrNotFound, err)
+	}
+
+	if err := product.RevertTo(ctx, db, event.DefaultID, p.ID, 1, updated.Add(time.Hour)); err != nil {
+		t.Fatalf("reverting: %s", err)
+	}
+
+	got, err := product.Retrieve(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("retrieving product: %s", err)
+	}
+	if got.Name != "Chair" || got.Cost != 30 || got.MinPrice != 20 || got.Archived {
+		t.Fatalf("expected the product reverted to version 1, got %+v", got)
+	}
+
+	if versions, err = product.ListVersions(ctx, db, event.DefaultID, p.ID); err != nil || len(versions) != 3 {
+		t.Fatalf("expected the revert recorded as version 3, got %v versions: %v", len(versions), err)
+	}
+	if _, err := product.AddVariant(ctx, db, event.DefaultID, p.ID, product.NewVariant{SKU: "CH-R", Name: "Red"}, updated.Add(2*time.Hour)); err != nil {
+		t.Fatalf("adding variant: %s", err)
+	}
+	if versions, err = product.ListVersions(ctx, db, event.DefaultID, p.ID); err != nil || len(versions) != 4 {
+		t.Fatalf("expected the new variant recorded as version 4, got %v versions: %v", len(versions), err)
+	}
+}
+
+func TestEventIsolation(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ev, err := event.Create(ctx, db, event.NewEvent{Name: "Spring Sale"}, now)
+	if err != nil {
+		t.Fatalf("creating event: %s", err)
+	}
+
+	p, err := product.Create(ctx, db, ev.ID, product.NewProduct{Name: "Bike", Cost: 80, Quantity: 1}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	if _, err := product.Retrieve(ctx, db, event.DefaultID, p.ID); err != product.ErrNotFound {
+		t.Fatalf("expected %v from another event, got %v", product.ErrNotFound, err)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{Quantity: 1}, p.ID, now); err != product.ErrNotFound {
+		t.Fatalf("expected %v selling from another event, got %v", product.ErrNotFound, err)
+	}
+	if imgs, err := product.Delete(ctx, db, event.DefaultID, p.ID, now); err != nil || imgs != nil {
+		t.Fatalf("expected nothing deleted from another event, got %v: %v", imgs, err)
+	}
+	if _, err := product.Retrieve(ctx, db, ev.ID, p.ID); err != nil {
+		t.Fatalf("expected the product kept in its own event, got %v", err)
+	}
+
+	// SKUs only have to be unique within an event.
+	other, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Helmet", Cost: 20, Quantity: 1}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+	if _, err := product.AddVariant(ctx, db, ev.ID, p.ID, product.NewVariant{SKU: "BK-1", Name: "Red"}, now); err != nil {
+		t.Fatalf("adding variant: %s", err)
+	}
+	if _, err := product.AddVariant(ctx, db, event.DefaultID, other.ID, product.NewVariant{SKU: "BK-1", Name: "Red"}, now); err != nil {
+		t.Fatalf("adding a variant with a sku used at another event: %s", err)
+	}
+	if _, err := product.AddVariant(ctx, db, ev.ID, p.ID, product.NewVariant{SKU: "BK-1", Name: "Blue"}, now); err != product.ErrSKUExists {
+		t.Fatalf("expected %v reusing a sku at the same event, got %v", product.ErrSKUExists, err)
+	}
+	if got, _, err := product.Lookup(ctx, db, ev.ID, "BK-1"); err != nil || got.ID != p.ID {
+		t.Fatalf("expected the sku to resolve to the product of the event, got %v: %v", got, err)
+	}
+
+	list, err := product.List(ctx, db, event.DefaultID)
+	if err != nil {
+		t.Fatalf("listing products: %s", err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("expected only the helmet in the default event, got %d", len(list))
+	}
+
+	half := pricing.NewMarkdown{Kind: pricing.KindPercent, Amount: 50, StartsAt: now.Add(-time.Minute)}
+	if _, err := pricing.CreateMarkdown(ctx, db, ev.ID, half, now); err != nil {
+		t.Fatalf("creating markdown: %s", err)
+	}
+	s, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{Quantity: 1}, other.ID, now)
+	if err != nil {
+		t.Fatalf("selling: %s", err)
+	}
+	if s.Net != 20 {
+		t.Fatalf("expected a markdown of another event not to apply, got %d paid for 20", s.Net)
+	}
+
+	if err := event.Delete(ctx, db, ev.ID); err != event.ErrInUse {
+		t.Fatalf("expected %v deleting an event with products, got %v", event.ErrInUse, err)
+	}
+}
+
+func TestReservations(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Desk", Cost: 40, Quantity: 3}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	hold := product.NewReservation{Quantity: 2, Note: "back in an hour", ExpiresAt: now.Add(time.Hour)}
+	res, err := product.Reserve(ctx, db, event.DefaultID, p.ID, hold, now)
+	if err != nil {
+		t.Fatalf("reserving: %s", err)
+	}
+	if _, err := product.Reserve(ctx, db, event.DefaultID, p.ID, hold, now); err != product.ErrInsufficientStock {
+		t.Fatalf("expected %v reserving more than is available, got %v", product.ErrInsufficientStock, err)
+	}
+
+	got, err := product.Retrieve(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("retrieving product: %s", err)
+	}
+	if got.OnHand != 3 || got.Reserved != 2 || got.Available != 1 {
+		t.Fatalf("expected 3 on hand, 2 reserved and 1 available, got %d, %d and %d", got.OnHand, got.Reserved, got.Available)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{Quantity: 2}, p.ID, now); err != product.ErrInsufficientStock {
+		t.Fatalf("expected %v selling reserved units, got %v", product.ErrInsufficientStock, err)
+	}
+
+	s, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{ReservationID: res.ID, Quantity: 2}, p.ID, now)
+	if err != nil {
+		t.Fatalf("converting reservation: %s", err)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{ReservationID: res.ID, Quantity: 1}, p.ID, now); err != product.ErrReservationClosed {
+		t.Fatalf("expected %v selling a reservation twice, got %v", product.ErrReservationClosed, err)
+	}
+
+	if res, err = product.RetrieveReservation(ctx, db, event.DefaultID, res.ID); err != nil {
+		t.Fatalf("retrieving reservation: %s", err)
+	}
+	if res.Status != product.ReservationSold || res.SaleID == nil || *res.SaleID != s.ID {
+		t.Fatalf("expected the reservation sold in sale %s, got %+v", s.ID, res)
+	}
+
+	hold.Quantity = 1
+	hold.ExpiresAt = now.Add(time.Minute)
+	if _, err := product.Reserve(ctx, db, event.DefaultID, p.ID, hold, now); err != nil {
+		t.Fatalf("reserving: %s", err)
+	}
+	n, err := product.ExpireReservations(ctx, db, now.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("expiring reservations: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 reservation expired, got %d", n)
+	}
+
+	if got, err = product.Retrieve(ctx, db, event.DefaultID, p.ID); err != nil {
+		t.Fatalf("retrieving product: %s", err)
+	}
+	if got.Reserved != 0 || got.Available != 1 {
+		t.Fatalf("expected the expired units available again, got %d reserved and %d available", got.Reserved, got.Available)
+	}
+}
+
+func TestOffers(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Lamp", Cost: 30, MinPrice: 20, Quantity: 2}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	low, err := product.MakeOffer(ctx, db, event.DefaultID, p.ID, product.NewOffer{Buyer: "Ann", Quantity: 1, Amount: 10}, now)
+	if err != nil {
+		t.Fatalf("making offer: %s", err)
+	}
+	if low.Status != product.OfferRejected {
+		t.Fatalf("expected an offer below the minimum price rejected, got %q", low.Status)
+	}
+
+	o, err := product.MakeOffer(ctx, db, event.DefaultID, p.ID, product.NewOffer{Buyer: "Bob", Quantity: 1, Amount: 22}, now)
+	if err != nil {
+		t.Fatalf("making offer: %s", err)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{OfferID: o.ID, Quantity: 1}, p.ID, now); err != product.ErrOfferNotAccepted {
+		t.Fatalf("expected %v selling a pending offer, got %v", product.ErrOfferNotAccepted, err)
+	}
+
+	counter := 25
+	if _, err := product.RespondToOffer(ctx, db, event.DefaultID, o.ID, product.OfferResponse{Action: product.OfferCounter, Amount: &counter}, now); err != nil {
+		t.Fatalf("countering offer: %s", err)
+	}
+	if o, err = product.RespondToOffer(ctx, db, event.DefaultID, o.ID, product.OfferResponse{Action: product.OfferAccept}, now); err != nil {
+		t.Fatalf("accepting offer: %s", err)
+	}
+	if o.Status != product.OfferAccepted || o.Price == nil || *o.Price != counter {
+		t.Fatalf("expected the offer accepted at %d, got %+v", counter, o)
+	}
+	if _, err := product.RespondToOffer(ctx, db, event.DefaultID, o.ID, product.OfferResponse{Action: product.OfferReject}, now); err != product.ErrOfferClosed {
+		t.Fatalf("expected %v answering an offer twice, got %v", product.ErrOfferClosed, err)
+	}
+
+	s, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{OfferID: o.ID, Quantity: 1}, p.ID, now)
+	if err != nil {
+		t.Fatalf("selling at the agreed price: %s", err)
+	}
+	if s.Net != counter {
+		t.Fatalf("expected the sale at the agreed price of %d, got %d", counter, s.Net)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{OfferID: o.ID, Quantity: 1}, p.ID, now); err != product.ErrOfferNotAccepted {
+		t.Fatalf("expected %v selling an offer twice, got %v", product.ErrOfferNotAccepted, err)
+	}
+}
+
+func TestAuctions(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	p, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Painting", Cost: 100, Quantity: 1}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	na := product.NewAuction{StartsAt: now, EndsAt: now.Add(time.Hour), ReservePrice: 150, MinIncrement: 10}
+	a, err := product.CreateAuction(ctx, db, event.DefaultID, p.ID, na, now)
+	if err != nil {
+		t.Fatalf("creating auction: %s", err)
+	}
+
+	// The only unit is held for the auction while bidding runs.
+	if _, err := product.CreateAuction(ctx, db, event.DefaultID, p.ID, na, now); err != product.ErrInsufficientStock {
+		t.Fatalf("expected %v auctioning a held unit, got %v", product.ErrInsufficientStock, err)
+	}
+	if _, err := product.AddSale(ctx, db, event.DefaultID, product.NewSale{Quantity: 1}, p.ID, now); err != product.ErrInsufficientStock {
+		t.Fatalf("expected %v selling a held unit, got %v", product.ErrInsufficientStock, err)
+	}
+
+	if _, err := product.PlaceBid(ctx, db, event.DefaultID, a.ID, product.NewBid{Bidder: "Ann", Amount: 120}, now); err != nil {
+		t.Fatalf("bidding: %s", err)
+	}
+	if _, err := product.PlaceBid(ctx, db, event.DefaultID, a.ID, product.NewBid{Bidder: "Bob", Amount: 125}, now); err != product.ErrBidTooLow {
+		t.Fatalf("expected %v for a bid under the increment, got %v", product.ErrBidTooLow, err)
+	}
+
+	// A bid in the last minute pushes the end back.
+	late := now.Add(59 * time.Minute)
+	if _, err := product.PlaceBid(ctx, db, event.DefaultID, a.ID, product.NewBid{Bidder: "Bob", Amount: 160}, late); err != nil {
+		t.Fatalf("bidding: %s", err)
+	}
+	if a, err = product.RetrieveAuction(ctx, db, event.DefaultID, a.ID); err != nil {
+		t.Fatalf("retrieving auction: %s", err)
+	}
+	if !a.EndsAt.After(na.EndsAt) {
+		t.Fatalf("expected the auction extended past %v, got %v", na.EndsAt, a.EndsAt)
+	}
+
+	if n, err := product.CloseAuctions(ctx, db, now.Add(time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected the extended auction still open, got %d sold: %v", n, err)
+	}
+	n, err := product.CloseAuctions(ctx, db, a.EndsAt)
+	if err != nil {
+		t.Fatalf("closing auctions: %s", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 auction sold, got %d", n)
+	}
+
+	if a, err = product.RetrieveAuction(ctx, db, event.DefaultID, a.ID); err != nil {
+		t.Fatalf("retrieving auction: %s", err)
+	}
+	if a.Status != product.AuctionSold || a.SaleID == nil {
+		t.Fatalf("expected the auction sold, got %+v", a)
+	}
+	s, err := product.RetrieveSale(ctx, db, event.DefaultID, *a.SaleID)
+	if err != nil {
+		t.Fatalf("retrieving sale: %s", err)
+	}
+	if s.Net != 160 {
+		t.Fatalf("expected the lot sold at the winning bid of 160, got %d", s.Net)
+	}
+	rs, err := product.ListReservations(ctx, db, event.DefaultID, p.ID)
+	if err != nil {
+		t.Fatalf("listing reservations: %s", err)
+	}
+	if len(rs) != 1 || rs[0].Status != product.ReservationSold || rs[0].SaleID == nil || *rs[0].SaleID != s.ID {
+		t.Fatalf("expected the hold on the lot converted by the sale, got %+v", rs)
+	}
+	if _, err := product.PlaceBid(ctx, db, event.DefaultID, a.ID, product.NewBid{Bidder: "Ann", Amount: 200}, a.EndsAt); err != product.ErrAuctionClosed {
+		t.Fatalf("expected %v bidding after the close, got %v", product.ErrAuctionClosed, err)
+	}
+
+	// A won lot that can not be sold is tried a limited number of times and
+	// then given up on.
+	vase, err := product.Create(ctx, db, event.DefaultID, product.NewProduct{Name: "Vase", Cost: 50, Quantity: 1}, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+	a, err = product.CreateAuction(ctx, db, event.DefaultID, vase.ID, product.NewAuction{StartsAt: now, EndsAt: now.Add(time.Hour), MinIncrement: 1}, now)
+	if err != nil {
+		t.Fatalf("creating auction: %s", err)
+	}
+	if _, err := product.PlaceBid(ctx, db, event.DefaultID, a.ID, product.NewBid{Bidder: "Ann", Amount: 60}, now); err != nil {
+		t.Fatalf("bidding: %s", err)
+	}
+	broken := product.NewAdjustment{Kind: product.MovementDamaged, Quantity: 1, Reason: "dropped"}
+	if _, err := product.Adjust(ctx, db, event.DefaultID, vase.ID, broken, "alice", now); err != nil {
+		t.Fatalf("adjusting stock: %s", err)
+	}
+
+	for i := 1; i <= product.MaxSaleAttempts; i++ {
+		if n, err := product.CloseAuctions(ctx, db, a.EndsAt); n != 0 || !errors.Is(err, product.ErrInsufficientStock) {
+			t.Fatalf("attempt %d: expected the sale to fail with %v, got %d sold: %v", i, product.ErrInsufficientStock, n, err)
+		}
+	}
+	if a, err = product.RetrieveAuction(ctx, db, event.DefaultID, a.ID); err != nil {
+		t.Fatalf("retrieving auction: %s", err)
+	}
+	if a.Status != product.AuctionFailed || a.SaleAttempts != product.MaxSaleAttempts || a.LastError == "" {
+		t.Fatalf("expected the auction failed after %d attempts, got %+v", product.MaxSaleAttempts, a)
+	}
+	if n, err := product.CloseAuctions(ctx, db, a.EndsAt); n != 0 || err != nil {
+		t.Fatalf("expected the failed auction left alone, got %d sold: %v", n, err)
+	}
+	rs, err = product.ListReservations(ctx, db, event.DefaultID, vase.ID)
+	if err != nil {
+		t.Fatalf("listing reservations: %s", err)
+	}
+	if len(rs) != 1 || rs[0].Status != product.ReservationReleased {
+		t.Fatalf("expected the hold on the lot released, got %+v", rs)
+	}
+}
@@ -0,0 +1,105 @@
+package product
+
+import (
+	"context"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/pricing"
+	"github.com/jmoiron/sqlx"
+)
+
+// ListPublic gives the products of an event shown in the public storefront:
+// those that are not archived and have units available.
+func ListPublic(ctx context.Context, db *sqlx.DB, eventID string, now time.Time) ([]PublicProduct, error) {
+	list, err := List(ctx, db, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := []PublicProduct{}
+	for _, p := range list {
+		if !onShow(p) {
+			continue
+		}
+		pp, err := publish(ctx, db, p, now)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *pp)
+	}
+
+	return out, nil
+}
+
+// RetrievePublic gives a single Product of an event as the public storefront
+// shows it. Products that are not on show are not found.
+func RetrievePublic(ctx context.Context, db *sqlx.DB, eventID, id string, now time.Time) (*PublicProduct, error) {
+	p, err := Retrieve(ctx, db, eventID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !onShow(*p) {
+		return nil, ErrNotFound
+	}
+
+	return publish(ctx, db, *p, now)
+}
+
+// onShow reports whether a product belongs in the public storefront.
+func onShow(p Product) bool {
+	return !p.Archived && p.Available > 0
+}
+
+// publish projects a Product onto what the public storefront shows, pricing
+// it and its variants with the markdowns active now. Sold out variants are
+// left out.
+func publish(ctx context.Context, db sqlx.QueryerContext, p Product, now time.Time) (*PublicProduct, error) {
+	markdowns, err := pricing.ActiveMarkdowns(ctx, db, p.EventID, p.ID, p.Category, now)
+	if err != nil {
+		return nil, err
+	}
+
+	pp := PublicProduct{
+		ID:        p.ID,
+		EventID:   p.EventID,
+		Name:      p.Name,
+		Category:  p.Category,
+		Price:     pricing.Calculate(p.Cost, markdowns, nil).Paid,
+		Available: p.Available,
+		Images:    []PublicImage{},
+		Variants:  []PublicVariant{},
+	}
+
+	for _, img := range p.Images {
+		pi := PublicImage{
+			ID:      img.ID,
+			Width:   img.Width,
+			Height:  img.Height,
+			Primary: img.Primary,
+			URLs:    make(map[string]string, len(img.URLs)),
+		}
+		for size, url := range img.URLs {
+			pi.URLs[size] = "/public" + url
+		}
+		pp.Images = append(pp.Images, pi)
+	}
+
+	for _, v := range p.Variants {
+		if v.Available <= 0 {
+			continue
+		}
+		cost := p.Cost
+		if v.Cost != nil {
+			cost = *v.Cost
+		}
+		pp.Variants = append(pp.Variants, PublicVariant{
+			ID:        v.ID,
+			Name:      v.Name,
+			Price:     pricing.Calculate(cost, markdowns, nil).Paid,
+			Available: v.Available,
+		})
+	}
+
+	return &pp, nil
+}
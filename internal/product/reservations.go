@@ -0,0 +1,287 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Predefined errors for reservation failure scenarios
+var (
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationClosed   = errors.New("reservation is no longer active")
+	ErrReservationMismatch = errors.New("sale does not match the reservation")
+	ErrInvalidExpiry       = errors.New("expiry must be in the future")
+)
+
+// ListReservations gives the reservations of a Product of an event, the most
+// recent first.
+func ListReservations(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Reservation, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	list := []Reservation{}
+
+	const q = `SELECT r.* FROM reservations AS r
+	JOIN products AS p ON p.product_id = r.product_id
+	WHERE r.product_id = $1 AND p.event_id = $2
+	ORDER BY r.date_created DESC`
+
+	if err := db.SelectContext(ctx, &list, q, productID, eventID); err != nil {
+		return nil, fmt.Errorf("selecting reservations: %w", err)
+	}
+
+	return list, nil
+}
+
+// RetrieveReservation gives a single Reservation of an event
+func RetrieveReservation(ctx context.Context, db *sqlx.DB, eventID, id string) (*Reservation, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	var r Reservation
+
+	const q = `SELECT r.* FROM reservations AS r
+	JOIN products AS p ON p.product_id = r.product_id
+	WHERE r.reservation_id = $1 AND p.event_id = $2`
+
+	if err := db.GetContext(ctx, &r, q, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("selecting reservation: %w", err)
+	}
+
+	return &r, nil
+}
+
+// Reserve holds units of a Product of an event for a customer. Only units
+// that are available, on hand and not already held, can be reserved.
+func Reserve(ctx context.Context, db *sqlx.DB, eventID, productID string, nr NewReservation, now time.Time) (*Reservation, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	if !nr.ExpiresAt.After(now) {
+		return nil, ErrInvalidExpiry
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st, err := lockStock(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := retrieve(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := Reservation{
+		ID:          uuid.New().String(),
+		ProductID:   p.ID,
+		Note:        nr.Note,
+		Quantity:    nr.Quantity,
+		Status:      ReservationActive,
+		ExpiresAt:   nr.ExpiresAt.UTC(),
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+	if nr.CustomerID != "" {
+		r.CustomerID = &nr.CustomerID
+	}
+
+	available := st.Available
+	switch {
+	case nr.VariantID != "":
+		v, err := RetrieveVariant(ctx, tx, eventID, p.ID, nr.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		r.VariantID = &v.ID
+		available = v.Available
+	case len(p.Variants) > 0:
+		return nil, ErrVariantRequired
+	}
+
+	if r.Quantity > available {
+		return nil, ErrInsufficientStock
+	}
+
+	const q = `INSERT INTO reservations
+	(reservation_id, product_id, variant_id, customer_id, note, quantity, status,
+		expires_at, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+
+	_, err = tx.ExecContext(ctx, q,
+		r.ID, r.ProductID, r.VariantID, r.CustomerID, r.Note, r.Quantity, r.Status,
+		r.ExpiresAt, r.DateCreated, r.DateUpdated,
+	)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && r.CustomerID != nil {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, fmt.Errorf("inserting reservation: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "reservation", r.ID, nil, r.audited(), now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing reservation: %w", err)
+	}
+
+	return &r, nil
+}
+
+// ReleaseReservation ends an active Reservation of an event, putting the
+// units it held back on sale. The hold on the lot of an auction ends with the
+// auction and can not be released by hand.
+func ReleaseReservation(ctx context.Context, db *sqlx.DB, eventID, id string, now time.Time) error {
+	if err := checkIDs(eventID, id); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var r Reservation
+
+	const sel = `SELECT r.* FROM reservations AS r
+	JOIN products AS p ON p.product_id = r.product_id
+	WHERE r.reservation_id = $1 AND p.event_id = $2
+	FOR UPDATE OF r`
+
+	if err := tx.GetContext(ctx, &r, sel, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return ErrReservationNotFound
+		}
+		return fmt.Errorf("selecting reservation: %w", err)
+	}
+	if r.Status != ReservationActive || r.AuctionID != nil {
+		return ErrReservationClosed
+	}
+	before := r
+
+	r.Status = ReservationReleased
+	r.DateUpdated = now.UTC()
+
+	const q = `UPDATE reservations SET
+		"status" = $2,
+		"date_updated" = $3
+	WHERE reservation_id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, r.ID, r.Status, r.DateUpdated); err != nil {
+		return fmt.Errorf("releasing reservation: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionUpdate, "reservation", r.ID, before.audited(), r.audited(), now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing reservation: %w", err)
+	}
+
+	return nil
+}
+
+// ExpireReservations ends every active reservation that expired by now. It
+// returns how many expired. Holds on the lots of auctions are left to the
+// auctions.
+func ExpireReservations(ctx context.Context, db *sqlx.DB, now time.Time) (int64, error) {
+	const q = `UPDATE reservations SET
+		"status" = 'expired',
+		"date_updated" = $1
+	WHERE status = 'active' AND expires_at <= $1 AND auction_id IS NULL`
+
+	res, err := db.ExecContext(ctx, q, now.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("expiring reservations: %w", err)
+	}
+
+	return res.RowsAffected()
+}
+
+// Expirer ends reservations once they expire so their units go back on sale.
+type Expirer struct {
+	DB       *sqlx.DB
+	Log      *log.Logger
+	Interval time.Duration
+}
+
+// Run expires reservations every Interval until the context is canceled.
+func (e *Expirer) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := ExpireReservations(ctx, e.DB, time.Now())
+		if err != nil {
+			e.Log.Printf("reservations : expiring : %v", err)
+		}
+		if n > 0 {
+			e.Log.Printf("reservations : expired %d", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// takeReservation locks an active Reservation of a product for the sale that
+// converts it.
+func takeReservation(ctx context.Context, tx sqlx.QueryerContext, productID, id string, now time.Time) (*Reservation, error) {
+	var r Reservation
+
+	const q = `SELECT * FROM reservations
+	WHERE reservation_id = $1 AND product_id = $2
+	FOR UPDATE`
+
+	if err := sqlx.GetContext(ctx, tx, &r, q, id, productID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrReservationNotFound
+		}
+		return nil, fmt.Errorf("selecting reservation: %w", err)
+	}
+
+	if r.Status != ReservationActive || !r.ExpiresAt.After(now) {
+		return nil, ErrReservationClosed
+	}
+	if r.AuctionID != nil {
+		return nil, ErrReservationMismatch
+	}
+
+	return &r, nil
+}
+
+// audited gives the Reservation as it is written to the audit log. Its note
+// may name the customer and is left out: entries in the log can not be
+// changed, so anonymizing the customer could not remove it later.
+func (r Reservation) audited() Reservation {
+	r.Note = ""
+	return r
+}
@@ -2,46 +2,405 @@ package product
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/pricing"
+	"github.com/ivan-sabo/garagesale/internal/tax"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
-// AddSale records a sales transation for a single Product
-func AddSale(ctx context.Context, db *sqlx.DB, ns NewSale, productID string, now time.Time) (*Sale, error) {
+// AddSale records a sales transation for a single Product of an event. The
+// amount paid is calculated from the product cost, any markdowns active at the
+// time of the sale, the coupon provided by the customer and the tax rate of
+// the product category in the sale jurisdiction. A sale converting a
+// reservation may take fewer units than were held; the rest are released. A
+// sale naming an accepted offer is made at the agreed price, which replaces
+// the cost and any markdowns, and can not take a coupon. The same goes for the
+// sale of a won auction, made at the winning bid to the highest bidder. Only
+// units that are available, or held by the reservation being converted, can
+// be sold.
+func AddSale(ctx context.Context, db *sqlx.DB, eventID string, ns NewSale, productID string, now time.Time) (*Sale, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The stock is locked so concurrent sales and reservations of the product
+	// see what the ones before them left available.
+	st, err := lockStock(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := retrieve(ctx, tx, eventID, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *Reservation
+	if ns.ReservationID != "" {
+		if res, err = takeReservation(ctx, tx, p.ID, ns.ReservationID, now); err != nil {
+			return nil, err
+		}
+
+		var reserved string
+		if res.VariantID != nil {
+			reserved = *res.VariantID
+		}
+		if ns.VariantID == "" {
+			ns.VariantID = reserved
+		}
+		if ns.VariantID != reserved || ns.Quantity > res.Quantity {
+			return nil, ErrReservationMismatch
+		}
+		if ns.CustomerID == "" && res.CustomerID != nil {
+			ns.CustomerID = *res.CustomerID
+		}
+	}
+
+	var offer *Offer
+	if ns.OfferID != "" {
+		if offer, err = takeOffer(ctx, tx, p.ID, ns.OfferID, now); err != nil {
+			return nil, err
+		}
+
+		var offered string
+		if offer.VariantID != nil {
+			offered = *offer.VariantID
+		}
+		if ns.VariantID == "" {
+			ns.VariantID = offered
+		}
+		if ns.VariantID != offered || ns.Quantity > offer.Quantity || ns.Coupon != "" {
+			return nil, ErrOfferMismatch
+		}
+		if ns.CustomerID == "" && offer.CustomerID != nil {
+			ns.CustomerID = *offer.CustomerID
+		}
+	}
+
+	var auction *Auction
+	if ns.AuctionID != "" {
+		var bid *Bid
+		if auction, bid, err = takeAuction(ctx, tx, p.ID, ns.AuctionID); err != nil {
+			return nil, err
+		}
+
+		var lot string
+		if auction.VariantID != nil {
+			lot = *auction.VariantID
+		}
+		if ns.VariantID == "" {
+			ns.VariantID = lot
+		}
+		if ns.VariantID != lot || ns.Quantity != 1 || ns.Coupon != "" || offer != nil || res != nil {
+			return nil, ErrAuctionMismatch
+		}
+		if ns.CustomerID == "" && bid.CustomerID != nil {
+			ns.CustomerID = *bid.CustomerID
+		}
+
+		// The lot was held for the auction since it was put up; the sale
+		// converts that hold.
+		if res, err = auctionHold(ctx, tx, auction.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	markdowns, err := pricing.ActiveMarkdowns(ctx, tx, p.EventID, p.ID, p.Category, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var coupon *pricing.Coupon
+	if ns.Coupon != "" {
+		if coupon, err = pricing.Redeem(ctx, tx, p.EventID, ns.Coupon, now); err != nil {
+			return nil, err
+		}
+	}
+
+	cost := p.Cost
+	available := st.Available
+	var variantID *string
+	switch {
+	case ns.VariantID != "":
+		v, err := RetrieveVariant(ctx, tx, eventID, p.ID, ns.VariantID)
+		if err != nil {
+			return nil, err
+		}
+		if v.Cost != nil {
+			cost = *v.Cost
+		}
+		variantID = &v.ID
+		available = v.Available
+	case len(p.Variants) > 0:
+		return nil, ErrVariantRequired
+	}
+
+	// The units held by the reservation or auction being converted are not
+	// available to anyone else but are to this sale.
+	if res != nil {
+		available += res.Quantity
+	}
+	if ns.Quantity > available {
+		return nil, ErrInsufficientStock
+	}
+
+	switch {
+	case offer != nil:
+		cost = *offer.Price
+		markdowns = nil
+	case auction != nil:
+		cost = *auction.HighBid
+		markdowns = nil
+	}
+
+	price := pricing.Calculate(cost*ns.Quantity, markdowns, coupon)
+
+	rate, err := tax.Lookup(ctx, tx, ns.Jurisdiction, p.Category)
+	if err != nil {
+		return nil, err
+	}
+	amounts := tax.Calculate(price.Paid, rate)
+
 	s := Sale{
-		ID:          uuid.New().String(),
-		ProductID:   productID,
-		Quantity:    ns.Quantity,
-		Paid:        ns.Paid,
-		DateCreated: now,
+		ID:           uuid.New().String(),
+		EventID:      p.EventID,
+		SellerID:     p.SellerID,
+		ProductID:    p.ID,
+		VariantID:    variantID,
+		Quantity:     ns.Quantity,
+		Paid:         amounts.Gross,
+		Discount:     price.Discount,
+		Jurisdiction: ns.Jurisdiction,
+		TaxRate:      rate,
+		Net:          amounts.Net,
+		Tax:          amounts.Tax,
+		Gross:        amounts.Gross,
+		DateCreated:  now,
+	}
+	if coupon != nil {
+		s.CouponCode = coupon.Code
+	}
+	if ns.CustomerID != "" {
+		s.CustomerID = &ns.CustomerID
 	}
 
 	const q = `INSERT INTO sales
-	(sale_id, product_id, quantity, paid, date_created)
-	VALUES ($1, $2, $3, $4, $5)`
+	(sale_id, event_id, seller_id, customer_id, product_id, variant_id, quantity, paid, discount, coupon_code,
+		jurisdiction, tax_rate, net, tax, gross, date_created)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)`
 
-	_, err := db.ExecContext(ctx, q,
-		s.ID, s.ProductID, s.Quantity,
-		s.Paid, s.DateCreated,
+	_, err = tx.ExecContext(ctx, q,
+		s.ID, s.EventID, s.SellerID, s.CustomerID, s.ProductID, s.VariantID, s.Quantity,
+		s.Paid, s.Discount, s.CouponCode,
+		s.Jurisdiction, s.TaxRate, s.Net, s.Tax, s.Gross,
+		s.DateCreated,
 	)
 	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation && s.CustomerID != nil {
+			return nil, ErrCustomerNotFound
+		}
 		return nil, fmt.Errorf("inserting sale: %w", err)
 	}
 
+	m := Movement{
+		ID:          uuid.New().String(),
+		ProductID:   s.ProductID,
+		VariantID:   s.VariantID,
+		SaleID:      &s.ID,
+		Kind:        MovementSale,
+		Quantity:    -s.Quantity,
+		DateCreated: s.DateCreated,
+	}
+	if err := recordMovement(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	if res != nil {
+		const q = `UPDATE reservations SET
+			"status" = 'sold',
+			"sale_id" = $2,
+			"date_updated" = $3
+			WHERE reservation_id = $1`
+
+		if _, err := tx.ExecContext(ctx, q, res.ID, s.ID, now.UTC()); err != nil {
+			return nil, fmt.Errorf("closing reservation: %w", err)
+		}
+		p.Reserved -= res.Quantity
+
+		sold := *res
+		sold.Status = ReservationSold
+		sold.SaleID = &s.ID
+		sold.DateUpdated = now.UTC()
+		if err := audit.Log(ctx, tx, audit.ActionUpdate, "reservation", res.ID, res.audited(), sold.audited(), now); err != nil {
+			return nil, err
+		}
+	}
+
+	if offer != nil {
+		const q = `UPDATE offers SET
+			"status" = 'sold',
+			"sale_id" = $2,
+			"date_updated" = $3
+			WHERE offer_id = $1`
+
+		if _, err := tx.ExecContext(ctx, q, offer.ID, s.ID, now.UTC()); err != nil {
+			return nil, fmt.Errorf("closing offer: %w", err)
+		}
+
+		sold := *offer
+		sold.Status = OfferSold
+		sold.SaleID = &s.ID
+		sold.DateUpdated = now.UTC()
+		if err := audit.Log(ctx, tx, audit.ActionUpdate, "offer", offer.ID, offer.audited(), sold.audited(), now); err != nil {
+			return nil, err
+		}
+	}
+
+	if auction != nil {
+		const q = `UPDATE auctions SET
+			"status" = 'sold',
+			"sale_id" = $2,
+			"date_updated" = $3
+			WHERE auction_id = $1`
+
+		if _, err := tx.ExecContext(ctx, q, auction.ID, s.ID, now.UTC()); err != nil {
+			return nil, fmt.Errorf("closing auction: %w", err)
+		}
+
+		sold := *auction
+		sold.Status = AuctionSold
+		sold.SaleID = &s.ID
+		sold.DateUpdated = now.UTC()
+		if err := audit.Log(ctx, tx, audit.ActionUpdate, "auction", auction.ID, auction, sold, now); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only the sale that takes the last units announces the product sold out.
+	wasAvailable := st.OnHand > 0
+	p.Sold += s.Quantity
+	p.OnHand = st.OnHand - s.Quantity
+	p.Available = p.OnHand - p.Reserved
+	p.Revenue += s.Paid
+	s.Remaining = p.Available
+
+	se := SaleEvent{
+		Sale:      s,
+		Category:  p.Category,
+		Sold:      p.Sold,
+		Remaining: s.Remaining,
+	}
+	if err := webhook.Publish(ctx, tx, webhook.EventSaleCreated, se, now); err != nil {
+		return nil, err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "sale", s.ID, nil, s, now); err != nil {
+		return nil, err
+	}
+
+	if wasAvailable && p.OnHand <= 0 {
+		if err := webhook.Publish(ctx, tx, webhook.EventProductSoldOut, p, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing sale: %w", err)
+	}
+
+	return &s, nil
+}
+
+// RetrieveSale gives a single Sale of an event
+func RetrieveSale(ctx context.Context, db *sqlx.DB, eventID, id string) (*Sale, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	var s Sale
+
+	const q = `SELECT * FROM sales WHERE sale_id = $1 AND event_id = $2`
+
+	if err := db.GetContext(ctx, &s, q, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrSaleNotFound
+		}
+		return nil, fmt.Errorf("selecting sale: %w", err)
+	}
+
 	return &s, nil
 }
 
-// ListSales gives all Sales for a Product
-func ListSales(ctx context.Context, db *sqlx.DB, productID string) ([]Sale, error) {
+// ListSales gives all Sales for a Product of an event
+func ListSales(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Sale, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
 	sales := []Sale{}
 
-	const q = `SELECT * FROM sales WHERE product_id = $1`
-	if err := db.SelectContext(ctx, &sales, q, productID); err != nil {
+	const q = `SELECT * FROM sales WHERE product_id = $1 AND event_id = $2`
+	if err := db.SelectContext(ctx, &sales, q, productID, eventID); err != nil {
 		return nil, fmt.Errorf("selecting sales: %w", err)
 	}
 
 	return sales, nil
 }
+
+// DailyTotals adds up the sales of an event by day, the most recent first.
+// Refunds count on the day they were made, not on the day of their sale, so
+// a day may have refunds and no sales.
+func DailyTotals(ctx context.Context, db *sqlx.DB, eventID string) ([]DayTotal, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, err
+	}
+
+	list := []DayTotal{}
+
+	const q = `SELECT
+		day,
+		SUM(sales) AS sales,
+		SUM(units) AS units,
+		SUM(discount) AS discount,
+		SUM(net) AS net,
+		SUM(tax) AS tax,
+		SUM(gross) AS gross,
+		SUM(refunded_units) AS refunded_units,
+		SUM(refunded_gross) AS refunded_gross
+	FROM (
+		SELECT date_trunc('day', date_created) AS day, 1 AS sales, quantity AS units,
+			discount, net, tax, gross, 0 AS refunded_units, 0 AS refunded_gross
+		FROM sales
+		WHERE event_id = $1
+		UNION ALL
+		SELECT date_trunc('day', m.date_created), 0, 0, 0, 0, 0, 0,
+			m.quantity,
+			m.quantity * s.gross / s.quantity
+		FROM inventory_movements AS m
+		JOIN sales AS s ON s.sale_id = m.sale_id
+		WHERE m.kind = 'refund' AND s.event_id = $1
+	) AS t
+	GROUP BY day
+	ORDER BY day DESC`
+
+	if err := db.SelectContext(ctx, &list, q, eventID); err != nil {
+		return nil, fmt.Errorf("selecting daily totals: %w", err)
+	}
+
+	return list, nil
+}
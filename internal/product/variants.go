@@ -0,0 +1,422 @@
+package product
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Postgres error codes for violated constraints.
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+// Predefined errors for variant failure scenarios
+var (
+	ErrVariantNotFound = errors.New("variant not found")
+	ErrVariantRequired = errors.New("product comes in variants, a variant_id is required")
+	ErrSKUExists       = errors.New("sku already exists at this event")
+	ErrVariantInUse    = errors.New("variant has stock movements and cannot be deleted")
+)
+
+// variantColumns selects a variant along with its stock and what was sold of
+// it.
+const variantColumns = `
+	v.variant_id, v.product_id, v.sku, v.name, v.cost,
+	v.date_created, v.date_updated,` + variantStockColumns + `,
+	COALESCE(SUM(s.quantity), 0) AS sold,
+	COALESCE(SUM(s.paid), 0) AS revenue`
+
+// ListVariants gives all variants of a Product of an event
+func ListVariants(ctx context.Context, db *sqlx.DB, eventID, productID string) ([]Variant, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	m, err := variantsFor(ctx, db, eventID, []string{productID})
+	if err != nil {
+		return nil, err
+	}
+
+	if m[productID] == nil {
+		return []Variant{}, nil
+	}
+	return m[productID], nil
+}
+
+// RetrieveVariant gives a single variant of a Product of an event
+func RetrieveVariant(ctx context.Context, db sqlx.QueryerContext, eventID, productID, variantID string) (*Variant, error) {
+	if err := checkIDs(eventID, productID, variantID); err != nil {
+		return nil, err
+	}
+
+	var v Variant
+
+	const q = `SELECT` + variantColumns + `
+	FROM product_variants AS v
+	JOIN products AS p ON p.product_id = v.product_id
+	LEFT JOIN sales AS s ON s.variant_id = v.variant_id
+	WHERE v.product_id = $1 AND v.variant_id = $2 AND p.event_id = $3
+	GROUP BY v.variant_id`
+
+	if err := sqlx.GetContext(ctx, db, &v, q, productID, variantID, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// Lookup resolves a scanned code to a product of an event. The code is either
+// a product ID or the SKU of a variant, in which case the variant is returned
+// too.
+func Lookup(ctx context.Context, db *sqlx.DB, eventID, code string) (*Product, *Variant, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := uuid.Parse(code); err == nil {
+		p, err := Retrieve(ctx, db, eventID, code)
+		return p, nil, err
+	}
+
+	var ids struct {
+		ProductID string `db:"product_id"`
+		VariantID string `db:"variant_id"`
+	}
+
+	const q = `SELECT v.product_id, v.variant_id
+	FROM product_variants AS v
+	JOIN products AS p ON p.product_id = v.product_id
+	WHERE v.sku = $1 AND p.event_id = $2`
+
+	if err := db.GetContext(ctx, &ids, q, code, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("looking up sku %q: %w", code, err)
+	}
+
+	p, err := Retrieve(ctx, db, eventID, ids.ProductID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range p.Variants {
+		if p.Variants[i].ID == ids.VariantID {
+			return p, &p.Variants[i], nil
+		}
+	}
+
+	return nil, nil, ErrVariantNotFound
+}
+
+// AddVariant creates a new Variant of a Product of an event. SKUs are unique
+// within an event.
+func AddVariant(ctx context.Context, db *sqlx.DB, eventID, productID string, nv NewVariant, now time.Time) (*Variant, error) {
+	if err := checkIDs(eventID, productID); err != nil {
+		return nil, err
+	}
+
+	v := Variant{
+		ID:          uuid.New().String(),
+		ProductID:   productID,
+		SKU:         nv.SKU,
+		Name:        nv.Name,
+		Cost:        nv.Cost,
+		Quantity:    nv.Quantity,
+		OnHand:      nv.Quantity,
+		Available:   nv.Quantity,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockStock(ctx, tx, eventID, productID); err != nil {
+		return nil, err
+	}
+
+	const q = `INSERT INTO product_variants
+	(variant_id, product_id, event_id, sku, name, cost, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+
+	if _, err := tx.ExecContext(ctx, q, v.ID, v.ProductID, eventID, v.SKU, v.Name, v.Cost, v.DateCreated, v.DateUpdated); err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrSKUExists
+		}
+		return nil, fmt.Errorf("inserting variant: %w", err)
+	}
+
+	if v.Quantity != 0 {
+		m := Movement{
+			ID:          uuid.New().String(),
+			ProductID:   v.ProductID,
+			VariantID:   &v.ID,
+			Kind:        MovementReceived,
+			Quantity:    v.Quantity,
+			Reason:      "initial stock",
+			DateCreated: v.DateCreated,
+		}
+		if err := recordMovement(ctx, tx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := variantsChanged(ctx, tx, eventID, productID, now); err != nil {
+		return nil, err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "variant", v.ID, nil, v, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing variant: %w", err)
+	}
+
+	return &v, nil
+}
+
+// EditVariant modifies a Variant. It will error if either ID is invalid or
+// the variant does not belong to the product. Changing the quantity records a
+// correction in the inventory ledger, which can not take the stock of the
+// variant on hand below zero.
+func EditVariant(ctx context.Context, db *sqlx.DB, eventID, productID, variantID string, update UpdateVariant, now time.Time) error {
+	if err := checkIDs(eventID, productID, variantID); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockStock(ctx, tx, eventID, productID); err != nil {
+		if err == ErrNotFound {
+			return ErrVariantNotFound
+		}
+		return err
+	}
+
+	v, err := RetrieveVariant(ctx, tx, eventID, productID, variantID)
+	if err != nil {
+		return err
+	}
+	before := *v
+
+	if update.SKU != nil {
+		v.SKU = *update.SKU
+	}
+	if update.Name != nil {
+		v.Name = *update.Name
+	}
+	if update.Cost != nil {
+		v.Cost = update.Cost
+	}
+	var correction int
+	if update.Quantity != nil {
+		correction = *update.Quantity - v.Quantity
+		if v.OnHand+correction < 0 {
+			return ErrInsufficientStock
+		}
+		v.Quantity = *update.Quantity
+		v.OnHand += correction
+		v.Available += correction
+	}
+	v.DateUpdated = now.UTC()
+
+	const q = `UPDATE product_variants SET
+		"sku" = $2,
+		"name" = $3,
+		"cost" = $4,
+		"date_updated" = $5
+		WHERE variant_id = $1 AND product_id = $6`
+
+	if _, err := tx.ExecContext(ctx, q, variantID, v.SKU, v.Name, v.Cost, v.DateUpdated, productID); err != nil {
+		if isUniqueViolation(err) {
+			return ErrSKUExists
+		}
+		return fmt.Errorf("updating variant: %w", err)
+	}
+
+	if correction != 0 {
+		m := Movement{
+			ID:          uuid.New().String(),
+			ProductID:   v.ProductID,
+			VariantID:   &v.ID,
+			Kind:        MovementCorrection,
+			Quantity:    correction,
+			Reason:      fmt.Sprintf("quantity set to %d", v.Quantity),
+			DateCreated: v.DateUpdated,
+		}
+		if err := recordMovement(ctx, tx, m); err != nil {
+			return err
+		}
+	}
+
+	if err := variantsChanged(ctx, tx, eventID, productID, now); err != nil {
+		return err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionUpdate, "variant", v.ID, before, v, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing variant: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteVariant removes a variant of a product. A variant that was ever
+// stocked or sold cannot be deleted as its movements and sales would no longer
+// add up with the stock of the product.
+func DeleteVariant(ctx context.Context, db *sqlx.DB, eventID, productID, variantID string, now time.Time) error {
+	if err := checkIDs(eventID, productID, variantID); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Deleting a variant that does not exist is not an error but there is
+	// nothing to tell anyone about either.
+	if _, err := lockStock(ctx, tx, eventID, productID); err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+	before, err := RetrieveVariant(ctx, tx, eventID, productID, variantID)
+	if err != nil {
+		if err == ErrVariantNotFound {
+			return nil
+		}
+		return err
+	}
+
+	const q = `DELETE FROM product_variants WHERE product_id = $1 AND variant_id = $2`
+
+	if _, err := tx.ExecContext(ctx, q, productID, variantID); err != nil {
+		if isForeignKeyViolation(err) {
+			return ErrVariantInUse
+		}
+		return fmt.Errorf("deleting variant (id: %s): %w", variantID, err)
+	}
+
+	if err := variantsChanged(ctx, tx, eventID, productID, now); err != nil {
+		return err
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionDelete, "variant", variantID, before, nil, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing variant deletion: %w", err)
+	}
+
+	return nil
+}
+
+// variantsChanged marks a product whose variants changed as updated: it is
+// kept as a new version and subscribers are told about it.
+func variantsChanged(ctx context.Context, tx *sqlx.Tx, eventID, productID string, now time.Time) error {
+	const q = `UPDATE products SET date_updated = $2 WHERE product_id = $1`
+
+	if _, err := tx.ExecContext(ctx, q, productID, now.UTC()); err != nil {
+		return fmt.Errorf("updating product: %w", err)
+	}
+
+	p, err := retrieve(ctx, tx, eventID, productID)
+	if err != nil {
+		return err
+	}
+
+	if err := recordVersion(ctx, tx, *p); err != nil {
+		return err
+	}
+
+	return webhook.Publish(ctx, tx, webhook.EventProductUpdated, p, now)
+}
+
+// attachVariants loads the variants of every product in the list.
+func attachVariants(ctx context.Context, db sqlx.QueryerContext, eventID string, list []Product) error {
+	ids := make([]string, len(list))
+	for i, p := range list {
+		ids[i] = p.ID
+	}
+
+	m, err := variantsFor(ctx, db, eventID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range list {
+		list[i].Variants = m[list[i].ID]
+		if list[i].Variants == nil {
+			list[i].Variants = []Variant{}
+		}
+	}
+
+	return nil
+}
+
+// variantsFor gives the variants of many products of an event keyed by
+// product ID.
+func variantsFor(ctx context.Context, db sqlx.QueryerContext, eventID string, productIDs []string) (map[string][]Variant, error) {
+	var list []Variant
+
+	const q = `SELECT` + variantColumns + `
+	FROM product_variants AS v
+	JOIN products AS p ON p.product_id = v.product_id
+	LEFT JOIN sales AS s ON s.variant_id = v.variant_id
+	WHERE v.product_id = ANY($1) AND p.event_id = $2
+	GROUP BY v.variant_id
+	ORDER BY v.date_created`
+
+	if err := sqlx.SelectContext(ctx, db, &list, q, pq.Array(productIDs), eventID); err != nil {
+		return nil, fmt.Errorf("selecting variants: %w", err)
+	}
+
+	m := make(map[string][]Variant)
+	for _, v := range list {
+		m[v.ProductID] = append(m[v.ProductID], v)
+	}
+
+	return m, nil
+}
+
+// isUniqueViolation reports whether err comes from a violated unique
+// constraint.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
+
+// isForeignKeyViolation reports whether err comes from a violated foreign key
+// constraint.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation
+}
@@ -0,0 +1,45 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+)
+
+// page is the HTML layout of a receipt. It is self contained so it can be
+// saved or printed straight from a browser.
+var page = template.Must(template.New("receipt").Funcs(template.FuncMap{
+	"neg":  func(v int) int { return -v },
+	"rate": formatRate,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Receipt {{.SaleID}}</title>
+<style>
+body { font-family: monospace; max-width: 24em; margin: 2em auto; }
+header, footer { text-align: center; }
+table { width: 100%; border-collapse: collapse; }
+td.amount { text-align: right; }
+tr.total td { border-top: 2px solid #000; font-weight: bold; }
+</style>
+</head>
+<body>
+<header>{{range .Header}}<div>{{.}}</div>{{end}}</header>
+<p>Date: {{.Date.Format "2006-01-02 15:04"}}<br>Sale: {{.SaleID}}</p>
+<table>
+{{range .Items}}<tr><td>{{.Name}}<br>&nbsp;&nbsp;{{.Quantity}} x {{.UnitPrice}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}<tr><td>Subtotal</td><td class="amount">{{.Subtotal}}</td></tr>
+{{if .Discount}}<tr><td>Discount{{if .Coupon}} ({{.Coupon}}){{end}}</td><td class="amount">{{neg .Discount}}</td></tr>
+{{end}}<tr><td>Net</td><td class="amount">{{.Net}}</td></tr>
+<tr><td>Tax {{rate .TaxRate}}</td><td class="amount">{{.Tax}}</td></tr>
+<tr class="total"><td>Total</td><td class="amount">{{.Total}}</td></tr>
+</table>
+<footer>{{range .Footer}}<div>{{.}}</div>{{end}}</footer>
+</body>
+</html>
+`))
+
+// HTML renders a receipt as a standalone HTML page.
+func HTML(w io.Writer, r Receipt) error {
+	return page.Execute(w, r)
+}
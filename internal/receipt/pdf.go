@@ -0,0 +1,85 @@
+package receipt
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Receipt PDFs are 80mm wide like a thermal printer roll.
+const (
+	pdfWidth  = 80.0
+	pdfMargin = 5.0
+	pdfLine   = 4.5
+)
+
+// PDF renders a receipt as a single page PDF as long as its content.
+func PDF(w io.Writer, r Receipt) error {
+	rows := len(r.Header) + len(r.Footer) + 2*len(r.Items) + 12
+	height := 2*pdfMargin + float64(rows)*pdfLine
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: pdfWidth, Ht: height},
+	})
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.AddPage()
+
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	inner := pdfWidth - 2*pdfMargin
+
+	line := func(label, value string) {
+		pdf.CellFormat(inner*0.7, pdfLine, tr(label), "", 0, "L", false, 0, "")
+		pdf.CellFormat(inner*0.3, pdfLine, tr(value), "", 1, "R", false, 0, "")
+	}
+	rule := func() {
+		y := pdf.GetY() + pdfLine/2
+		pdf.Line(pdfMargin, y, pdfWidth-pdfMargin, y)
+		pdf.Ln(pdfLine)
+	}
+
+	pdf.SetFont("Helvetica", "B", 9)
+	for _, l := range r.Header {
+		pdf.CellFormat(inner, pdfLine, tr(l), "", 1, "C", false, 0, "")
+	}
+	if len(r.Header) > 0 {
+		rule()
+	}
+
+	pdf.SetFont("Helvetica", "", 8)
+	line("Date", r.Date.Format("2006-01-02 15:04"))
+	pdf.CellFormat(inner, pdfLine, "Sale "+r.SaleID, "", 1, "L", false, 0, "")
+	rule()
+
+	for _, it := range r.Items {
+		pdf.CellFormat(inner, pdfLine, tr(it.Name), "", 1, "L", false, 0, "")
+		line(fmt.Sprintf("    %d x %d", it.Quantity, it.UnitPrice), fmt.Sprint(it.Amount))
+	}
+	rule()
+
+	line("Subtotal", fmt.Sprint(r.Subtotal))
+	if r.Discount > 0 {
+		label := "Discount"
+		if r.Coupon != "" {
+			label += " (" + r.Coupon + ")"
+		}
+		line(label, fmt.Sprint(-r.Discount))
+	}
+	line("Net", fmt.Sprint(r.Net))
+	line("Tax "+formatRate(r.TaxRate), fmt.Sprint(r.Tax))
+
+	pdf.SetFont("Helvetica", "B", 9)
+	line("TOTAL", fmt.Sprint(r.Total))
+
+	pdf.SetFont("Helvetica", "", 8)
+	if len(r.Footer) > 0 {
+		rule()
+	}
+	for _, l := range r.Footer {
+		pdf.CellFormat(inner, pdfLine, tr(l), "", 1, "C", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
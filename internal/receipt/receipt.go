@@ -0,0 +1,109 @@
+// Package receipt renders sales receipts as plain text, HTML and PDF.
+package receipt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/product"
+)
+
+// Formats a receipt can be rendered in.
+const (
+	FormatText = "text"
+	FormatHTML = "html"
+	FormatPDF  = "pdf"
+)
+
+// ErrUnknownFormat is returned for a format we can not render.
+var ErrUnknownFormat = errors.New("format must be text, html or pdf")
+
+// The range of line widths text receipts can be rendered in.
+const (
+	MinWidth = 24
+	MaxWidth = 80
+)
+
+// Config holds the shop specific parts of every receipt. Header and Footer may
+// span several lines separated by newlines. Width is the number of characters
+// per line of text receipts, 42 fits most 80mm thermal printers.
+type Config struct {
+	Header string
+	Footer string
+	Width  int
+}
+
+// Receipt is everything printed on a receipt for a single sale.
+type Receipt struct {
+	Header   []string
+	Footer   []string
+	SaleID   string
+	Date     time.Time
+	Items    []Item
+	Subtotal int
+	Discount int
+	Coupon   string
+	Net      int
+	TaxRate  int
+	Tax      int
+	Total    int
+}
+
+// Item is one line of a receipt.
+type Item struct {
+	Name      string
+	Quantity  int
+	UnitPrice int
+	Amount    int
+}
+
+// Build puts together the receipt of a sale of a product.
+func Build(cfg Config, s product.Sale, p product.Product) Receipt {
+	name := p.Name
+	if s.VariantID != nil {
+		for _, v := range p.Variants {
+			if v.ID == *s.VariantID {
+				name += " - " + v.Name
+			}
+		}
+	}
+
+	subtotal := s.Net + s.Discount
+	unit := 0
+	if s.Quantity > 0 {
+		unit = subtotal / s.Quantity
+	}
+
+	return Receipt{
+		Header:   lines(cfg.Header),
+		Footer:   lines(cfg.Footer),
+		SaleID:   s.ID,
+		Date:     s.DateCreated,
+		Items:    []Item{{Name: name, Quantity: s.Quantity, UnitPrice: unit, Amount: subtotal}},
+		Subtotal: subtotal,
+		Discount: s.Discount,
+		Coupon:   s.CouponCode,
+		Net:      s.Net,
+		TaxRate:  s.TaxRate,
+		Tax:      s.Tax,
+		Total:    s.Gross,
+	}
+}
+
+// lines splits a multi line setting, dropping a trailing empty line.
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+// formatRate gives a tax rate in basis points as a percentage.
+func formatRate(rate int) string {
+	if rate%100 == 0 {
+		return fmt.Sprintf("%d%%", rate/100)
+	}
+	return strings.TrimRight(fmt.Sprintf("%.2f", float64(rate)/100), "0") + "%"
+}
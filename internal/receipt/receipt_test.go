@@ -0,0 +1,66 @@
+package receipt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/receipt"
+)
+
+func TestText(t *testing.T) {
+	cfg := receipt.Config{Header: "Smith Garage Sale\n12 Elm St", Footer: "Thank you!"}
+	s := product.Sale{
+		ID:          "dc3ea3fa-dcfc-4073-8fa1-7187d44eaa14",
+		Quantity:    2,
+		Discount:    20,
+		CouponCode:  "SPRING",
+		TaxRate:     2500,
+		Net:         80,
+		Tax:         20,
+		Gross:       100,
+		DateCreated: time.Date(2021, 1, 18, 14, 5, 6, 0, time.UTC),
+	}
+	p := product.Product{Name: "Comic Books"}
+
+	var buf bytes.Buffer
+	if err := receipt.Text(&buf, receipt.Build(cfg, s, p), 32); err != nil {
+		t.Fatalf("rendering receipt: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"       Smith Garage Sale",
+		"           12 Elm St",
+		"--------------------------------",
+		"Date            2021-01-18 14:05",
+		"Sale  dc3ea3fa-dcfc-4073-8fa1-71",
+		"--------------------------------",
+		"Comic Books",
+		"  2 x 50                     100",
+		"--------------------------------",
+		"Subtotal                     100",
+		"Discount (SPRING)            -20",
+		"Net                           80",
+		"Tax 25%                       20",
+		"================================",
+		"TOTAL                        100",
+		"--------------------------------",
+		"           Thank you!",
+		"",
+	}, "\n")
+
+	if diff := cmp.Diff(want, buf.String()); diff != "" {
+		t.Fatalf("receipt did not match expected: see diff \n%s", diff)
+	}
+
+	buf.Reset()
+	if err := receipt.PDF(&buf, receipt.Build(cfg, s, p)); err != nil {
+		t.Fatalf("rendering pdf receipt: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF")) {
+		t.Fatal("expected pdf output to start with %PDF")
+	}
+}
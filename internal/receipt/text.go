@@ -0,0 +1,89 @@
+package receipt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Text renders a fixed width plain text receipt suitable for thermal
+// printers. Only line breaks are used for layout so the output can be sent to
+// ESC/POS printers as is.
+func Text(w io.Writer, r Receipt, width int) error {
+	if width < MinWidth {
+		width = MinWidth
+	}
+
+	var b strings.Builder
+	rule := strings.Repeat("-", width) + "\n"
+
+	for _, l := range r.Header {
+		b.WriteString(center(l, width))
+	}
+	if len(r.Header) > 0 {
+		b.WriteString(rule)
+	}
+
+	b.WriteString(pair("Date", r.Date.Format("2006-01-02 15:04"), width))
+	b.WriteString(pair("Sale", shorten(r.SaleID, width-6), width))
+	b.WriteString(rule)
+
+	for _, it := range r.Items {
+		b.WriteString(shorten(it.Name, width) + "\n")
+		b.WriteString(pair(fmt.Sprintf("  %d x %d", it.Quantity, it.UnitPrice), fmt.Sprint(it.Amount), width))
+	}
+	b.WriteString(rule)
+
+	b.WriteString(pair("Subtotal", fmt.Sprint(r.Subtotal), width))
+	if r.Discount > 0 {
+		label := "Discount"
+		if r.Coupon != "" {
+			label += " (" + r.Coupon + ")"
+		}
+		b.WriteString(pair(label, fmt.Sprint(-r.Discount), width))
+	}
+	b.WriteString(pair("Net", fmt.Sprint(r.Net), width))
+	b.WriteString(pair("Tax "+formatRate(r.TaxRate), fmt.Sprint(r.Tax), width))
+	b.WriteString(strings.Repeat("=", width) + "\n")
+	b.WriteString(pair("TOTAL", fmt.Sprint(r.Total), width))
+
+	if len(r.Footer) > 0 {
+		b.WriteString(rule)
+	}
+	for _, l := range r.Footer {
+		b.WriteString(center(l, width))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// pair writes a label on the left and a value on the right of one line.
+func pair(label, value string, width int) string {
+	label = shorten(label, width-utf8.RuneCountInString(value)-1)
+	gap := width - utf8.RuneCountInString(label) - utf8.RuneCountInString(value)
+	if gap < 1 {
+		gap = 1
+	}
+	return label + strings.Repeat(" ", gap) + value + "\n"
+}
+
+// center places s in the middle of a line.
+func center(s string, width int) string {
+	s = shorten(s, width)
+	pad := (width - utf8.RuneCountInString(s)) / 2
+	return strings.Repeat(" ", pad) + s + "\n"
+}
+
+// shorten cuts s to at most n characters.
+func shorten(s string, n int) string {
+	if n < 0 {
+		n = 0
+	}
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -36,6 +36,548 @@ CREATE TABLE sales (
 	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
 	);`,
 	},
+	{
+		Version:     3,
+		Description: "Add product categories and sale discounts",
+		Script: `
+ALTER TABLE products ADD COLUMN category TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN discount INT NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN coupon_code TEXT NOT NULL DEFAULT '';`,
+	},
+	{
+		Version:     4,
+		Description: "Add coupons",
+		Script: `
+CREATE TABLE coupons (
+	coupon_id		UUID,
+	code			TEXT NOT NULL UNIQUE,
+	kind			TEXT NOT NULL,
+	amount			INT NOT NULL,
+	max_uses		INT NOT NULL DEFAULT 0,
+	uses			INT NOT NULL DEFAULT 0,
+	expires_at		TIMESTAMP,
+	date_created	TIMESTAMP,
+
+	PRIMARY KEY (coupon_id)
+);`,
+	},
+	{
+		Version:     5,
+		Description: "Add markdowns",
+		Script: `
+CREATE TABLE markdowns (
+	markdown_id		UUID,
+	product_id		UUID,
+	category		TEXT NOT NULL DEFAULT '',
+	kind			TEXT NOT NULL,
+	amount			INT NOT NULL,
+	starts_at		TIMESTAMP NOT NULL,
+	ends_at			TIMESTAMP,
+	date_created	TIMESTAMP,
+
+	PRIMARY KEY (markdown_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
+);`,
+	},
+	{
+		Version:     6,
+		Description: "Add tax rates",
+		Script: `
+CREATE TABLE tax_rates (
+	tax_rate_id		UUID,
+	jurisdiction	TEXT NOT NULL DEFAULT '',
+	category		TEXT NOT NULL DEFAULT '',
+	rate			INT NOT NULL,
+	date_created	TIMESTAMP,
+	date_updated	TIMESTAMP,
+
+	PRIMARY KEY (tax_rate_id),
+	UNIQUE (jurisdiction, category)
+);`,
+	},
+	{
+		Version:     7,
+		Description: "Add tax breakdown to sales",
+		Script: `
+ALTER TABLE sales ADD COLUMN jurisdiction TEXT NOT NULL DEFAULT '';
+ALTER TABLE sales ADD COLUMN tax_rate INT NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN net INT NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN tax INT NOT NULL DEFAULT 0;
+ALTER TABLE sales ADD COLUMN gross INT NOT NULL DEFAULT 0;
+UPDATE sales SET net = paid, gross = paid;`,
+	},
+	{
+		Version:     8,
+		Description: "Add idempotency keys",
+		Script: `
+CREATE TABLE idempotency_keys (
+	scope			TEXT,
+	idempotency_key	TEXT,
+	holder			TEXT NOT NULL,
+	fingerprint		TEXT NOT NULL,
+	status			INT NOT NULL DEFAULT 0,
+	content_type	TEXT NOT NULL DEFAULT '',
+	body			BYTEA,
+	date_created	TIMESTAMP,
+	expires_at		TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (scope, idempotency_key)
+);
+CREATE INDEX idempotency_keys_expires_at ON idempotency_keys (expires_at);`,
+	},
+	{
+		Version:     9,
+		Description: "Add outbox",
+		Script: `
+CREATE TABLE outbox (
+	seq				BIGSERIAL,
+	event_id		UUID NOT NULL UNIQUE,
+	type			TEXT NOT NULL,
+	payload			JSONB NOT NULL,
+	dispatched		BOOLEAN NOT NULL DEFAULT false,
+	date_created	TIMESTAMP,
+
+	PRIMARY KEY (seq)
+);
+CREATE INDEX outbox_pending ON outbox (seq) WHERE NOT dispatched;`,
+	},
+	{
+		Version:     10,
+		Description: "Add webhooks",
+		Script: `
+CREATE TABLE webhook_subscriptions (
+	subscription_id	UUID,
+	url				TEXT NOT NULL,
+	secret			TEXT NOT NULL,
+	events			TEXT[] NOT NULL DEFAULT '{}',
+	active			BOOLEAN NOT NULL DEFAULT true,
+	date_created	TIMESTAMP,
+	date_updated	TIMESTAMP,
+
+	PRIMARY KEY (subscription_id)
+);
+
+CREATE TABLE webhook_deliveries (
+	delivery_id		UUID,
+	subscription_id	UUID NOT NULL,
+	event_id		UUID NOT NULL,
+	event_type		TEXT NOT NULL,
+	status			TEXT NOT NULL,
+	attempts		INT NOT NULL DEFAULT 0,
+	next_attempt	TIMESTAMP,
+	last_status		INT NOT NULL DEFAULT 0,
+	last_error		TEXT NOT NULL DEFAULT '',
+	locked_until	TIMESTAMP,
+	date_created	TIMESTAMP,
+	date_updated	TIMESTAMP,
+
+	PRIMARY KEY (delivery_id),
+	FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions(subscription_id) ON DELETE CASCADE,
+	FOREIGN KEY (event_id) REFERENCES outbox(event_id) ON DELETE CASCADE
+);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (next_attempt) WHERE status = 'pending';
+
+CREATE TABLE webhook_attempts (
+	attempt_id		UUID,
+	delivery_id		UUID NOT NULL,
+	number			INT NOT NULL,
+	status			INT NOT NULL DEFAULT 0,
+	error			TEXT NOT NULL DEFAULT '',
+	duration_ms		BIGINT NOT NULL DEFAULT 0,
+	date_created	TIMESTAMP,
+
+	PRIMARY KEY (attempt_id),
+	FOREIGN KEY (delivery_id) REFERENCES webhook_deliveries(delivery_id) ON DELETE CASCADE
+);`,
+	},
+	{
+		Version:     11,
+		Description: "Index outbox events for pruning",
+		Script: `
+CREATE INDEX outbox_date_created ON outbox (date_created) WHERE dispatched;
+CREATE INDEX webhook_deliveries_event ON webhook_deliveries (event_id);`,
+	},
+	{
+		Version:     12,
+		Description: "Add product images",
+		Script: `
+CREATE TABLE product_images (
+	image_id		UUID,
+	product_id		UUID NOT NULL,
+	content_type	TEXT NOT NULL,
+	width			INT NOT NULL,
+	height			INT NOT NULL,
+	size			BIGINT NOT NULL,
+	position		INT NOT NULL DEFAULT 0,
+	is_primary		BOOLEAN NOT NULL DEFAULT false,
+	date_created	TIMESTAMP,
+
+	PRIMARY KEY (image_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
+);
+CREATE INDEX product_images_product ON product_images (product_id, position);`,
+	},
+	{
+		Version:     13,
+		Description: "Add product variants",
+		Script: `
+CREATE TABLE product_variants (
+	variant_id		UUID,
+	product_id		UUID NOT NULL,
+	sku				TEXT NOT NULL UNIQUE,
+	name			TEXT NOT NULL,
+	cost			INT,
+	quantity		INT NOT NULL DEFAULT 0,
+	date_created	TIMESTAMP,
+	date_updated	TIMESTAMP,
+
+	PRIMARY KEY (variant_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
+);
+CREATE INDEX product_variants_product ON product_variants (product_id);
+
+ALTER TABLE sales ADD COLUMN variant_id UUID REFERENCES product_variants(variant_id);
+CREATE INDEX sales_variant ON sales (variant_id);`,
+	},
+	{
+		Version:     14,
+		Description: "Add low stock thresholds",
+		Script: `
+ALTER TABLE products ADD COLUMN low_stock_threshold INT NOT NULL DEFAULT 0;`,
+	},
+	{
+		Version:     15,
+		Description: "Add inventory movements",
+		Script: `
+CREATE TABLE inventory_movements (
+	movement_id		UUID,
+	product_id		UUID NOT NULL,
+	variant_id		UUID,
+	sale_id			UUID,
+	kind			TEXT NOT NULL,
+	quantity		INT NOT NULL,
+	reason			TEXT NOT NULL DEFAULT '',
+	actor			TEXT NOT NULL DEFAULT '',
+	date_created	TIMESTAMP,
+
+	PRIMARY KEY (movement_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE,
+	FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id),
+	FOREIGN KEY (sale_id) REFERENCES sales(sale_id) ON DELETE CASCADE
+);
+CREATE INDEX inventory_movements_product ON inventory_movements (product_id, date_created);
+CREATE INDEX inventory_movements_variant ON inventory_movements (variant_id);
+
+INSERT INTO inventory_movements (movement_id, product_id, kind, quantity, reason, date_created)
+SELECT product_id, product_id, 'received', quantity, 'opening stock', date_created
+FROM products WHERE COALESCE(quantity, 0) <> 0;
+
+INSERT INTO inventory_movements (movement_id, product_id, variant_id, kind, quantity, reason, date_created)
+SELECT variant_id, product_id, variant_id, 'received', quantity, 'opening stock', date_created
+FROM product_variants WHERE quantity <> 0;
+
+INSERT INTO inventory_movements (movement_id, product_id, variant_id, sale_id, kind, quantity, date_created)
+SELECT sale_id, product_id, variant_id, sale_id, 'sale', -quantity, date_created
+FROM sales WHERE COALESCE(quantity, 0) <> 0;
+
+ALTER TABLE products DROP COLUMN quantity;
+ALTER TABLE product_variants DROP COLUMN quantity;`,
+	},
+	{
+		Version:     16,
+		Description: "Add audit log",
+		Script: `
+CREATE TABLE audit_log (
+	seq				BIGSERIAL,
+	entry_id		UUID NOT NULL UNIQUE,
+	actor			TEXT NOT NULL DEFAULT '',
+	action			TEXT NOT NULL,
+	resource_type	TEXT NOT NULL,
+	resource_id		TEXT NOT NULL DEFAULT '',
+	before			JSONB NOT NULL,
+	after			JSONB NOT NULL,
+	diff			JSONB NOT NULL,
+	request_id		TEXT NOT NULL DEFAULT '',
+	date_created	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (seq)
+);
+CREATE INDEX audit_log_resource ON audit_log (resource_type, resource_id);
+CREATE INDEX audit_log_actor ON audit_log (actor);
+CREATE INDEX audit_log_date ON audit_log (date_created);
+
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+FOR EACH STATEMENT EXECUTE PROCEDURE audit_log_append_only();`,
+	},
+	{
+		Version:     17,
+		Description: "Add product versions",
+		Script: `
+CREATE TABLE product_versions (
+	product_id			UUID NOT NULL,
+	version				INT NOT NULL,
+	name				TEXT,
+	category			TEXT NOT NULL DEFAULT '',
+	cost				INT,
+	low_stock_threshold	INT NOT NULL DEFAULT 0,
+	valid_from			TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (product_id, version),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE
+);
+
+INSERT INTO product_versions
+(product_id, version, name, category, cost, low_stock_threshold, valid_from)
+SELECT product_id, 1, name, category, cost, low_stock_threshold, COALESCE(date_updated, date_created, now())
+FROM products;`,
+	},
+	{
+		Version:     18,
+		Description: "Add events",
+		Script: `
+CREATE TABLE events (
+	event_id		UUID,
+	name			TEXT NOT NULL,
+	location		TEXT NOT NULL DEFAULT '',
+	starts_at		TIMESTAMP,
+	ends_at			TIMESTAMP,
+	date_created	TIMESTAMP NOT NULL,
+	date_updated	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (event_id)
+);
+
+INSERT INTO events (event_id, name, date_created, date_updated)
+VALUES ('00000000-0000-0000-0000-000000000001', 'Default', now(), now());
+
+ALTER TABLE products ADD COLUMN event_id UUID NOT NULL
+	DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES events(event_id);
+ALTER TABLE products ALTER COLUMN event_id DROP DEFAULT;
+CREATE INDEX products_event ON products (event_id);
+
+ALTER TABLE sales ADD COLUMN event_id UUID REFERENCES events(event_id);
+UPDATE sales AS s SET event_id = p.event_id FROM products AS p WHERE p.product_id = s.product_id;
+ALTER TABLE sales ALTER COLUMN event_id SET NOT NULL;
+CREATE INDEX sales_event ON sales (event_id);
+
+ALTER TABLE product_variants ADD COLUMN event_id UUID;
+UPDATE product_variants AS v SET event_id = p.event_id FROM products AS p WHERE p.product_id = v.product_id;
+ALTER TABLE product_variants ALTER COLUMN event_id SET NOT NULL;
+ALTER TABLE product_variants DROP CONSTRAINT product_variants_sku_key;
+ALTER TABLE product_variants ADD UNIQUE (event_id, sku);
+
+ALTER TABLE coupons ADD COLUMN event_id UUID NOT NULL
+	DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES events(event_id) ON DELETE CASCADE;
+ALTER TABLE coupons ALTER COLUMN event_id DROP DEFAULT;
+ALTER TABLE coupons DROP CONSTRAINT coupons_code_key;
+ALTER TABLE coupons ADD UNIQUE (event_id, code);
+
+ALTER TABLE markdowns ADD COLUMN event_id UUID;
+UPDATE markdowns AS m SET event_id = COALESCE(
+	(SELECT p.event_id FROM products AS p WHERE p.product_id = m.product_id),
+	'00000000-0000-0000-0000-000000000001');
+ALTER TABLE markdowns ALTER COLUMN event_id SET NOT NULL;
+ALTER TABLE markdowns ADD FOREIGN KEY (event_id) REFERENCES events(event_id) ON DELETE CASCADE;
+CREATE INDEX markdowns_event ON markdowns (event_id, starts_at);`,
+	},
+	{
+		Version:     19,
+		Description: "Add consignment sellers and payouts",
+		Script: `
+ALTER TABLE events ADD COLUMN commission INT NOT NULL DEFAULT 0;
+
+CREATE TABLE sellers (
+	seller_id		UUID,
+	event_id		UUID NOT NULL,
+	name			TEXT NOT NULL,
+	email			TEXT NOT NULL DEFAULT '',
+	commission		INT,
+	date_created	TIMESTAMP NOT NULL,
+	date_updated	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (seller_id),
+	FOREIGN KEY (event_id) REFERENCES events(event_id)
+);
+CREATE INDEX sellers_event ON sellers (event_id);
+
+ALTER TABLE products ADD COLUMN seller_id UUID REFERENCES sellers(seller_id);
+CREATE INDEX products_seller ON products (seller_id);
+
+ALTER TABLE sales ADD COLUMN seller_id UUID REFERENCES sellers(seller_id);
+CREATE INDEX sales_seller ON sales (seller_id);
+
+ALTER TABLE product_versions ADD COLUMN seller_id UUID;
+
+CREATE TABLE payouts (
+	payout_id		UUID,
+	event_id		UUID NOT NULL,
+	seller_id		UUID NOT NULL,
+	amount			INT NOT NULL,
+	date_created	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (payout_id),
+	FOREIGN KEY (event_id) REFERENCES events(event_id),
+	FOREIGN KEY (seller_id) REFERENCES sellers(seller_id)
+);
+CREATE INDEX payouts_seller ON payouts (seller_id);`,
+	},
+	{
+		Version:     20,
+		Description: "Add customers",
+		Script: `
+CREATE TABLE customers (
+	customer_id			UUID,
+	name				TEXT NOT NULL,
+	email				TEXT NOT NULL DEFAULT '',
+	phone				TEXT NOT NULL DEFAULT '',
+	notes				TEXT NOT NULL DEFAULT '',
+	marketing_consent	BOOLEAN NOT NULL DEFAULT FALSE,
+	date_anonymized		TIMESTAMP,
+	date_created		TIMESTAMP NOT NULL,
+	date_updated		TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (customer_id)
+);
+
+ALTER TABLE sales ADD COLUMN customer_id UUID REFERENCES customers(customer_id);
+CREATE INDEX sales_customer ON sales (customer_id);`,
+	},
+	{
+		Version:     21,
+		Description: "Add reservations",
+		Script: `
+CREATE TABLE reservations (
+	reservation_id	UUID,
+	product_id		UUID NOT NULL,
+	variant_id		UUID,
+	customer_id		UUID,
+	sale_id			UUID,
+	note			TEXT NOT NULL DEFAULT '',
+	quantity		INT NOT NULL,
+	status			TEXT NOT NULL,
+	expires_at		TIMESTAMP NOT NULL,
+	date_created	TIMESTAMP NOT NULL,
+	date_updated	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (reservation_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE,
+	FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+	FOREIGN KEY (customer_id) REFERENCES customers(customer_id),
+	FOREIGN KEY (sale_id) REFERENCES sales(sale_id) ON DELETE SET NULL
+);
+CREATE INDEX reservations_product ON reservations (product_id);
+CREATE INDEX reservations_active ON reservations (expires_at) WHERE status = 'active';`,
+	},
+	{
+		Version:     22,
+		Description: "Add offers and minimum prices",
+		Script: `
+ALTER TABLE products ADD COLUMN min_price INT NOT NULL DEFAULT 0;
+ALTER TABLE product_versions ADD COLUMN min_price INT;
+CREATE TABLE offers (
+	offer_id		UUID,
+	product_id		UUID NOT NULL,
+	variant_id		UUID,
+	customer_id		UUID,
+	sale_id			UUID,
+	buyer			TEXT NOT NULL DEFAULT '',
+	quantity		INT NOT NULL,
+	amount			INT NOT NULL,
+	counter_amount	INT,
+	price			INT,
+	status			TEXT NOT NULL,
+	reason			TEXT NOT NULL DEFAULT '',
+	expires_at		TIMESTAMP,
+	date_created	TIMESTAMP NOT NULL,
+	date_updated	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (offer_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE,
+	FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+	FOREIGN KEY (customer_id) REFERENCES customers(customer_id),
+	FOREIGN KEY (sale_id) REFERENCES sales(sale_id) ON DELETE SET NULL
+);
+CREATE INDEX offers_product ON offers (product_id);`,
+	},
+	{
+		Version:     23,
+		Description: "Add auctions",
+		Script: `
+CREATE TABLE auctions (
+	auction_id		UUID,
+	product_id		UUID NOT NULL,
+	variant_id		UUID,
+	sale_id			UUID,
+	starts_at		TIMESTAMP NOT NULL,
+	ends_at			TIMESTAMP NOT NULL,
+	reserve_price	INT NOT NULL DEFAULT 0,
+	min_increment	INT NOT NULL,
+	high_bid		INT,
+	bids			INT NOT NULL DEFAULT 0,
+	status			TEXT NOT NULL,
+	sale_attempts	INT NOT NULL DEFAULT 0,
+	last_error		TEXT NOT NULL DEFAULT '',
+	date_created	TIMESTAMP NOT NULL,
+	date_updated	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (auction_id),
+	FOREIGN KEY (product_id) REFERENCES products(product_id) ON DELETE CASCADE,
+	FOREIGN KEY (variant_id) REFERENCES product_variants(variant_id) ON DELETE CASCADE,
+	FOREIGN KEY (sale_id) REFERENCES sales(sale_id) ON DELETE SET NULL
+);
+CREATE INDEX auctions_product ON auctions (product_id);
+CREATE INDEX auctions_open ON auctions (ends_at) WHERE status IN ('open', 'won');
+CREATE TABLE bids (
+	bid_id			UUID,
+	auction_id		UUID NOT NULL,
+	customer_id		UUID,
+	bidder			TEXT NOT NULL DEFAULT '',
+	amount			INT NOT NULL,
+	date_created	TIMESTAMP NOT NULL,
+
+	PRIMARY KEY (bid_id),
+	FOREIGN KEY (auction_id) REFERENCES auctions(auction_id) ON DELETE CASCADE,
+	FOREIGN KEY (customer_id) REFERENCES customers(customer_id)
+);
+CREATE INDEX bids_auction ON bids (auction_id, amount DESC);
+
+ALTER TABLE reservations ADD COLUMN auction_id UUID REFERENCES auctions(auction_id) ON DELETE CASCADE;
+CREATE UNIQUE INDEX reservations_auction ON reservations (auction_id) WHERE status = 'active';`,
+	},
+	{
+		Version:     24,
+		Description: "Add event places and opening hours",
+		Script: `
+ALTER TABLE events
+	ADD COLUMN address TEXT NOT NULL DEFAULT '',
+	ADD COLUMN latitude DOUBLE PRECISION,
+	ADD COLUMN longitude DOUBLE PRECISION,
+	ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+CREATE INDEX events_place ON events (latitude, longitude) WHERE latitude IS NOT NULL;
+CREATE TABLE event_hours (
+	event_id	UUID NOT NULL,
+	weekday		INT NOT NULL,
+	opens		TIME NOT NULL,
+	closes		TIME NOT NULL,
+
+	PRIMARY KEY (event_id, weekday, opens),
+	FOREIGN KEY (event_id) REFERENCES events(event_id) ON DELETE CASCADE
+);`,
+	},
+	{
+		Version:     25,
+		Description: "Add archived products",
+		Script: `
+ALTER TABLE products ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE product_versions ADD COLUMN archived BOOLEAN;`,
+	},
 }
 
 func Migrate(db *sqlx.DB) error {
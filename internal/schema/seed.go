@@ -3,14 +3,26 @@ package schema
 import "github.com/jmoiron/sqlx"
 
 const seeds = `
-INSERT INTO products (product_id, name, cost, quantity, date_created, date_updated) VALUES
-('fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 'Comic Books', 50, 42, '1999-01-08 04:05:06', '1999-01-08 04:05:06'),
-('67621e3c-b845-4379-9ec8-875c8b2702c6', 'McDonalds Toys', 75, 120, '2020-04-04 04:05:06', '2020-04-04 04:05:06')
+INSERT INTO products (product_id, event_id, name, cost, date_created, date_updated) VALUES
+('fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', '00000000-0000-0000-0000-000000000001', 'Comic Books', 50, '1999-01-08 04:05:06', '1999-01-08 04:05:06'),
+('67621e3c-b845-4379-9ec8-875c8b2702c6', '00000000-0000-0000-0000-000000000001', 'McDonalds Toys', 75, '2020-04-04 04:05:06', '2020-04-04 04:05:06')
 ON CONFLICT DO NOTHING;
 
-INSERT INTO sales (sale_id, product_id, quantity, paid, date_created) VALUES
-	('dc3ea3fa-dcfc-4073-8fa1-7187d44eaa14', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 2, 100, '2021-01-18 14:05:06'),
-	('bf27a541-e746-4762-a3dc-641f86e3e06c', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 4, 300, '2015-06-12 06:05:06')
+INSERT INTO product_versions (product_id, version, name, category, cost, low_stock_threshold, valid_from) VALUES
+('fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 1, 'Comic Books', '', 50, 0, '1999-01-08 04:05:06'),
+('67621e3c-b845-4379-9ec8-875c8b2702c6', 1, 'McDonalds Toys', '', 75, 0, '2020-04-04 04:05:06')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO sales (sale_id, event_id, product_id, quantity, paid, net, gross, date_created) VALUES
+	('dc3ea3fa-dcfc-4073-8fa1-7187d44eaa14', '00000000-0000-0000-0000-000000000001', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 2, 100, 100, 100, '2021-01-18 14:05:06'),
+	('bf27a541-e746-4762-a3dc-641f86e3e06c', '00000000-0000-0000-0000-000000000001', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 4, 300, 300, 300, '2015-06-12 06:05:06')
+	ON CONFLICT DO NOTHING;
+
+INSERT INTO inventory_movements (movement_id, product_id, sale_id, kind, quantity, reason, date_created) VALUES
+	('fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', NULL, 'received', 42, 'opening stock', '1999-01-08 04:05:06'),
+	('67621e3c-b845-4379-9ec8-875c8b2702c6', '67621e3c-b845-4379-9ec8-875c8b2702c6', NULL, 'received', 120, 'opening stock', '2020-04-04 04:05:06'),
+	('dc3ea3fa-dcfc-4073-8fa1-7187d44eaa14', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 'dc3ea3fa-dcfc-4073-8fa1-7187d44eaa14', 'sale', -2, '', '2021-01-18 14:05:06'),
+	('bf27a541-e746-4762-a3dc-641f86e3e06c', 'fb5c6c41-2b8a-499a-abd7-ab4d02bd2c01', 'bf27a541-e746-4762-a3dc-641f86e3e06c', 'sale', -4, '', '2015-06-12 06:05:06')
 	ON CONFLICT DO NOTHING;`
 
 func Seed(db *sqlx.DB) error {
@@ -0,0 +1,58 @@
+package seller
+
+import "time"
+
+// Seller is a household selling its items on consignment at an event. The
+// event keeps a Commission, a percentage of the seller's takings; sellers
+// without a rate of their own pay the commission of the event.
+type Seller struct {
+	ID          string    `db:"seller_id" json:"id"`
+	EventID     string    `db:"event_id" json:"event_id"`
+	Name        string    `db:"name" json:"name"`
+	Email       string    `db:"email" json:"email,omitempty"`
+	Commission  *int      `db:"commission" json:"commission,omitempty"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+	DateUpdated time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewSeller is what we require from clients to add a Seller to an event.
+type NewSeller struct {
+	Name       string `json:"name" validate:"required"`
+	Email      string `json:"email" validate:"omitempty,email"`
+	Commission *int   `json:"commission" validate:"omitempty,gte=0,lte=100"`
+}
+
+// UpdateSeller defines what information may be provided to modify an
+// existing Seller. All fields are optional.
+type UpdateSeller struct {
+	Name       *string `json:"name" validate:"omitempty,min=1"`
+	Email      *string `json:"email" validate:"omitempty,email"`
+	Commission *int    `json:"commission" validate:"omitempty,gte=0,lte=100"`
+}
+
+// Settlement is what a Seller is owed for their sales at an event. Net adds
+// up the sales of their products before tax and Refunds what was given back
+// on them. The commission is taken at CommissionRate percent of what is left
+// and Earned is the seller's share after it. Owed is what has not been paid
+// out yet.
+type Settlement struct {
+	SellerID       string `db:"seller_id" json:"seller_id"`
+	Name           string `db:"name" json:"name"`
+	Sold           int    `db:"sold" json:"sold"`
+	Net            int    `db:"net" json:"net"`
+	Refunds        int    `db:"refunds" json:"refunds"`
+	CommissionRate int    `db:"commission_rate" json:"commission_rate"`
+	Commission     int    `db:"-" json:"commission"`
+	Earned         int    `db:"-" json:"earned"`
+	Paid           int    `db:"paid" json:"paid"`
+	Owed           int    `db:"-" json:"owed"`
+}
+
+// Payout records money handed over to a Seller.
+type Payout struct {
+	ID          string    `db:"payout_id" json:"id"`
+	EventID     string    `db:"event_id" json:"event_id"`
+	SellerID    string    `db:"seller_id" json:"seller_id"`
+	Amount      int       `db:"amount" json:"amount"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
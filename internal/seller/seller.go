@@ -0,0 +1,222 @@
+// Package seller manages consignment sellers and settles what they are owed.
+package seller
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// foreignKeyViolation is the postgres error code for a violated foreign key.
+const foreignKeyViolation = "23503"
+
+// Predefined errors for known failure scenarios
+var (
+	ErrNotFound      = errors.New("seller not found")
+	ErrInvalidID     = errors.New("id provided was not a valid UUID")
+	ErrEventNotFound = errors.New("event not found")
+	ErrInUse         = errors.New("seller still has products or payouts")
+	ErrNothingOwed   = errors.New("seller is not owed anything")
+)
+
+// List returns the sellers of an event
+func List(ctx context.Context, db *sqlx.DB, eventID string) ([]Seller, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, err
+	}
+
+	list := []Seller{}
+
+	const q = `SELECT * FROM sellers WHERE event_id = $1 ORDER BY name`
+
+	if err := db.SelectContext(ctx, &list, q, eventID); err != nil {
+		return nil, fmt.Errorf("selecting sellers: %w", err)
+	}
+
+	return list, nil
+}
+
+// Retrieve gives a single Seller of an event
+func Retrieve(ctx context.Context, db *sqlx.DB, eventID, id string) (*Seller, error) {
+	if err := checkIDs(eventID, id); err != nil {
+		return nil, err
+	}
+
+	return retrieve(ctx, db, eventID, id, false)
+}
+
+// Create adds a new Seller to an event
+func Create(ctx context.Context, db *sqlx.DB, eventID string, ns NewSeller, now time.Time) (*Seller, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, err
+	}
+
+	s := Seller{
+		ID:          uuid.New().String(),
+		EventID:     eventID,
+		Name:        ns.Name,
+		Email:       ns.Email,
+		Commission:  ns.Commission,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+
+	const q = `INSERT INTO sellers
+	(seller_id, event_id, name, email, commission, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, q, s.ID, s.EventID, s.Name, s.Email, s.Commission, s.DateCreated, s.DateUpdated); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("inserting seller: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "seller", s.ID, nil, s, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing seller: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Update modifies a Seller of an event. It will error if the specified ID is
+// invalid or does not reference an existing Seller.
+func Update(ctx context.Context, db *sqlx.DB, eventID, id string, update UpdateSeller, now time.Time) error {
+	if err := checkIDs(eventID, id); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	s, err := retrieve(ctx, tx, eventID, id, true)
+	if err != nil {
+		return err
+	}
+	before := *s
+
+	if update.Name != nil {
+		s.Name = *update.Name
+	}
+	if update.Email != nil {
+		s.Email = *update.Email
+	}
+	if update.Commission != nil {
+		s.Commission = update.Commission
+	}
+	s.DateUpdated = now.UTC()
+
+	const q = `UPDATE sellers SET
+		"name" = $3,
+		"email" = $4,
+		"commission" = $5,
+		"date_updated" = $6
+		WHERE seller_id = $1 AND event_id = $2`
+
+	if _, err := tx.ExecContext(ctx, q, id, eventID, s.Name, s.Email, s.Commission, s.DateUpdated); err != nil {
+		return fmt.Errorf("updating seller: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionUpdate, "seller", s.ID, before, s, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing seller: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a Seller of an event. Sellers that still have products or
+// were paid can not be deleted.
+func Delete(ctx context.Context, db *sqlx.DB, eventID, id string, now time.Time) error {
+	if err := checkIDs(eventID, id); err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := retrieve(ctx, tx, eventID, id, true)
+	if err != nil {
+		if err == ErrNotFound {
+			return nil
+		}
+		return err
+	}
+
+	const q = `DELETE FROM sellers WHERE seller_id = $1 AND event_id = $2`
+
+	if _, err := tx.ExecContext(ctx, q, id, eventID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+			return ErrInUse
+		}
+		return fmt.Errorf("deleting seller (id: %s): %w", id, err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionDelete, "seller", id, before, nil, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing seller: %w", err)
+	}
+
+	return nil
+}
+
+// retrieve selects a Seller of an event, locking it when asked to so the
+// change made to it in the same transaction is audited from what it was.
+func retrieve(ctx context.Context, db sqlx.QueryerContext, eventID, id string, lock bool) (*Seller, error) {
+	var s Seller
+
+	q := `SELECT * FROM sellers WHERE seller_id = $1 AND event_id = $2`
+	if lock {
+		q += ` FOR UPDATE`
+	}
+
+	if err := sqlx.GetContext(ctx, db, &s, q, id, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// checkIDs makes sure every given ID is a valid UUID.
+func checkIDs(ids ...string) error {
+	for _, id := range ids {
+		if _, err := uuid.Parse(id); err != nil {
+			return ErrInvalidID
+		}
+	}
+	return nil
+}
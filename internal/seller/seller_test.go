@@ -0,0 +1,101 @@
+package seller_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/event"
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/product"
+	"github.com/ivan-sabo/garagesale/internal/seller"
+	"github.com/ivan-sabo/garagesale/internal/tax"
+)
+
+func TestSettlements(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	ev, err := event.Create(ctx, db, event.NewEvent{Name: "Street Sale", Commission: 10}, now)
+	if err != nil {
+		t.Fatalf("creating event: %s", err)
+	}
+
+	twenty := 20
+	alice, err := seller.Create(ctx, db, ev.ID, seller.NewSeller{Name: "Alice"}, now)
+	if err != nil {
+		t.Fatalf("creating seller: %s", err)
+	}
+	if _, err := seller.Create(ctx, db, ev.ID, seller.NewSeller{Name: "Bob", Commission: &twenty}, now); err != nil {
+		t.Fatalf("creating seller: %s", err)
+	}
+
+	np := product.NewProduct{Name: "Vase", Cost: 100, Quantity: 5, SellerID: alice.ID}
+	p, err := product.Create(ctx, db, ev.ID, np, now)
+	if err != nil {
+		t.Fatalf("creating product: %s", err)
+	}
+
+	s, err := product.AddSale(ctx, db, ev.ID, product.NewSale{Quantity: 2}, p.ID, now)
+	if err != nil {
+		t.Fatalf("adding sale: %s", err)
+	}
+
+	refund := product.NewAdjustment{SaleID: s.ID, Kind: product.MovementRefund, Quantity: 1, Reason: "chipped"}
+	if _, err := product.Adjust(ctx, db, ev.ID, p.ID, refund, "alice", now); err != nil {
+		t.Fatalf("refunding: %s", err)
+	}
+	refund.Quantity = 2
+	if _, err := product.Adjust(ctx, db, ev.ID, p.ID, refund, "alice", now); err != product.ErrRefundExceedsSale {
+		t.Fatalf("expected %v refunding more than was sold, got %v", product.ErrRefundExceedsSale, err)
+	}
+
+	report, err := tax.Summarize(ctx, db, ev.ID, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("summarizing tax: %s", err)
+	}
+	if report.Total.Gross != 200 || report.Refunded.Gross != 100 {
+		t.Fatalf("expected 200 sold and 100 refunded in the tax report, got %+v", report)
+	}
+
+	list, err := seller.Settlements(ctx, db, ev.ID)
+	if err != nil {
+		t.Fatalf("settling: %s", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 settlements, got %d", len(list))
+	}
+
+	got := list[0]
+	want := seller.Settlement{
+		SellerID:       alice.ID,
+		Name:           "Alice",
+		Sold:           2,
+		Net:            200,
+		Refunds:        100,
+		CommissionRate: 10,
+		Commission:     10,
+		Earned:         90,
+		Owed:           90,
+	}
+	if got != want {
+		t.Fatalf("expected settlement %+v, got %+v", want, got)
+	}
+	if list[1].CommissionRate != 20 || list[1].Owed != 0 {
+		t.Fatalf("expected nothing owed to Bob at his own rate, got %+v", list[1])
+	}
+
+	po, err := seller.Pay(ctx, db, ev.ID, alice.ID, now)
+	if err != nil {
+		t.Fatalf("paying: %s", err)
+	}
+	if po.Amount != 90 {
+		t.Fatalf("expected a payout of 90, got %d", po.Amount)
+	}
+	if _, err := seller.Pay(ctx, db, ev.ID, alice.ID, now); err != seller.ErrNothingOwed {
+		t.Fatalf("expected %v paying twice, got %v", seller.ErrNothingOwed, err)
+	}
+}
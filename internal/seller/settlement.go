@@ -0,0 +1,127 @@
+package seller
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivan-sabo/garagesale/internal/audit"
+	"github.com/jmoiron/sqlx"
+)
+
+// settlementQuery adds up the sales, refunds and payouts of the sellers of an
+// event, or of a single seller when one is given. A refund is worth the net
+// price per unit of the sale it names.
+const settlementQuery = `SELECT
+	sl.seller_id, sl.name,
+	COALESCE(sl.commission, e.commission) AS commission_rate,
+	COALESCE((SELECT SUM(s.quantity) FROM sales AS s
+		WHERE s.seller_id = sl.seller_id), 0) AS sold,
+	COALESCE((SELECT SUM(s.net) FROM sales AS s
+		WHERE s.seller_id = sl.seller_id), 0) AS net,
+	COALESCE((SELECT SUM(m.quantity * s.net / s.quantity) FROM inventory_movements AS m
+		JOIN sales AS s ON s.sale_id = m.sale_id
+		WHERE m.kind = 'refund' AND s.seller_id = sl.seller_id), 0) AS refunds,
+	COALESCE((SELECT SUM(po.amount) FROM payouts AS po
+		WHERE po.seller_id = sl.seller_id), 0) AS paid
+FROM sellers AS sl
+JOIN events AS e ON e.event_id = sl.event_id
+WHERE sl.event_id = $1 AND ($2 = '' OR sl.seller_id::text = $2)
+ORDER BY sl.name`
+
+// Settlements works out what every seller of an event is owed.
+func Settlements(ctx context.Context, db *sqlx.DB, eventID string) ([]Settlement, error) {
+	if err := checkIDs(eventID); err != nil {
+		return nil, err
+	}
+
+	return settlements(ctx, db, eventID, "")
+}
+
+// Pay records a payout of everything a Seller of an event is owed, marking
+// their settlement paid.
+func Pay(ctx context.Context, db *sqlx.DB, eventID, sellerID string, now time.Time) (*Payout, error) {
+	if err := checkIDs(eventID, sellerID); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the seller so the same settlement can not be paid out twice.
+	const lock = `SELECT seller_id FROM sellers WHERE seller_id = $1 AND event_id = $2 FOR UPDATE`
+	var id string
+	if err := tx.GetContext(ctx, &id, lock, sellerID, eventID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("locking seller: %w", err)
+	}
+
+	list, err := settlements(ctx, tx, eventID, sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, ErrNotFound
+	}
+	if list[0].Owed <= 0 {
+		return nil, ErrNothingOwed
+	}
+
+	p := Payout{
+		ID:          uuid.New().String(),
+		EventID:     eventID,
+		SellerID:    sellerID,
+		Amount:      list[0].Owed,
+		DateCreated: now.UTC(),
+	}
+
+	const q = `INSERT INTO payouts
+	(payout_id, event_id, seller_id, amount, date_created)
+	VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := tx.ExecContext(ctx, q, p.ID, p.EventID, p.SellerID, p.Amount, p.DateCreated); err != nil {
+		return nil, fmt.Errorf("inserting payout: %w", err)
+	}
+
+	if err := audit.Log(ctx, tx, audit.ActionCreate, "payout", p.ID, nil, p, now); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing payout: %w", err)
+	}
+
+	return &p, nil
+}
+
+// settlements selects the settlements of an event and works out the
+// commission and what is owed on each.
+func settlements(ctx context.Context, db sqlx.QueryerContext, eventID, sellerID string) ([]Settlement, error) {
+	list := []Settlement{}
+
+	if err := sqlx.SelectContext(ctx, db, &list, settlementQuery, eventID, sellerID); err != nil {
+		return nil, fmt.Errorf("selecting settlements: %w", err)
+	}
+
+	for i := range list {
+		list[i].settle()
+	}
+
+	return list, nil
+}
+
+// settle takes the commission off the takings of a seller. Commission is
+// rounded down, in favour of the seller.
+func (s *Settlement) settle() {
+	takings := s.Net - s.Refunds
+	s.Commission = takings * s.CommissionRate / 100
+	s.Earned = takings - s.Commission
+	s.Owed = s.Earned - s.Paid
+}
@@ -0,0 +1,272 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// subscriberBuffer is how many events may queue up for a client before it is
+// considered too slow and disconnected.
+const subscriberBuffer = 64
+
+// gapPolls is for how many poll intervals a gap in the outbox sequence is
+// waited on before the broker moves past it.
+const gapPolls = 10
+
+// Broker follows the outbox and fans new events out to subscribed clients.
+// It keeps the most recent events in memory so reconnecting clients can
+// resume where they left off.
+type Broker struct {
+	db       *sqlx.DB
+	log      *log.Logger
+	interval time.Duration
+	size     int
+
+	mu     sync.Mutex
+	recent []Event
+	last   int64
+	subs   map[*Subscription]struct{}
+
+	// gap is the first missing sequence number the broker is waiting on and
+	// gapSince when it was first seen missing. Only the polling loop uses
+	// them.
+	gap      int64
+	gapSince time.Time
+}
+
+// Subscription is a single client listening to the broker.
+type Subscription struct {
+	filter Filter
+	events chan Event
+}
+
+// Events gives the channel new events are delivered on. It is closed when the
+// subscriber falls too far behind or the broker stops.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// NewBroker constructs a Broker that polls the outbox every interval and
+// remembers the last size events.
+func NewBroker(db *sqlx.DB, log *log.Logger, interval time.Duration, size int) *Broker {
+	return &Broker{
+		db:       db,
+		log:      log,
+		interval: interval,
+		size:     size,
+		subs:     make(map[*Subscription]struct{}),
+	}
+}
+
+// Run loads the tail of the outbox and then polls for new events until the
+// context is canceled.
+func (b *Broker) Run(ctx context.Context) {
+	if err := b.load(ctx); err != nil {
+		b.log.Printf("stream : loading recent events : %v", err)
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.mu.Lock()
+			for s := range b.subs {
+				delete(b.subs, s)
+				close(s.events)
+			}
+			b.mu.Unlock()
+			return
+		case <-ticker.C:
+			if err := b.poll(ctx); err != nil {
+				b.log.Printf("stream : polling events : %v", err)
+			}
+		}
+	}
+}
+
+// Subscribe registers a client. Events after lastSeq that are still held in
+// memory and match the filter are returned for replay.
+func (b *Broker) Subscribe(f Filter, lastSeq int64) ([]Event, *Subscription) {
+	s := Subscription{
+		filter: f,
+		events: make(chan Event, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []Event
+	if lastSeq > 0 {
+		for _, e := range b.recent {
+			if e.Seq > lastSeq && f.matches(e) {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	b.subs[&s] = struct{}{}
+
+	return backlog, &s
+}
+
+// Unsubscribe removes a client from the broker.
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+// load fills the in memory log with the most recent events.
+func (b *Broker) load(ctx context.Context) error {
+	var events []Event
+
+	const q = `SELECT seq, type, payload, date_created FROM (
+		SELECT seq, type, payload, date_created FROM outbox
+		ORDER BY seq DESC
+		LIMIT $1
+	) AS recent ORDER BY seq`
+
+	if err := b.db.SelectContext(ctx, &events, q, b.size); err != nil {
+		return fmt.Errorf("selecting recent events: %w", err)
+	}
+
+	if len(events) > 0 {
+		events = b.settled(events[0].Seq-1, events, time.Now())
+	}
+
+	b.publish(events)
+	return nil
+}
+
+// poll reads events that were added to the outbox since the last poll.
+// Sequence numbers are handed out as rows are inserted, not as they commit, so
+// a row may show up after rows with higher numbers. Events are only published
+// up to the first gap; see settled.
+func (b *Broker) poll(ctx context.Context) error {
+	b.mu.Lock()
+	last := b.last
+	b.mu.Unlock()
+
+	var events []Event
+
+	const q = `SELECT seq, type, payload, date_created FROM outbox
+	WHERE seq > $1
+	ORDER BY seq
+	LIMIT $2`
+
+	if err := b.db.SelectContext(ctx, &events, q, last, b.size); err != nil {
+		return fmt.Errorf("selecting new events: %w", err)
+	}
+
+	b.publish(b.settled(last, events, time.Now()))
+	return nil
+}
+
+// settled gives the leading events that follow on from the last one without a
+// gap. A gap is most likely a transaction that has not committed yet, so the
+// events after it wait for the next poll. One that stays open for gapPolls
+// intervals is taken to be a transaction that rolled back and is skipped.
+func (b *Broker) settled(last int64, events []Event, now time.Time) []Event {
+	next := last + 1
+	for i, e := range events {
+		if e.Seq != next {
+			if b.gap != next {
+				b.gap, b.gapSince = next, now
+			}
+			if now.Sub(b.gapSince) < gapPolls*b.interval {
+				return events[:i]
+			}
+		}
+		next = e.Seq + 1
+	}
+	return events
+}
+
+// publish records events in the in memory log and hands them to every
+// interested subscriber.
+func (b *Broker) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range events {
+		e.index()
+
+		b.recent = append(b.recent, e)
+		b.last = e.Seq
+
+		for s := range b.subs {
+			if !s.filter.matches(e) {
+				continue
+			}
+			select {
+			case s.events <- e:
+			default:
+				// The client can not keep up. Drop it so it reconnects and
+				// resumes from the last event it actually received.
+				delete(b.subs, s)
+				close(s.events)
+			}
+		}
+	}
+
+	if extra := len(b.recent) - b.size; extra > 0 {
+		b.recent = append([]Event(nil), b.recent[extra:]...)
+	}
+}
+
+// index extracts the fields events are filtered on from the payload. Product
+// events carry the product itself, sale events reference it. Every event
+// names the event the product belongs to.
+func (e *Event) index() {
+	var v struct {
+		ID        string `json:"id"`
+		EventID   string `json:"event_id"`
+		ProductID string `json:"product_id"`
+		Category  string `json:"category"`
+	}
+	json.Unmarshal(e.Data, &v)
+
+	e.eventID = v.EventID
+	e.productID = v.ProductID
+	if e.productID == "" {
+		e.productID = v.ID
+	}
+	e.category = v.Category
+}
+
+// matches reports whether the event passes the filter.
+func (f Filter) matches(e Event) bool {
+	if f.EventID != "" && f.EventID != e.eventID {
+		return false
+	}
+	return contains(f.ProductIDs, e.productID) && contains(f.Categories, e.category)
+}
+
+// contains reports whether v is in list, treating an empty list as a match.
+func contains(list []string, v string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, l := range list {
+		if l == v {
+			return true
+		}
+	}
+	return false
+}
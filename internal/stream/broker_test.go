@@ -0,0 +1,92 @@
+package stream_test
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/stream"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
+)
+
+// TestBrokerLateCommit checks an event that commits after a later one is
+// still streamed, and in order, while a rolled back one does not hold the
+// stream up for good.
+func TestBrokerLateCommit(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	log := log.New(os.Stderr, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	b := stream.NewBroker(db, log, 10*time.Millisecond, 100)
+	go b.Run(ctx)
+
+	_, sub := b.Subscribe(stream.Filter{}, 0)
+	defer b.Unsubscribe(sub)
+
+	publish := func(id string) {
+		t.Helper()
+		if err := webhook.Publish(ctx, db, webhook.EventProductCreated, map[string]string{"id": id}, time.Now()); err != nil {
+			t.Fatalf("publishing %s: %s", id, err)
+		}
+	}
+	receive := func(want string) {
+		t.Helper()
+		select {
+		case e := <-sub.Events():
+			var v struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal(e.Data, &v); err != nil {
+				t.Fatalf("decoding event: %s", err)
+			}
+			if v.ID != want {
+				t.Fatalf("expected event %s, got %s", want, v.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("expected event %s, got none", want)
+		}
+	}
+
+	// The late event takes its sequence number first but commits last.
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatalf("starting transaction: %s", err)
+	}
+	if err := webhook.Publish(ctx, tx, webhook.EventProductCreated, map[string]string{"id": "late"}, time.Now()); err != nil {
+		t.Fatalf("publishing late: %s", err)
+	}
+	publish("early")
+
+	select {
+	case e := <-sub.Events():
+		t.Fatalf("expected the stream to wait for the late event, got %d", e.Seq)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("committing late: %s", err)
+	}
+	receive("late")
+	receive("early")
+
+	// A rolled back event leaves a gap the broker moves past after a while.
+	if tx, err = db.BeginTxx(ctx, nil); err != nil {
+		t.Fatalf("starting transaction: %s", err)
+	}
+	if err := webhook.Publish(ctx, tx, webhook.EventProductCreated, map[string]string{"id": "gone"}, time.Now()); err != nil {
+		t.Fatalf("publishing gone: %s", err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("rolling back: %s", err)
+	}
+	publish("after")
+	receive("after")
+}
@@ -0,0 +1,27 @@
+package stream
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event is an entry of the outbox as it is pushed to stream clients. The Seq
+// doubles as the SSE event ID clients resume from.
+type Event struct {
+	Seq         int64           `db:"seq"`
+	Type        string          `db:"type"`
+	Data        json.RawMessage `db:"payload"`
+	DateCreated time.Time       `db:"date_created"`
+
+	eventID   string
+	productID string
+	category  string
+}
+
+// Filter restricts which events a client receives. An empty EventID and
+// empty lists match everything.
+type Filter struct {
+	EventID    string
+	ProductIDs []string
+	Categories []string
+}
@@ -0,0 +1,56 @@
+package tax
+
+import "time"
+
+// Rate is the tax rate applied to sales of a product category in a
+// jurisdiction. Rates are expressed in basis points, so 2500 is 25%. A rate
+// with an empty Category is the default for its jurisdiction.
+type Rate struct {
+	ID           string    `db:"tax_rate_id" json:"id"`
+	Jurisdiction string    `db:"jurisdiction" json:"jurisdiction"`
+	Category     string    `db:"category" json:"category"`
+	Rate         int       `db:"rate" json:"rate"`
+	DateCreated  time.Time `db:"date_created" json:"date_created"`
+	DateUpdated  time.Time `db:"date_updated" json:"date_updated"`
+}
+
+// NewRate is what we require from clients to set a tax rate. Setting a rate
+// for a jurisdiction and category that already has one replaces it.
+type NewRate struct {
+	Jurisdiction string `json:"jurisdiction"`
+	Category     string `json:"category"`
+	Rate         int    `json:"rate" validate:"gte=0,lte=10000"`
+}
+
+// Amounts is the tax breakdown of a sale.
+type Amounts struct {
+	Net   int `json:"net"`
+	Tax   int `json:"tax"`
+	Gross int `json:"gross"`
+}
+
+// ReportLine summarizes the sales of one jurisdiction at one tax rate. The
+// refunded amounts are those of refunds made in the range, whenever the sales
+// they name were made.
+type ReportLine struct {
+	Jurisdiction  string `db:"jurisdiction" json:"jurisdiction"`
+	Rate          int    `db:"tax_rate" json:"rate"`
+	Sales         int    `db:"sales" json:"sales"`
+	Net           int    `db:"net" json:"net"`
+	Tax           int    `db:"tax" json:"tax"`
+	Gross         int    `db:"gross" json:"gross"`
+	RefundedNet   int    `db:"refunded_net" json:"refunded_net"`
+	RefundedTax   int    `db:"refunded_tax" json:"refunded_tax"`
+	RefundedGross int    `db:"refunded_gross" json:"refunded_gross"`
+}
+
+// Report is the tax summary of all sales and refunds in a date range. From is
+// inclusive and To is exclusive. Refunds do not change the sales they name, so
+// they are added up on their own.
+type Report struct {
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Lines    []ReportLine `json:"lines"`
+	Total    Amounts      `json:"total"`
+	Refunded Amounts      `json:"refunded"`
+}
@@ -0,0 +1,165 @@
+package tax
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Predefined errors for known failure scenarios
+var (
+	ErrInvalidID    = errors.New("id provided was not a valid UUID")
+	ErrInvalidRange = errors.New("report range must end after it starts")
+)
+
+// Calculate works out the tax on a net amount for a rate in basis points.
+// Tax is rounded half up to the nearest unit.
+func Calculate(net, rate int) Amounts {
+	tax := (net*rate + 5000) / 10000
+
+	return Amounts{
+		Net:   net,
+		Tax:   tax,
+		Gross: net + tax,
+	}
+}
+
+// ListRates returns all configured tax rates
+func ListRates(ctx context.Context, db *sqlx.DB) ([]Rate, error) {
+	list := []Rate{}
+
+	const q = `SELECT * FROM tax_rates ORDER BY jurisdiction, category`
+
+	if err := db.SelectContext(ctx, &list, q); err != nil {
+		return nil, fmt.Errorf("selecting tax rates: %w", err)
+	}
+
+	return list, nil
+}
+
+// SetRate creates or replaces the tax rate of a jurisdiction and category.
+func SetRate(ctx context.Context, db *sqlx.DB, nr NewRate, now time.Time) (*Rate, error) {
+	r := Rate{
+		ID:           uuid.New().String(),
+		Jurisdiction: nr.Jurisdiction,
+		Category:     nr.Category,
+		Rate:         nr.Rate,
+		DateCreated:  now.UTC(),
+		DateUpdated:  now.UTC(),
+	}
+
+	const q = `INSERT INTO tax_rates
+	(tax_rate_id, jurisdiction, category, rate, date_created, date_updated)
+	VALUES($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (jurisdiction, category) DO UPDATE SET
+		rate = EXCLUDED.rate,
+		date_updated = EXCLUDED.date_updated
+	RETURNING *`
+
+	if err := db.GetContext(ctx, &r, q, r.ID, r.Jurisdiction, r.Category, r.Rate, r.DateCreated, r.DateUpdated); err != nil {
+		return nil, fmt.Errorf("setting tax rate: %w", err)
+	}
+
+	return &r, nil
+}
+
+// DeleteRate removes the tax rate identified by a given ID.
+func DeleteRate(ctx context.Context, db *sqlx.DB, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM tax_rates WHERE tax_rate_id = $1`
+
+	if _, err := db.ExecContext(ctx, q, id); err != nil {
+		return fmt.Errorf("deleting tax rate (id: %s): %w", id, err)
+	}
+
+	return nil
+}
+
+// Lookup gives the rate for a category in a jurisdiction, falling back to the
+// jurisdiction default. Sales in a jurisdiction without any rate are not
+// taxed.
+func Lookup(ctx context.Context, db sqlx.QueryerContext, jurisdiction, category string) (int, error) {
+	var rate int
+
+	const q = `SELECT rate FROM tax_rates
+	WHERE jurisdiction = $1 AND (category = $2 OR category = '')
+	ORDER BY category DESC
+	LIMIT 1`
+
+	if err := sqlx.GetContext(ctx, db, &rate, q, jurisdiction, category); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("looking up tax rate: %w", err)
+	}
+
+	return rate, nil
+}
+
+// Summarize builds the tax report for sales and refunds of an event made
+// between from and to. A refund is worth its share of the amounts of the sale
+// it names.
+func Summarize(ctx context.Context, db *sqlx.DB, eventID string, from, to time.Time) (*Report, error) {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return nil, ErrInvalidID
+	}
+	if !to.After(from) {
+		return nil, ErrInvalidRange
+	}
+
+	r := Report{
+		From:  from.UTC(),
+		To:    to.UTC(),
+		Lines: []ReportLine{},
+	}
+
+	const q = `SELECT
+		jurisdiction, tax_rate,
+		SUM(sales) AS sales,
+		SUM(net) AS net,
+		SUM(tax) AS tax,
+		SUM(gross) AS gross,
+		SUM(refunded_net) AS refunded_net,
+		SUM(refunded_tax) AS refunded_tax,
+		SUM(refunded_gross) AS refunded_gross
+	FROM (
+		SELECT jurisdiction, tax_rate, 1 AS sales, net, tax, gross,
+			0 AS refunded_net, 0 AS refunded_tax, 0 AS refunded_gross
+		FROM sales
+		WHERE event_id = $3 AND date_created >= $1 AND date_created < $2
+		UNION ALL
+		SELECT s.jurisdiction, s.tax_rate, 0, 0, 0, 0,
+			m.quantity * s.net / s.quantity,
+			m.quantity * s.tax / s.quantity,
+			m.quantity * s.gross / s.quantity
+		FROM inventory_movements AS m
+		JOIN sales AS s ON s.sale_id = m.sale_id
+		WHERE m.kind = 'refund' AND s.event_id = $3
+			AND m.date_created >= $1 AND m.date_created < $2
+	) AS t
+	GROUP BY jurisdiction, tax_rate
+	ORDER BY jurisdiction, tax_rate`
+
+	if err := db.SelectContext(ctx, &r.Lines, q, r.From, r.To, eventID); err != nil {
+		return nil, fmt.Errorf("summarizing sales tax: %w", err)
+	}
+
+	for _, l := range r.Lines {
+		r.Total.Net += l.Net
+		r.Total.Tax += l.Tax
+		r.Total.Gross += l.Gross
+		r.Refunded.Net += l.RefundedNet
+		r.Refunded.Tax += l.RefundedTax
+		r.Refunded.Gross += l.RefundedGross
+	}
+
+	return &r, nil
+}
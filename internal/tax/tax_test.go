@@ -0,0 +1,28 @@
+package tax_test
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/ivan-sabo/garagesale/internal/tax"
+)
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		net, rate int
+		want      tax.Amounts
+	}{
+		{net: 100, rate: 0, want: tax.Amounts{Net: 100, Tax: 0, Gross: 100}},
+		{net: 100, rate: 2500, want: tax.Amounts{Net: 100, Tax: 25, Gross: 125}},
+		{net: 10, rate: 750, want: tax.Amounts{Net: 10, Tax: 1, Gross: 11}},
+		{net: 10, rate: 740, want: tax.Amounts{Net: 10, Tax: 1, Gross: 11}},
+		{net: 10, rate: 449, want: tax.Amounts{Net: 10, Tax: 0, Gross: 10}},
+	}
+
+	for _, tt := range tests {
+		got := tax.Calculate(tt.net, tt.rate)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Fatalf("amounts for %d at %d did not match expected: see diff \n%s", tt.net, tt.rate, diff)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// batchSize limits how much work the dispatcher does on every tick.
+const batchSize = 50
+
+// claimTimeout is how long a delivery stays claimed by the dispatcher sending
+// it. Sends are cut off after it so no other dispatcher picks the delivery up
+// while it is still being sent.
+const claimTimeout = time.Minute
+
+// Dispatcher moves events from the outbox to the subscriptions interested in
+// them. Failed deliveries are retried with an exponential backoff and marked
+// dead once MaxAttempts is reached. Events are pruned from the outbox along
+// with their deliveries once they are older than Retention and no delivery
+// of them is pending. A zero Retention keeps them forever.
+type Dispatcher struct {
+	DB          *sqlx.DB
+	Log         *log.Logger
+	Client      *http.Client
+	Interval    time.Duration
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Retention   time.Duration
+}
+
+// Run processes the outbox every Interval until the context is canceled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.fanOut(ctx, time.Now()); err != nil {
+			d.Log.Printf("webhook : fanning out events : %v", err)
+		}
+		if err := d.deliver(ctx, time.Now()); err != nil {
+			d.Log.Printf("webhook : delivering events : %v", err)
+		}
+		if err := d.prune(ctx, time.Now()); err != nil {
+			d.Log.Printf("webhook : pruning events : %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sign computes the signature sent in the X-Garagesale-Signature header.
+// Receivers recompute it with their copy of the secret to verify a payload.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff gives how long to wait after the given failed attempt.
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.Backoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// fanOut creates a pending delivery for every subscription of every event
+// that has not been dispatched yet.
+func (d *Dispatcher) fanOut(ctx context.Context, now time.Time) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var events []Event
+
+	const q = `SELECT * FROM outbox
+	WHERE dispatched = false
+	ORDER BY seq
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED`
+
+	if err := tx.SelectContext(ctx, &events, q, batchSize); err != nil {
+		return fmt.Errorf("selecting outbox: %w", err)
+	}
+
+	for _, e := range events {
+		var subs []string
+
+		const s = `SELECT subscription_id FROM webhook_subscriptions
+		WHERE active AND (cardinality(events) = 0 OR $1 = ANY(events))`
+
+		if err := tx.SelectContext(ctx, &subs, s, e.Type); err != nil {
+			return fmt.Errorf("selecting subscriptions: %w", err)
+		}
+
+		for _, sub := range subs {
+			const i = `INSERT INTO webhook_deliveries
+			(delivery_id, subscription_id, event_id, event_type, status, attempts,
+				next_attempt, last_status, last_error, date_created, date_updated)
+			VALUES ($1, $2, $3, $4, $5, 0, $6, 0, '', $6, $6)`
+
+			if _, err := tx.ExecContext(ctx, i, uuid.New().String(), sub, e.ID, e.Type, StatusPending, now.UTC()); err != nil {
+				return fmt.Errorf("inserting delivery: %w", err)
+			}
+		}
+
+		const u = `UPDATE outbox SET dispatched = true WHERE seq = $1`
+
+		if _, err := tx.ExecContext(ctx, u, e.Seq); err != nil {
+			return fmt.Errorf("marking event dispatched: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// prune deletes a batch of the events that are past Retention, were fanned
+// out and have no delivery left pending. Their deliveries and attempts go with
+// them.
+func (d *Dispatcher) prune(ctx context.Context, now time.Time) error {
+	if d.Retention <= 0 {
+		return nil
+	}
+
+	const q = `DELETE FROM outbox WHERE seq IN (
+		SELECT o.seq FROM outbox AS o
+		WHERE o.dispatched AND o.date_created < $1
+		AND NOT EXISTS (
+			SELECT 1 FROM webhook_deliveries AS d
+			WHERE d.event_id = o.event_id AND d.status = $2
+		)
+		ORDER BY o.seq
+		LIMIT $3
+	)`
+
+	if _, err := d.DB.ExecContext(ctx, q, now.Add(-d.Retention).UTC(), StatusPending, batchSize); err != nil {
+		return fmt.Errorf("deleting old events: %w", err)
+	}
+
+	return nil
+}
+
+// deliver sends pending deliveries that are due, one at a time.
+func (d *Dispatcher) deliver(ctx context.Context, now time.Time) error {
+	for i := 0; i < batchSize; i++ {
+		ok, err := d.deliverOne(ctx, now)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+// claimed is a delivery claimed for sending along with what is sent.
+type claimed struct {
+	Delivery
+	URL     string          `db:"url"`
+	Secret  string          `db:"secret"`
+	Payload json.RawMessage `db:"payload"`
+	Created time.Time       `db:"event_created"`
+}
+
+// deliverOne sends the next due delivery. It reports false when there was
+// nothing to do. No transaction is held open while the subscriber is called:
+// the delivery is claimed first and the outcome recorded after.
+func (d *Dispatcher) deliverOne(ctx context.Context, now time.Time) (bool, error) {
+	row, err := d.claim(ctx, now)
+	if err != nil || row == nil {
+		return false, err
+	}
+
+	e := Event{
+		ID:          row.EventID,
+		Type:        row.EventType,
+		Data:        row.Payload,
+		DateCreated: row.Created,
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, claimTimeout)
+	defer cancel()
+
+	start := time.Now()
+	status, sendErr := d.send(sendCtx, row.Delivery, e, row.URL, row.Secret)
+
+	a := Attempt{
+		ID:          uuid.New().String(),
+		DeliveryID:  row.ID,
+		Number:      row.Attempts + 1,
+		Status:      status,
+		Duration:    time.Since(start).Milliseconds(),
+		DateCreated: now.UTC(),
+	}
+	if sendErr != nil {
+		a.Error = sendErr.Error()
+	}
+
+	if err := d.record(ctx, row.Delivery, a, now); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// claim takes the next due delivery that no other dispatcher is sending and
+// marks it as being sent until claimTimeout from now. It gives nil when
+// there is nothing to send.
+func (d *Dispatcher) claim(ctx context.Context, now time.Time) (*claimed, error) {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var row claimed
+
+	const q = `SELECT d.*, s.url, s.secret, o.payload, o.date_created AS event_created
+	FROM webhook_deliveries AS d
+	JOIN webhook_subscriptions AS s ON s.subscription_id = d.subscription_id
+	JOIN outbox AS o ON o.event_id = d.event_id
+	WHERE d.status = $1 AND d.next_attempt <= $2 AND s.active
+	AND (d.locked_until IS NULL OR d.locked_until <= $2)
+	ORDER BY d.next_attempt
+	LIMIT 1
+	FOR UPDATE OF d SKIP LOCKED`
+
+	if err := tx.GetContext(ctx, &row, q, StatusPending, now.UTC()); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("selecting delivery: %w", err)
+	}
+
+	const u = `UPDATE webhook_deliveries SET locked_until = $2 WHERE delivery_id = $1`
+
+	if _, err := tx.ExecContext(ctx, u, row.ID, now.Add(claimTimeout).UTC()); err != nil {
+		return nil, fmt.Errorf("claiming delivery: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("committing claim: %w", err)
+	}
+
+	return &row, nil
+}
+
+// record stores an attempt at sending a claimed delivery and schedules the
+// next one, or marks the delivery delivered or dead, releasing the claim.
+func (d *Dispatcher) record(ctx context.Context, dl Delivery, a Attempt, now time.Time) error {
+	tx, err := d.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	const i = `INSERT INTO webhook_attempts
+	(attempt_id, delivery_id, number, status, error, duration_ms, date_created)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	if _, err := tx.ExecContext(ctx, i, a.ID, a.DeliveryID, a.Number, a.Status, a.Error, a.Duration, a.DateCreated); err != nil {
+		return fmt.Errorf("inserting attempt: %w", err)
+	}
+
+	next := now.Add(d.backoff(a.Number)).UTC()
+	nextAttempt := &next
+	state := StatusPending
+	switch {
+	case a.Error == "":
+		state = StatusDelivered
+		nextAttempt = nil
+	case a.Number >= d.MaxAttempts:
+		state = StatusDead
+		nextAttempt = nil
+		d.Log.Printf("webhook : delivery %s of %s is dead after %d attempts : %s", dl.ID, dl.EventID, a.Number, a.Error)
+	}
+
+	const u = `UPDATE webhook_deliveries SET
+		status = $2,
+		attempts = $3,
+		next_attempt = $4,
+		last_status = $5,
+		last_error = $6,
+		locked_until = NULL,
+		date_updated = $7
+		WHERE delivery_id = $1`
+
+	if _, err := tx.ExecContext(ctx, u, dl.ID, state, a.Number, nextAttempt, a.Status, a.Error, now.UTC()); err != nil {
+		return fmt.Errorf("updating delivery: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// send posts a signed event to a subscriber. Any response other than a 2xx
+// counts as a failure.
+func (d *Dispatcher) send(ctx context.Context, dl Delivery, e Event, url, secret string) (int, error) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return 0, fmt.Errorf("marshaling event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Garagesale-Event", e.Type)
+	req.Header.Set("X-Garagesale-Delivery", dl.ID)
+	req.Header.Set("X-Garagesale-Timestamp", strconv.FormatInt(ts, 10))
+	req.Header.Set("X-Garagesale-Signature", Sign(secret, ts, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("subscriber responded with %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Event types published by the application.
+const (
+	EventProductCreated = "product.created"
+	EventProductUpdated = "product.updated"
+	EventProductDeleted = "product.deleted"
+	EventProductSoldOut = "product.sold_out"
+	EventSaleCreated    = "sale.created"
+
+	EventInventoryAdjusted = "inventory.adjusted"
+)
+
+// Events lists every event type a subscription can ask for.
+var Events = []string{
+	EventProductCreated,
+	EventProductUpdated,
+	EventProductDeleted,
+	EventProductSoldOut,
+	EventSaleCreated,
+	EventInventoryAdjusted,
+}
+
+// Delivery states.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Subscription is an endpoint that wants to be told about events. An empty
+// list of Events subscribes to all of them. The Secret is never sent back
+// after the subscription is created.
+type Subscription struct {
+	ID          string         `db:"subscription_id" json:"id"`
+	URL         string         `db:"url" json:"url"`
+	Secret      string         `db:"secret" json:"-"`
+	Events      pq.StringArray `db:"events" json:"events"`
+	Active      bool           `db:"active" json:"active"`
+	DateCreated time.Time      `db:"date_created" json:"date_created"`
+	DateUpdated time.Time      `db:"date_updated" json:"date_updated"`
+}
+
+// CreatedSubscription is what clients get back when they subscribe, the only
+// time the secret payloads are signed with is shown.
+type CreatedSubscription struct {
+	Subscription
+	Secret string `json:"secret"`
+}
+
+// NewSubscription is what we require from clients to subscribe to events. A
+// secret is generated when none is provided.
+type NewSubscription struct {
+	URL    string   `json:"url" validate:"required,url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// UpdateSubscription defines what information may be provided to modify an
+// existing Subscription. All fields are optional.
+type UpdateSubscription struct {
+	URL    *string   `json:"url" validate:"omitempty,url"`
+	Secret *string   `json:"secret"`
+	Events *[]string `json:"events"`
+	Active *bool     `json:"active"`
+}
+
+// Event is something that happened in the application, stored in the outbox
+// in the same transaction as the change that caused it. Seq orders events.
+type Event struct {
+	Seq         int64           `db:"seq" json:"-"`
+	ID          string          `db:"event_id" json:"id"`
+	Type        string          `db:"type" json:"type"`
+	Data        json.RawMessage `db:"payload" json:"data"`
+	Dispatched  bool            `db:"dispatched" json:"-"`
+	DateCreated time.Time       `db:"date_created" json:"created_at"`
+}
+
+// Delivery tracks sending one event to one subscription. While it is being
+// sent it is claimed by a dispatcher until LockedUntil.
+type Delivery struct {
+	ID             string     `db:"delivery_id" json:"id"`
+	SubscriptionID string     `db:"subscription_id" json:"subscription_id"`
+	EventID        string     `db:"event_id" json:"event_id"`
+	EventType      string     `db:"event_type" json:"event_type"`
+	Status         string     `db:"status" json:"status"`
+	Attempts       int        `db:"attempts" json:"attempts"`
+	NextAttempt    *time.Time `db:"next_attempt" json:"next_attempt,omitempty"`
+	LastStatus     int        `db:"last_status" json:"last_status,omitempty"`
+	LastError      string     `db:"last_error" json:"last_error,omitempty"`
+	LockedUntil    *time.Time `db:"locked_until" json:"-"`
+	DateCreated    time.Time  `db:"date_created" json:"date_created"`
+	DateUpdated    time.Time  `db:"date_updated" json:"date_updated"`
+}
+
+// Attempt is one try at sending a Delivery.
+type Attempt struct {
+	ID          string    `db:"attempt_id" json:"id"`
+	DeliveryID  string    `db:"delivery_id" json:"delivery_id"`
+	Number      int       `db:"number" json:"number"`
+	Status      int       `db:"status" json:"status"`
+	Error       string    `db:"error" json:"error,omitempty"`
+	Duration    int64     `db:"duration_ms" json:"duration_ms"`
+	DateCreated time.Time `db:"date_created" json:"date_created"`
+}
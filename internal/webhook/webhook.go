@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Predefined errors for known failure scenarios
+var (
+	ErrNotFound     = errors.New("subscription not found")
+	ErrNoDelivery   = errors.New("delivery not found")
+	ErrInvalidID    = errors.New("id provided was not a valid UUID")
+	ErrUnknownEvent = errors.New("unknown event type")
+)
+
+// Publish writes an event to the outbox. It must be called with the
+// transaction that makes the change the event describes so the event is
+// stored if and only if the change is.
+func Publish(ctx context.Context, tx sqlx.ExecerContext, eventType string, data interface{}, now time.Time) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshaling %s event: %w", eventType, err)
+	}
+
+	const q = `INSERT INTO outbox
+	(event_id, type, payload, dispatched, date_created)
+	VALUES ($1, $2, $3, false, $4)`
+
+	if _, err := tx.ExecContext(ctx, q, uuid.New().String(), eventType, payload, now.UTC()); err != nil {
+		return fmt.Errorf("publishing %s event: %w", eventType, err)
+	}
+
+	return nil
+}
+
+// List returns all webhook subscriptions
+func List(ctx context.Context, db *sqlx.DB) ([]Subscription, error) {
+	list := []Subscription{}
+
+	const q = `SELECT * FROM webhook_subscriptions ORDER BY date_created`
+
+	if err := db.SelectContext(ctx, &list, q); err != nil {
+		return nil, fmt.Errorf("selecting subscriptions: %w", err)
+	}
+
+	return list, nil
+}
+
+// Retrieve gives a single subscription
+func Retrieve(ctx context.Context, db *sqlx.DB, id string) (*Subscription, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var s Subscription
+
+	const q = `SELECT * FROM webhook_subscriptions WHERE subscription_id = $1`
+
+	if err := db.GetContext(ctx, &s, q, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Create makes a new Subscription
+func Create(ctx context.Context, db *sqlx.DB, ns NewSubscription, now time.Time) (*Subscription, error) {
+	if err := checkEvents(ns.Events); err != nil {
+		return nil, err
+	}
+
+	s := Subscription{
+		ID:          uuid.New().String(),
+		URL:         ns.URL,
+		Secret:      ns.Secret,
+		Events:      ns.Events,
+		Active:      true,
+		DateCreated: now.UTC(),
+		DateUpdated: now.UTC(),
+	}
+	if s.Events == nil {
+		s.Events = []string{}
+	}
+
+	if s.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("generating secret: %w", err)
+		}
+		s.Secret = hex.EncodeToString(b)
+	}
+
+	const q = `INSERT INTO webhook_subscriptions
+	(subscription_id, url, secret, events, active, date_created, date_updated)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	if _, err := db.ExecContext(ctx, q, s.ID, s.URL, s.Secret, s.Events, s.Active, s.DateCreated, s.DateUpdated); err != nil {
+		return nil, fmt.Errorf("inserting subscription: %w", err)
+	}
+
+	return &s, nil
+}
+
+// Update modifies a Subscription. It will error if the specified ID is
+// invalid or does not reference an existing Subscription.
+func Update(ctx context.Context, db *sqlx.DB, id string, update UpdateSubscription, now time.Time) error {
+	s, err := Retrieve(ctx, db, id)
+	if err != nil {
+		return err
+	}
+
+	if update.URL != nil {
+		s.URL = *update.URL
+	}
+	if update.Secret != nil {
+		s.Secret = *update.Secret
+	}
+	if update.Events != nil {
+		if err := checkEvents(*update.Events); err != nil {
+			return err
+		}
+		s.Events = *update.Events
+	}
+	if update.Active != nil {
+		s.Active = *update.Active
+	}
+	s.DateUpdated = now.UTC()
+
+	const q = `UPDATE webhook_subscriptions SET
+		"url" = $2,
+		"secret" = $3,
+		"events" = $4,
+		"active" = $5,
+		"date_updated" = $6
+		WHERE subscription_id = $1`
+
+	if _, err := db.ExecContext(ctx, q, id, s.URL, s.Secret, s.Events, s.Active, s.DateUpdated); err != nil {
+		return fmt.Errorf("updating subscription: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes the subscription identified by a given ID.
+func Delete(ctx context.Context, db *sqlx.DB, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrInvalidID
+	}
+
+	const q = `DELETE FROM webhook_subscriptions WHERE subscription_id = $1`
+
+	if _, err := db.ExecContext(ctx, q, id); err != nil {
+		return fmt.Errorf("deleting subscription (id: %s): %w", id, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries gives the most recent deliveries for a subscription.
+func ListDeliveries(ctx context.Context, db *sqlx.DB, subscriptionID string) ([]Delivery, error) {
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	list := []Delivery{}
+
+	const q = `SELECT * FROM webhook_deliveries
+	WHERE subscription_id = $1
+	ORDER BY date_created DESC
+	LIMIT 100`
+
+	if err := db.SelectContext(ctx, &list, q, subscriptionID); err != nil {
+		return nil, fmt.Errorf("selecting deliveries: %w", err)
+	}
+
+	return list, nil
+}
+
+// ListAttempts gives every attempt made at sending a delivery of a
+// subscription.
+func ListAttempts(ctx context.Context, db *sqlx.DB, subscriptionID, deliveryID string) ([]Attempt, error) {
+	if _, err := uuid.Parse(subscriptionID); err != nil {
+		return nil, ErrInvalidID
+	}
+	if _, err := uuid.Parse(deliveryID); err != nil {
+		return nil, ErrInvalidID
+	}
+
+	var found bool
+
+	const d = `SELECT EXISTS (
+		SELECT 1 FROM webhook_deliveries WHERE delivery_id = $1 AND subscription_id = $2
+	)`
+
+	if err := db.GetContext(ctx, &found, d, deliveryID, subscriptionID); err != nil {
+		return nil, fmt.Errorf("selecting delivery: %w", err)
+	}
+	if !found {
+		return nil, ErrNoDelivery
+	}
+
+	list := []Attempt{}
+
+	const q = `SELECT * FROM webhook_attempts WHERE delivery_id = $1 ORDER BY number`
+
+	if err := db.SelectContext(ctx, &list, q, deliveryID); err != nil {
+		return nil, fmt.Errorf("selecting attempts: %w", err)
+	}
+
+	return list, nil
+}
+
+// checkEvents makes sure every event type is one we publish.
+func checkEvents(events []string) error {
+	for _, e := range events {
+		known := false
+		for _, k := range Events {
+			if e == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("%w: %s", ErrUnknownEvent, e)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package webhook_test
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ivan-sabo/garagesale/internal/platform/database/databasetest"
+	"github.com/ivan-sabo/garagesale/internal/webhook"
+)
+
+func TestSign(t *testing.T) {
+	got := webhook.Sign("secret", 1700000000, []byte(`{"id":"1"}`))
+
+	const want = "sha256=086f6aff7bd084c98679825129c5a64dbad88c760016d6d2c0fb123f27951d54"
+	if got != want {
+		t.Fatalf("expected signature %s, got %s", want, got)
+	}
+}
+
+// TestPublishInTransaction checks an event is only in the outbox once the
+// transaction of the change it describes commits.
+func TestPublishInTransaction(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx := context.Background()
+	now := time.Now()
+
+	count := func() int {
+		t.Helper()
+		var n int
+		if err := db.GetContext(ctx, &n, `SELECT COUNT(*) FROM outbox`); err != nil {
+			t.Fatalf("counting outbox: %s", err)
+		}
+		return n
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		t.Fatalf("starting transaction: %s", err)
+	}
+	if err := webhook.Publish(ctx, tx, webhook.EventProductCreated, map[string]string{"id": "a"}, now); err != nil {
+		t.Fatalf("publishing: %s", err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected no event before the commit, got %d", n)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("rolling back: %s", err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("expected no event after a rollback, got %d", n)
+	}
+
+	if tx, err = db.BeginTxx(ctx, nil); err != nil {
+		t.Fatalf("starting transaction: %s", err)
+	}
+	if err := webhook.Publish(ctx, tx, webhook.EventProductCreated, map[string]string{"id": "b"}, now); err != nil {
+		t.Fatalf("publishing: %s", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("committing: %s", err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("expected the event after the commit, got %d", n)
+	}
+}
+
+// TestDispatcher checks failed deliveries are retried with a growing backoff
+// until they go through, or are marked dead after MaxAttempts.
+func TestDispatcher(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The flaky subscriber fails once, the broken one always.
+	var mu sync.Mutex
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		n := calls[r.URL.Path]
+		mu.Unlock()
+
+		if r.URL.Path == "/broken" || n == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	flaky, err := webhook.Create(ctx, db, webhook.NewSubscription{URL: srv.URL + "/flaky"}, time.Now())
+	if err != nil {
+		t.Fatalf("subscribing: %s", err)
+	}
+	broken, err := webhook.Create(ctx, db, webhook.NewSubscription{URL: srv.URL + "/broken"}, time.Now())
+	if err != nil {
+		t.Fatalf("subscribing: %s", err)
+	}
+	if err := webhook.Publish(ctx, db, webhook.EventProductCreated, map[string]string{"id": "a"}, time.Now()); err != nil {
+		t.Fatalf("publishing: %s", err)
+	}
+
+	d := webhook.Dispatcher{
+		DB:          db,
+		Log:         log.New(os.Stderr, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile),
+		Client:      srv.Client(),
+		Interval:    5 * time.Millisecond,
+		MaxAttempts: 3,
+		Backoff:     20 * time.Millisecond,
+		MaxBackoff:  30 * time.Millisecond,
+	}
+	go d.Run(ctx)
+
+	// settle waits for the delivery of a subscription to leave pending.
+	settle := func(subscriptionID string) webhook.Delivery {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			list, err := webhook.ListDeliveries(ctx, db, subscriptionID)
+			if err != nil {
+				t.Fatalf("listing deliveries: %s", err)
+			}
+			if len(list) == 1 && list[0].Status != webhook.StatusPending {
+				return list[0]
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected the delivery to %s settled", subscriptionID)
+		return webhook.Delivery{}
+	}
+
+	if dl := settle(flaky.ID); dl.Status != webhook.StatusDelivered || dl.Attempts != 2 {
+		t.Fatalf("expected the flaky delivery delivered on the second attempt, got %+v", dl)
+	}
+
+	dl := settle(broken.ID)
+	if dl.Status != webhook.StatusDead || dl.Attempts != 3 || dl.LastStatus != http.StatusInternalServerError {
+		t.Fatalf("expected the broken delivery dead after 3 attempts, got %+v", dl)
+	}
+
+	if _, err := webhook.ListAttempts(ctx, db, flaky.ID, dl.ID); err != webhook.ErrNoDelivery {
+		t.Fatalf("expected %v listing attempts under another subscription, got %v", webhook.ErrNoDelivery, err)
+	}
+
+	attempts, err := webhook.ListAttempts(ctx, db, broken.ID, dl.ID)
+	if err != nil {
+		t.Fatalf("listing attempts: %s", err)
+	}
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(attempts))
+	}
+	for i, want := range []time.Duration{20 * time.Millisecond, 30 * time.Millisecond} {
+		if gap := attempts[i+1].DateCreated.Sub(attempts[i].DateCreated); gap < want {
+			t.Fatalf("expected attempt %d at least %v after the one before, got %v", i+2, want, gap)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["/broken"] != 3 {
+		t.Fatalf("expected 3 calls to the broken subscriber, got %d", calls["/broken"])
+	}
+}
+
+// TestPrune checks old events leave the outbox once nothing is left to
+// deliver for them.
+func TestPrune(t *testing.T) {
+	db, teardown := databasetest.Setup(t)
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	ns := webhook.NewSubscription{URL: srv.URL, Events: []string{webhook.EventProductCreated}}
+	if _, err := webhook.Create(ctx, db, ns, time.Now()); err != nil {
+		t.Fatalf("subscribing: %s", err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, typ := range []string{webhook.EventProductCreated, webhook.EventProductUpdated} {
+		if err := webhook.Publish(ctx, db, typ, map[string]string{"id": "a"}, old); err != nil {
+			t.Fatalf("publishing: %s", err)
+		}
+	}
+	if err := webhook.Publish(ctx, db, webhook.EventProductUpdated, map[string]string{"id": "b"}, time.Now()); err != nil {
+		t.Fatalf("publishing: %s", err)
+	}
+
+	d := webhook.Dispatcher{
+		DB:          db,
+		Log:         log.New(os.Stderr, "TEST : ", log.LstdFlags|log.Lmicroseconds|log.Lshortfile),
+		Client:      srv.Client(),
+		Interval:    5 * time.Millisecond,
+		MaxAttempts: 10,
+		Backoff:     time.Hour,
+		MaxBackoff:  time.Hour,
+		Retention:   time.Hour,
+	}
+	go d.Run(ctx)
+
+	// The old event nobody subscribed to goes, the one still being delivered
+	// and the recent one stay.
+	var types []string
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		types = nil
+		if err := db.SelectContext(ctx, &types, `SELECT type FROM outbox ORDER BY seq`); err != nil {
+			t.Fatalf("selecting outbox: %s", err)
+		}
+		if len(types) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(types) != 2 || types[0] != webhook.EventProductCreated || types[1] != webhook.EventProductUpdated {
+		t.Fatalf("expected the pending and the recent event kept, got %v", types)
+	}
+}